@@ -3,46 +3,185 @@ package main
 import (
 	"context"
 	"errors"
-	"log"
+	"flag"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/OlgaPie/casino-transaction-system/internal/auth"
+	"github.com/OlgaPie/casino-transaction-system/internal/config"
 	"github.com/OlgaPie/casino-transaction-system/internal/handler"
+	"github.com/OlgaPie/casino-transaction-system/internal/ingest"
+	"github.com/OlgaPie/casino-transaction-system/internal/limits"
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/metrics"
+	"github.com/OlgaPie/casino-transaction-system/internal/migrate"
+	"github.com/OlgaPie/casino-transaction-system/internal/reporting"
 	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/tracing"
+	"github.com/OlgaPie/casino-transaction-system/migrations"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/segmentio/kafka-go"
 )
 
 func main() {
-	log.Println("Starting API server...")
+	// Конфигурация: YAML-файл, переменные окружения и флаги
+	cfg, err := config.Load(config.API, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal(logging.New(os.Stdout, slog.LevelInfo), "invalid configuration", err)
+	}
+	logger := logging.New(os.Stdout, cfg.Level())
+	logger.Info("starting API server", slog.Any("config", cfg))
 
 	// Контекст завершения приложения
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	postgresDSN := os.Getenv("POSTGRES_DSN")
+	// Трассировка: span'ы экспортируются по OTLP, если задан OTEL_EXPORTER_OTLP_ENDPOINT
+	tracerProvider, shutdownTracing, err := tracing.NewTracerProvider(ctx, tracing.Config{
+		ServiceName: cfg.Tracing.ServiceName,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal(logger, "failed to set up tracing", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			logger.Error("failed to flush traces", logging.Err(err))
+		}
+	}()
 
-	// 2. Подключение к PostgreSQL
-	dbpool, err := pgxpool.New(ctx, postgresDSN)
+	// 2. Подключение к PostgreSQL; каждый запрос получает свой span
+	dbConfig, err := pgxpool.ParseConfig(cfg.Postgres.DSN)
 	if err != nil {
-		log.Fatalf("Unable to connect to database: %v\n", err)
+		fatal(logger, "invalid POSTGRES_DSN", err)
+	}
+	dbConfig.ConnConfig.Tracer = tracing.NewQueryTracer(tracerProvider)
+	dbpool, err := pgxpool.NewWithConfig(ctx, dbConfig)
+	if err != nil {
+		fatal(logger, "unable to connect to database", err)
 	}
 	defer dbpool.Close()
-	log.Println("Connected to PostgreSQL")
+	logger.Info("connected to PostgreSQL")
+
+	// Миграции схемы при запуске; реплики, стартующие одновременно, ждут друг друга
+	if cfg.MigrateOnStartup {
+		migrator, err := migrate.New(dbpool, migrations.FS, logger)
+		if err != nil {
+			fatal(logger, "invalid migrations", err)
+		}
+		if err := migrator.Up(ctx); err != nil {
+			fatal(logger, "failed to apply migrations", err)
+		}
+	}
+
+	// Метрики Prometheus: запросы к БД, пул соединений и HTTP
+	registry := metrics.NewRegistry()
+	registry.MustRegister(metrics.NewPoolCollector(dbpool))
+	queries := metrics.NewQueries(registry)
+	httpMetrics := metrics.NewHTTP(registry)
 
 	// 3. Инициализация зависимостей
-	txRepo := repository.NewPostgresRepository(dbpool)
-	txHandler := handler.NewTransactionHandler(txRepo)
+	limitRepo := repository.InstrumentLimitRepository(repository.NewPostgresLimitRepository(dbpool), queries)
+	limitChecker := limits.NewChecker(limitRepo)
+	// Лимиты проверяются в транзакции БД, в которой сохраняется транзакция
+	txRepo := repository.InstrumentTransactionRepository(repository.NewPostgresRepository(dbpool, repository.WithSaveChecker(limitChecker)), queries)
+	var txOpts []handler.TransactionHandlerOption
+	if cfg.API.StrictQueryParams {
+		txOpts = append(txOpts, handler.WithStrictQuery())
+	}
+	txHandler := handler.NewTransactionHandler(txRepo, logger, txOpts...)
+	walletRepo := repository.InstrumentWalletRepository(repository.NewPostgresWalletRepository(dbpool), queries)
+	balanceHandler := handler.NewBalanceHandler(walletRepo, logger)
+	currencyRepo := repository.InstrumentCurrencyRepository(repository.NewPostgresCurrencyRepository(dbpool), queries)
+	currencyHandler := handler.NewCurrencyHandler(currencyRepo, logger)
+	limitHandler := handler.NewLimitHandler(limitRepo, logger)
+	reportRepo := repository.InstrumentReportRepository(repository.NewPostgresReportRepository(dbpool), queries)
+	reportHandler := handler.NewReportHandler(reporting.NewReporter(reportRepo), logger)
+
+	// Аутентификация: API-ключи из БД и, если задан AUTH_JWKS_FILE, JWT
+	var jwtVerifier *auth.JWTVerifier
+	if cfg.Auth.JWKSFile != "" {
+		keys, err := auth.LoadJWKS(cfg.Auth.JWKSFile)
+		if err != nil {
+			fatal(logger, "failed to load JWKS", err)
+		}
+		jwtVerifier = auth.NewJWTVerifier(keys, cfg.Auth.JWTIssuer, cfg.Auth.JWTAudience)
+		logger.Info("accepting JWTs", slog.String("jwks_file", cfg.Auth.JWKSFile))
+	}
+	apiKeyRepo := repository.InstrumentAPIKeyRepository(repository.NewPostgresAPIKeyRepository(dbpool), queries)
+	authenticator := auth.NewAuthenticator(auth.NewAPIKeyVerifier(apiKeyRepo), jwtVerifier)
+
+	// RBAC: разрешения ролей из RBAC_CONFIG или встроенной политики
+	policy := auth.DefaultPolicy()
+	if cfg.Auth.RBACConfig != "" {
+		if policy, err = auth.LoadPolicy(cfg.Auth.RBACConfig); err != nil {
+			fatal(logger, "failed to load RBAC policy", err)
+		}
+		logger.Info("loaded RBAC policy", slog.String("file", cfg.Auth.RBACConfig))
+	}
+	access := handler.NewAccessControl(policy, repository.InstrumentAuditRepository(repository.NewPostgresAuditRepository(dbpool), queries), logger)
+
+	// Прием транзакций через HTTP: напрямую в БД или через Kafka (INGEST_MODE=kafka)
+	var sink ingest.Sink
+	if cfg.API.IngestMode == config.IngestKafka {
+		ingestWriter := &kafka.Writer{
+			Addr:                   kafka.TCP(cfg.Kafka.Broker),
+			Topic:                  cfg.Kafka.Topic,
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		}
+		defer func() {
+			if err := ingestWriter.Close(); err != nil {
+				logger.Error("failed to close Kafka writer", logging.Err(err))
+			}
+		}()
+		sink = ingest.NewKafkaSink(ingestWriter)
+		logger.Info("ingestion publishes to Kafka", slog.String(logging.KeyTopic, cfg.Kafka.Topic))
+	} else {
+		// События о нарушениях лимитов публикуются, как у consumer'а, если задан брокер Kafka
+		var limitEvents ingest.MessageWriter
+		if cfg.Kafka.Broker != "" {
+			limitsWriter := &kafka.Writer{
+				Addr:                   kafka.TCP(cfg.Kafka.Broker),
+				Topic:                  cfg.Kafka.LimitsTopic,
+				Balancer:               &kafka.Hash{},
+				RequiredAcks:           kafka.RequireAll,
+				AllowAutoTopicCreation: true,
+			}
+			defer func() {
+				if err := limitsWriter.Close(); err != nil {
+					logger.Error("failed to close Kafka limits writer", logging.Err(err))
+				}
+			}()
+			limitEvents = limitsWriter
+		} else {
+			logger.Warn("limit violation events are not published: Kafka broker is not set")
+		}
+		sink = ingest.NewRepositorySink(txRepo, limitChecker, limitEvents)
+	}
+	ingestHandler := handler.NewIngestHandler(sink, cfg.DefaultCurrency, logger)
 
 	// 4. Настройка роутера
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	r.Use(middleware.RequestID)
+	r.Use(tracing.Middleware(tracerProvider))
+	r.Use(httpMetrics.Middleware)
+	r.Use(logging.Middleware(logger))
+	r.Use(handler.Recoverer(logger))
 
 	// Health endpoints
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -50,46 +189,72 @@ func main() {
 	})
 	r.Get("/ready", func(w http.ResponseWriter, r *http.Request) {
 		if err := dbpool.Ping(r.Context()); err != nil {
-			http.Error(w, "Database not ready", http.StatusServiceUnavailable)
+			handler.WriteProblem(w, r, http.StatusServiceUnavailable, handler.CodeUnavailable, "database is not ready")
 			return
 		}
 		w.WriteHeader(http.StatusOK)
 	})
+	r.Method(http.MethodGet, "/metrics", metrics.Handler(registry))
 
-	// API endpoints
-	r.Get("/transactions", txHandler.GetAllTransactions)
-	r.Get("/users/{userID}/transactions", txHandler.GetUserTransactions)
+	// API endpoints: все требуют API-ключ или JWT
+	r.Group(func(r chi.Router) {
+		r.Use(handler.Authenticate(authenticator, logger))
 
-	// Порт из окружения
-	port := os.Getenv("API_PORT")
-	if port == "" {
-		port = "8080"
-	}
+		r.Get("/currencies", currencyHandler.GetCurrencies)
+
+		// Back-office
+		r.With(access.Require(auth.PermTransactionsRead)).Get("/transactions", txHandler.GetAllTransactions)
+		r.With(access.Require(auth.PermTransactionsWrite)).Post("/transactions", ingestHandler.PostTransactions)
+		r.With(access.Require(auth.PermTransactionsRead)).Get("/transactions/{transactionID}", txHandler.GetTransaction)
+		r.With(access.Require(auth.PermTransactionsRead)).Get("/rounds/{roundID}", txHandler.GetRound)
+		r.With(access.Require(auth.PermReportsRead)).Get("/reports/ggr", reportHandler.GetGGR)
+
+		// Данные пользователя: сам игрок или back-office с users:read
+		r.Route("/users/{userID}", func(r chi.Router) {
+			r.Use(access.RequireUserAccess)
+			r.Get("/transactions", txHandler.GetUserTransactions)
+			r.Get("/summary", txHandler.GetUserSummary)
+			r.Get("/balance", balanceHandler.GetUserBalance)
+		})
+
+		// Административные endpoints
+		r.Route("/admin/users/{userID}", func(r chi.Router) {
+			r.With(access.Require(auth.PermLimitsRead)).Get("/limits", limitHandler.GetUserLimits)
+			r.With(access.Require(auth.PermLimitsWrite)).Put("/limits", limitHandler.SetUserLimits)
+			r.With(access.Require(auth.PermLimitsWrite)).Put("/self-exclusion", limitHandler.SetSelfExclusion)
+		})
+	})
 
 	srv := &http.Server{
-		Addr:    ":" + port,
+		Addr:    cfg.APIAddr(),
 		Handler: r,
 	}
 
 	// Запуск сервера
 	go func() {
-		log.Printf("API server is listening on :%s\n", port)
+		logger.Info("API server is listening", slog.String("addr", srv.Addr))
 		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal(logger, "failed to start server", err)
 		}
 	}()
 
 	// Ожидание сигнала завершения
 	<-ctx.Done()
-	log.Println("Shutting down server...")
+	logger.Info("shutting down server")
 
 	// Graceful shutdown
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server shutdown failed: %v", err)
+		logger.Error("server shutdown failed", logging.Err(err))
 	}
 
-	log.Println("Server exited properly")
+	logger.Info("server exited properly")
+}
+
+// fatal логирует ошибку запуска и завершает процесс.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, logging.Err(err))
+	os.Exit(1)
 }
@@ -2,73 +2,220 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/OlgaPie/casino-transaction-system/internal/config"
 	"github.com/OlgaPie/casino-transaction-system/internal/consumer"
+	"github.com/OlgaPie/casino-transaction-system/internal/limits"
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/metrics"
+	"github.com/OlgaPie/casino-transaction-system/internal/migrate"
 	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/tracing"
+	"github.com/OlgaPie/casino-transaction-system/migrations"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/segmentio/kafka-go"
 	"golang.org/x/sync/errgroup"
 )
 
 func main() {
-	log.Println("Starting consumer...")
+	if err := run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// run запускает consumer до сигнала на завершение или ошибки. Ошибка возвращается
+// уже залогированной и после всех отложенных закрытий, поэтому main завершает процесс
+// только когда reader и writer'ы Kafka закрыты, а трассы выгружены.
+func run() error {
+	// Конфигурация: YAML-файл, переменные окружения и флаги
+	cfg, err := config.Load(config.Consumer, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return failed(logging.New(os.Stdout, slog.LevelInfo), "invalid configuration", err)
+	}
+	logger := logging.New(os.Stdout, cfg.Level())
+	logger.Info("starting consumer", slog.Any("config", cfg))
 
 	// 1. Контекст с автоматической отменой по сигналу
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	postgresDSN := os.Getenv("POSTGRES_DSN")
-	kafkaBroker := os.Getenv("KAFKA_BROKER")
-	kafkaTopic := os.Getenv("KAFKA_TOPIC")
-	if kafkaTopic == "" {
-		kafkaTopic = "transactions"
+	// Трассировка: span'ы экспортируются по OTLP, если задан OTEL_EXPORTER_OTLP_ENDPOINT
+	tracerProvider, shutdownTracing, err := tracing.NewTracerProvider(ctx, tracing.Config{
+		ServiceName: cfg.Tracing.ServiceName,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return failed(logger, "failed to set up tracing", err)
 	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			logger.Error("failed to flush traces", logging.Err(err))
+		}
+	}()
 
-	// 2. Подключение к PostgreSQL
-	dbpool, err := pgxpool.New(ctx, postgresDSN)
+	// 2. Подключение к PostgreSQL; каждый запрос получает свой span
+	dbConfig, err := pgxpool.ParseConfig(cfg.Postgres.DSN)
 	if err != nil {
-		log.Fatalf("Unable to connect to database: %v", err)
+		return failed(logger, "invalid POSTGRES_DSN", err)
+	}
+	dbConfig.ConnConfig.Tracer = tracing.NewQueryTracer(tracerProvider)
+	dbpool, err := pgxpool.NewWithConfig(ctx, dbConfig)
+	if err != nil {
+		return failed(logger, "unable to connect to database", err)
 	}
 	defer dbpool.Close()
-	log.Println("Connected to PostgreSQL")
+	logger.Info("connected to PostgreSQL")
+
+	// Миграции схемы при запуске; реплики, стартующие одновременно, ждут друг друга
+	if cfg.MigrateOnStartup {
+		migrator, err := migrate.New(dbpool, migrations.FS, logger)
+		if err != nil {
+			return failed(logger, "invalid migrations", err)
+		}
+		if err := migrator.Up(ctx); err != nil {
+			return failed(logger, "failed to apply migrations", err)
+		}
+	}
 
 	// 3. Инициализация Kafka reader
 	kafkaReader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:        []string{kafkaBroker},
-		Topic:          kafkaTopic,
-		GroupID:        "transaction-savers",
+		Brokers:        []string{cfg.Kafka.Broker},
+		Topic:          cfg.Kafka.Topic,
+		GroupID:        cfg.Kafka.GroupID,
 		CommitInterval: 0,
 	})
 	defer func() {
 		if err := kafkaReader.Close(); err != nil {
-			log.Printf("Failed to close Kafka reader: %v", err)
+			logger.Error("failed to close Kafka reader", logging.Err(err))
+		}
+	}()
+	logger.Info("connected to Kafka")
+
+	// Writer для сообщений, которые не удалось обработать
+	dlqWriter := &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Kafka.Broker),
+		Topic:                  cfg.Kafka.DLQTopic,
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+	defer func() {
+		if err := dlqWriter.Close(); err != nil {
+			logger.Error("failed to close Kafka DLQ writer", logging.Err(err))
 		}
 	}()
-	log.Println("Connected to Kafka")
+
+	// Writer для событий о нарушении лимитов ответственной игры
+	limitsWriter := &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Kafka.Broker),
+		Topic:                  cfg.Kafka.LimitsTopic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+	defer func() {
+		if err := limitsWriter.Close(); err != nil {
+			logger.Error("failed to close Kafka limits writer", logging.Err(err))
+		}
+	}()
+
+	// Метрики Prometheus: обработка сообщений, Kafka reader, запросы к БД и пул соединений
+	registry := metrics.NewRegistry()
+	registry.MustRegister(metrics.NewReaderCollector(kafkaReader), metrics.NewPoolCollector(dbpool))
+	queries := metrics.NewQueries(registry)
 
 	// 4. Зависимости
-	txRepo := repository.NewPostgresRepository(dbpool)
-	consumerHandler := consumer.NewHandler(kafkaReader, txRepo)
+	// Лимиты проверяются в транзакции БД, в которой сохраняется транзакция
+	limitChecker := limits.NewChecker(repository.InstrumentLimitRepository(repository.NewPostgresLimitRepository(dbpool), queries))
+	txRepo := repository.InstrumentTransactionRepository(repository.NewPostgresRepository(dbpool, repository.WithSaveChecker(limitChecker)), queries)
+	handlerOpts := []consumer.Option{
+		consumer.WithDeadLetterWriter(dlqWriter),
+		consumer.WithLimits(limitChecker, limitsWriter),
+		consumer.WithMetrics(metrics.NewConsumer(registry)),
+		consumer.WithTracerProvider(tracerProvider),
+		consumer.WithLogger(logger),
+	}
+	// Пакетная обработка включается, если размер пакета больше 1
+	if cfg.Consumer.BatchSize > 1 {
+		handlerOpts = append(handlerOpts, consumer.WithBatching(cfg.Consumer.BatchSize, cfg.Consumer.BatchLinger))
+		logger.Info("batch mode enabled", slog.Int("batch_size", cfg.Consumer.BatchSize))
+	}
+	// Параллельная обработка включается, если воркеров больше 1
+	if cfg.Consumer.Workers > 1 {
+		routing := consumer.RouteByPartition
+		if cfg.Consumer.Routing == config.RoutingUser {
+			routing = consumer.RouteByUser
+		}
+		handlerOpts = append(handlerOpts, consumer.WithWorkers(cfg.Consumer.Workers, routing))
+		logger.Info("concurrent mode enabled", slog.Int("workers", cfg.Consumer.Workers))
+	}
+	// Без ограничения попыток временные ошибки не уходят в DLQ, но останавливают партицию
+	if cfg.Consumer.RetryUntilStopped {
+		handlerOpts = append(handlerOpts, consumer.WithRetryUntilStopped())
+	}
+	// Валюта для сообщений без поля currency; без нее такие сообщения уходят в DLQ
+	if cfg.DefaultCurrency != "" {
+		handlerOpts = append(handlerOpts, consumer.WithDefaultCurrency(cfg.DefaultCurrency))
+	}
+	consumerHandler := consumer.NewHandler(kafkaReader, txRepo, handlerOpts...)
+
+	// HTTP-сервер для /metrics
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler(registry))
+	metricsSrv := &http.Server{Addr: cfg.MetricsAddr(), Handler: mux}
 
-	// 5. Запуск с errgroup
-	g := new(errgroup.Group)
+	// 5. Запуск с errgroup: ошибка любой горутины отменяет gctx и останавливает остальные
+	g, gctx := errgroup.WithContext(ctx)
 	g.Go(func() error {
-		consumerHandler.ProcessMessages(ctx)
+		consumerHandler.ProcessMessages(gctx)
+		if gctx.Err() == nil {
+			return errors.New("message processing stopped")
+		}
+		return nil
+	})
+	g.Go(func() error {
+		logger.Info("metrics server is listening", slog.String("addr", metricsSrv.Addr))
+		if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("metrics server: %w", err)
+		}
 		return nil
 	})
 
-	// 6. Ожидание сигнала на завершение
-	<-ctx.Done()
-	log.Println("Shutting down consumer gracefully...")
+	// 6. Ожидание сигнала на завершение или ошибки в одной из горутин
+	<-gctx.Done()
+	logger.Info("shutting down consumer gracefully")
 	stop()
 
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("metrics server shutdown failed", logging.Err(err))
+	}
+
 	if err := g.Wait(); err != nil {
-		log.Printf("Error in consumer goroutine: %v", err)
+		return failed(logger, "consumer stopped with an error", err)
 	}
 
-	log.Println("Consumer exited properly")
+	logger.Info("consumer exited properly")
+	return nil
+}
+
+// failed логирует ошибку и возвращает ее для завершения run.
+func failed(logger *slog.Logger, msg string, err error) error {
+	logger.Error(msg, logging.Err(err))
+	return err
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/config"
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/migrate"
+	"github.com/OlgaPie/casino-transaction-system/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const usage = `usage: migrate [flags] <command>
+
+commands:
+  up                 apply all pending migrations
+  down [N]           revert the last N applied migrations (default 1)
+  to VERSION         apply or revert migrations until VERSION is the latest applied; 0 reverts all
+  status             list migrations and whether they are applied
+  baseline VERSION   record migrations up to VERSION as applied without running them,
+                     for a database whose schema predates schema_migrations
+
+Run with -help to list the flags.
+`
+
+func main() {
+	// Конфигурация: YAML-файл, переменные окружения и флаги; после флагов — команда
+	cfg, err := config.Load(config.Migrate, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(os.Stderr, usage)
+		return
+	}
+	if err != nil {
+		fatal(logging.New(os.Stdout, slog.LevelInfo), "invalid configuration", err)
+	}
+	logger := logging.New(os.Stdout, cfg.Level())
+
+	args := cfg.Args()
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	dbpool, err := pgxpool.New(ctx, cfg.Postgres.DSN)
+	if err != nil {
+		fatal(logger, "unable to connect to database", err)
+	}
+	defer dbpool.Close()
+
+	migrator, err := migrate.New(dbpool, migrations.FS, logger)
+	if err != nil {
+		fatal(logger, "invalid migrations", err)
+	}
+
+	if err := run(ctx, migrator, args); errors.Is(err, errUsage) {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	} else if err != nil {
+		fatal(logger, "migration failed", err)
+	}
+}
+
+// errUsage — неизвестная команда или неверное число аргументов.
+var errUsage = errors.New("invalid command")
+
+// run выполняет команду migrate.
+func run(ctx context.Context, migrator *migrate.Migrator, args []string) error {
+	command, rest := args[0], args[1:]
+	switch {
+	case command == "up" && len(rest) == 0:
+		return migrator.Up(ctx)
+	case command == "down" && len(rest) <= 1:
+		steps := 1
+		if len(rest) == 1 {
+			n, err := strconv.Atoi(rest[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q", rest[0])
+			}
+			steps = n
+		}
+		return migrator.Down(ctx, steps)
+	case (command == "to" || command == "baseline") && len(rest) == 1:
+		version, err := strconv.ParseInt(rest[0], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", rest[0])
+		}
+		if command == "baseline" {
+			return migrator.Baseline(ctx, version)
+		}
+		return migrator.To(ctx, version)
+	case command == "status" && len(rest) == 0:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printStatus(statuses)
+	}
+	return errUsage
+}
+
+// printStatus выводит таблицу версий в stdout.
+func printStatus(statuses []migrate.Status) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if !s.AppliedAt.IsZero() {
+			appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt)
+	}
+	return w.Flush()
+}
+
+// fatal логирует ошибку и завершает процесс.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, logging.Err(err))
+	os.Exit(1)
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+)
+
+// HashAPIKey возвращает SHA-256 ключа в hex — в таком виде ключи хранятся в api_keys.
+// Ключи генерируются случайно и длинные, поэтому соль и медленный хеш не нужны.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyVerifier проверяет статические API-ключи по их хешам в БД.
+type APIKeyVerifier struct {
+	repo repository.APIKeyRepository
+}
+
+func NewAPIKeyVerifier(repo repository.APIKeyRepository) *APIKeyVerifier {
+	return &APIKeyVerifier{repo: repo}
+}
+
+// Verify ищет ключ по хешу. Неизвестный или отозванный ключ дает ErrInvalidCredentials.
+func (v *APIKeyVerifier) Verify(ctx context.Context, key string) (Principal, error) {
+	apiKey, err := v.repo.GetAPIKey(ctx, HashAPIKey(key))
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return Principal{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{Subject: apiKey.Name, Scopes: apiKey.Scopes, Roles: apiKey.Roles, Method: MethodAPIKey}, nil
+}
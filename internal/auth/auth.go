@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Разрешения на endpoint'ы. Выдаются ролям в политике RBAC или напрямую
+// API-ключам и токенам как scopes.
+const (
+	// PermTransactionsRead — транзакции всех игроков и раунды.
+	PermTransactionsRead = "transactions:read"
+	// PermTransactionsWrite — прием транзакций через POST /transactions.
+	PermTransactionsWrite = "transactions:write"
+	// PermUsersRead — данные любого пользователя по /users/{userID}/...
+	PermUsersRead   = "users:read"
+	PermReportsRead = "reports:read"
+	PermLimitsRead  = "limits:read"
+	PermLimitsWrite = "limits:write"
+)
+
+// Permissions перечисляет все разрешения; политика RBAC не может ссылаться на другие.
+var Permissions = []string{
+	PermTransactionsRead,
+	PermTransactionsWrite,
+	PermUsersRead,
+	PermReportsRead,
+	PermLimitsRead,
+	PermLimitsWrite,
+}
+
+// ScopeUserRead — scope клиентов игрока: дает доступ только к /users/{userID}/...
+// своего subject.
+const ScopeUserRead = "user:read"
+
+var (
+	// ErrMissingCredentials возвращается, если в запросе нет ни API-ключа, ни токена.
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials возвращается для неизвестного ключа и недействительного токена.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// APIKeyHeader — заголовок со статическим API-ключом.
+const APIKeyHeader = "X-API-Key"
+
+// Method — способ, которым клиент подтвердил свою личность.
+type Method string
+
+const (
+	MethodAPIKey Method = "api_key"
+	MethodJWT    Method = "jwt"
+)
+
+// Principal — проверенный клиент запроса.
+type Principal struct {
+	// Subject — sub токена или имя API-ключа; для токенов игрока совпадает с user_id.
+	Subject string
+	Scopes  []string
+	// Roles — роли back-office; их разрешения определяет Policy.
+	Roles  []string
+	Method Method
+}
+
+// HasScope сообщает, выдана ли клиенту область доступа scope.
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+// NewContext возвращает копию ctx с клиентом запроса.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает клиента, сохраненного Authenticate.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Authenticator проверяет API-ключи и JWT из заголовков запроса.
+type Authenticator struct {
+	apiKeys *APIKeyVerifier
+	jwt     *JWTVerifier
+	now     func() time.Time
+}
+
+// NewAuthenticator создает Authenticator. Если jwt равен nil, bearer-токены отклоняются
+// и принимаются только API-ключи.
+func NewAuthenticator(apiKeys *APIKeyVerifier, jwt *JWTVerifier) *Authenticator {
+	return &Authenticator{apiKeys: apiKeys, jwt: jwt, now: time.Now}
+}
+
+// Authenticate проверяет заголовок Authorization: Bearer <JWT> или X-API-Key.
+// Ошибки, кроме ErrMissingCredentials и ErrInvalidCredentials, означают сбой хранилища ключей.
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || a.jwt == nil {
+			return Principal{}, ErrInvalidCredentials
+		}
+		return a.jwt.Verify(strings.TrimSpace(token), a.now())
+	}
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.apiKeys.Verify(r.Context(), key)
+	}
+	return Principal{}, ErrMissingCredentials
+}
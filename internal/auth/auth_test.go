@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	signer := newRSASigner(t, "rsa-1")
+	repo := new(mocks.APIKeyRepository)
+	authenticator := NewAuthenticator(NewAPIKeyVerifier(repo), NewJWTVerifier(testKeySet(t, signer), "", ""))
+	authenticator.now = func() time.Time { return testNow }
+
+	t.Run("should accept a known API key", func(t *testing.T) {
+		repo.On("GetAPIKey", mock.Anything, HashAPIKey("secret-key")).
+			Return(models.APIKey{Name: "backoffice", Scopes: []string{PermTransactionsRead}, Roles: []string{"support"}}, nil).Once()
+
+		req := httptest.NewRequest("GET", "/transactions", nil)
+		req.Header.Set(APIKeyHeader, "secret-key")
+		principal, err := authenticator.Authenticate(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, Principal{Subject: "backoffice", Scopes: []string{PermTransactionsRead}, Roles: []string{"support"}, Method: MethodAPIKey}, principal)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should reject an unknown API key", func(t *testing.T) {
+		repo.On("GetAPIKey", mock.Anything, HashAPIKey("wrong-key")).
+			Return(models.APIKey{}, repository.ErrAPIKeyNotFound).Once()
+
+		req := httptest.NewRequest("GET", "/transactions", nil)
+		req.Header.Set(APIKeyHeader, "wrong-key")
+		_, err := authenticator.Authenticate(req)
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("should pass through key store errors", func(t *testing.T) {
+		repo.On("GetAPIKey", mock.Anything, HashAPIKey("any-key")).
+			Return(models.APIKey{}, errors.New("database is down")).Once()
+
+		req := httptest.NewRequest("GET", "/transactions", nil)
+		req.Header.Set(APIKeyHeader, "any-key")
+		_, err := authenticator.Authenticate(req)
+
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("should accept a bearer token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/users/user-42/balance", nil)
+		req.Header.Set("Authorization", "Bearer "+signer.sign(t, nil, validClaims()))
+		principal, err := authenticator.Authenticate(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, "user-42", principal.Subject)
+		assert.Equal(t, MethodJWT, principal.Method)
+	})
+
+	t.Run("should reject other authorization schemes", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/transactions", nil)
+		req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+		_, err := authenticator.Authenticate(req)
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("should reject bearer tokens without JWKS", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/transactions", nil)
+		req.Header.Set("Authorization", "Bearer "+signer.sign(t, nil, validClaims()))
+		_, err := NewAuthenticator(NewAPIKeyVerifier(repo), nil).Authenticate(req)
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("should report missing credentials", func(t *testing.T) {
+		_, err := authenticator.Authenticate(httptest.NewRequest("GET", "/transactions", nil))
+		assert.ErrorIs(t, err, ErrMissingCredentials)
+	})
+}
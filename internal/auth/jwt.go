@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clockSkew — допустимое расхождение часов при проверке exp и nbf.
+const clockSkew = 30 * time.Second
+
+// JWTVerifier проверяет подпись и срок действия JWT и превращает claims в Principal.
+type JWTVerifier struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+}
+
+// NewJWTVerifier создает проверку токенов; пустые issuer и audience не проверяются.
+func NewJWTVerifier(keys *KeySet, issuer, audience string) *JWTVerifier {
+	return &JWTVerifier{keys: keys, issuer: issuer, audience: audience}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Scope     string   `json:"scope"`
+	Scp       []string `json:"scp"`
+	Roles     []string `json:"roles"`
+}
+
+// audience принимает aud и строкой, и массивом строк (RFC 7519, раздел 4.1.3).
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Verify проверяет токен на момент now. Любая ошибка проверки дает ErrInvalidCredentials
+// с причиной в тексте.
+func (v *JWTVerifier) Verify(token string, now time.Time) (Principal, error) {
+	claims, err := v.verify(token, now)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	// scope — строка через пробел (RFC 8693), scp — массив, как у части провайдеров.
+	scopes := strings.Fields(claims.Scope)
+	scopes = append(scopes, claims.Scp...)
+	return Principal{Subject: claims.Subject, Scopes: scopes, Roles: claims.Roles, Method: MethodJWT}, nil
+}
+
+func (v *JWTVerifier) verify(token string, now time.Time) (jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return jwtClaims{}, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return jwtClaims{}, errors.New("malformed header")
+	}
+	key, ok := v.keys.lookup(header.Kid)
+	if !ok {
+		return jwtClaims{}, fmt.Errorf("unknown kid %q", header.Kid)
+	}
+	// Алгоритм берется из ключа, а не из токена: так нельзя подменить его на none или HS256.
+	if header.Alg != key.alg {
+		return jwtClaims{}, fmt.Errorf("unexpected alg %q", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return jwtClaims{}, errors.New("malformed signature")
+	}
+	if !verifySignature(key, parts[0]+"."+parts[1], signature) {
+		return jwtClaims{}, errors.New("invalid signature")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return jwtClaims{}, errors.New("malformed claims")
+	}
+	if claims.ExpiresAt == nil {
+		return jwtClaims{}, errors.New("missing exp")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(clockSkew)) {
+		return jwtClaims{}, errors.New("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(*claims.NotBefore, 0)) {
+		return jwtClaims{}, errors.New("token not valid yet")
+	}
+	if claims.Subject == "" {
+		return jwtClaims{}, errors.New("missing sub")
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return jwtClaims{}, fmt.Errorf("unexpected iss %q", claims.Issuer)
+	}
+	if v.audience != "" && !slices.Contains(claims.Audience, v.audience) {
+		return jwtClaims{}, errors.New("unexpected aud")
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func verifySignature(key publicKey, signingInput string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch pub := key.key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		// ES256 хранит подпись как r||s по 32 байта, а не в ASN.1.
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// testSigner подписывает тестовые токены и отдает свой открытый ключ в виде JWK.
+type testSigner struct {
+	kid string
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newRSASigner(t *testing.T, kid string) testSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return testSigner{kid: kid, rsa: key}
+}
+
+func newECSigner(t *testing.T, kid string) testSigner {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return testSigner{kid: kid, ec: key}
+}
+
+func (s testSigner) jwk() map[string]string {
+	if s.rsa != nil {
+		return map[string]string{
+			"kid": s.kid, "kty": "RSA", "alg": "RS256", "use": "sig",
+			"n": b64(s.rsa.N.Bytes()),
+			"e": b64(big.NewInt(int64(s.rsa.E)).Bytes()),
+		}
+	}
+	point, _ := s.ec.PublicKey.Bytes()
+	return map[string]string{
+		"kid": s.kid, "kty": "EC", "crv": "P-256", "alg": "ES256",
+		"x": b64(point[1:33]), "y": b64(point[33:]),
+	}
+}
+
+func (s testSigner) sign(t *testing.T, header, claims map[string]any) string {
+	if header == nil {
+		header = map[string]any{"kid": s.kid, "alg": "RS256"}
+		if s.ec != nil {
+			header["alg"] = "ES256"
+		}
+	}
+	headerJSON, err := json.Marshal(header)
+	require.NoError(t, err)
+	claimsJSON, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	input := b64(headerJSON) + "." + b64(claimsJSON)
+	digest := sha256.Sum256([]byte(input))
+	var signature []byte
+	if s.rsa != nil {
+		signature, err = rsa.SignPKCS1v15(rand.Reader, s.rsa, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	} else {
+		r, sig, err := ecdsa.Sign(rand.Reader, s.ec, digest[:])
+		require.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), sig.FillBytes(make([]byte, 32))...)
+	}
+	return input + "." + b64(signature)
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func testKeySet(t *testing.T, signers ...testSigner) *KeySet {
+	keys := make([]map[string]string, len(signers))
+	for i, s := range signers {
+		keys[i] = s.jwk()
+	}
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	require.NoError(t, err)
+	ks, err := ParseJWKS(data)
+	require.NoError(t, err)
+	return ks
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":   "user-42",
+		"iss":   "https://auth.example.com",
+		"aud":   []string{"casino-api"},
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"scope": "user:read",
+	}
+}
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	rsaSigner := newRSASigner(t, "rsa-1")
+	ecSigner := newECSigner(t, "ec-1")
+	verifier := NewJWTVerifier(testKeySet(t, rsaSigner, ecSigner), "https://auth.example.com", "casino-api")
+
+	t.Run("should accept RS256 and ES256 tokens", func(t *testing.T) {
+		for _, signer := range []testSigner{rsaSigner, ecSigner} {
+			principal, err := verifier.Verify(signer.sign(t, nil, validClaims()), testNow)
+			require.NoError(t, err, signer.kid)
+			assert.Equal(t, Principal{Subject: "user-42", Scopes: []string{ScopeUserRead}, Method: MethodJWT}, principal)
+		}
+	})
+
+	t.Run("should read scopes from scope and scp", func(t *testing.T) {
+		claims := validClaims()
+		claims["scope"] = "transactions:read limits:write"
+		claims["scp"] = []string{"transactions:write"}
+
+		principal, err := verifier.Verify(rsaSigner.sign(t, nil, claims), testNow)
+		require.NoError(t, err)
+		assert.Equal(t, []string{PermTransactionsRead, PermLimitsWrite, PermTransactionsWrite}, principal.Scopes)
+	})
+
+	t.Run("should read roles", func(t *testing.T) {
+		claims := validClaims()
+		claims["roles"] = []string{"support", "finance"}
+
+		principal, err := verifier.Verify(rsaSigner.sign(t, nil, claims), testNow)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"support", "finance"}, principal.Roles)
+	})
+
+	t.Run("should accept a single audience string", func(t *testing.T) {
+		claims := validClaims()
+		claims["aud"] = "casino-api"
+
+		_, err := verifier.Verify(rsaSigner.sign(t, nil, claims), testNow)
+		assert.NoError(t, err)
+	})
+
+	rejected := map[string]func() string{
+		"expired": func() string {
+			claims := validClaims()
+			claims["exp"] = testNow.Add(-time.Minute).Unix()
+			return rsaSigner.sign(t, nil, claims)
+		},
+		"missing exp": func() string {
+			claims := validClaims()
+			delete(claims, "exp")
+			return rsaSigner.sign(t, nil, claims)
+		},
+		"not valid yet": func() string {
+			claims := validClaims()
+			claims["nbf"] = testNow.Add(time.Minute).Unix()
+			return rsaSigner.sign(t, nil, claims)
+		},
+		"wrong issuer": func() string {
+			claims := validClaims()
+			claims["iss"] = "https://evil.example.com"
+			return rsaSigner.sign(t, nil, claims)
+		},
+		"wrong audience": func() string {
+			claims := validClaims()
+			claims["aud"] = "other-api"
+			return rsaSigner.sign(t, nil, claims)
+		},
+		"missing subject": func() string {
+			claims := validClaims()
+			delete(claims, "sub")
+			return rsaSigner.sign(t, nil, claims)
+		},
+		"unknown kid": func() string {
+			return newRSASigner(t, "rsa-2").sign(t, nil, validClaims())
+		},
+		"signed by another key with known kid": func() string {
+			return newRSASigner(t, "rsa-1").sign(t, nil, validClaims())
+		},
+		"alg none": func() string {
+			token := rsaSigner.sign(t, map[string]any{"kid": "rsa-1", "alg": "none"}, validClaims())
+			return token[:strings.LastIndex(token, ".")+1]
+		},
+		"alg of another key type": func() string {
+			return rsaSigner.sign(t, map[string]any{"kid": "rsa-1", "alg": "ES256"}, validClaims())
+		},
+		"malformed": func() string {
+			return "not-a-token"
+		},
+	}
+	for name, token := range rejected {
+		t.Run("should reject "+name, func(t *testing.T) {
+			_, err := verifier.Verify(token(), testNow)
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+		})
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	t.Run("should use the only key for tokens without kid", func(t *testing.T) {
+		signer := newECSigner(t, "ec-1")
+		verifier := NewJWTVerifier(testKeySet(t, signer), "", "")
+
+		_, err := verifier.Verify(signer.sign(t, map[string]any{"alg": "ES256"}, validClaims()), testNow)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject unsupported and empty key sets", func(t *testing.T) {
+		for _, data := range []string{
+			`{"keys": []}`,
+			`{"keys": [{"kid": "k", "kty": "oct", "k": "c2VjcmV0"}]}`,
+			`{"keys": [{"kid": "k", "kty": "EC", "crv": "P-521", "x": "AA", "y": "AA"}]}`,
+			`not json`,
+		} {
+			_, err := ParseJWKS([]byte(data))
+			assert.Error(t, err, data)
+		}
+	})
+
+	t.Run("should skip encryption keys", func(t *testing.T) {
+		signer := newRSASigner(t, "rsa-1")
+		jwk := signer.jwk()
+		data, err := json.Marshal(map[string]any{"keys": []any{
+			map[string]string{"kid": "enc-1", "kty": "oct", "use": "enc"},
+			jwk,
+		}})
+		require.NoError(t, err)
+
+		ks, err := ParseJWKS(data)
+		require.NoError(t, err)
+		_, ok := ks.lookup("rsa-1")
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed rbac.yaml
+var defaultPolicy []byte
+
+// Policy сопоставляет ролям back-office их разрешения.
+type Policy struct {
+	roles map[string][]string
+}
+
+// DefaultPolicy возвращает встроенную политику из rbac.yaml.
+func DefaultPolicy() *Policy {
+	policy, err := ParsePolicy(defaultPolicy)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default rbac policy: %v", err))
+	}
+	return policy
+}
+
+// LoadPolicy читает политику RBAC из YAML-файла.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read rbac policy: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy разбирает политику вида {roles: {role: [permission, ...]}}.
+// Неизвестное разрешение — ошибка: опечатка не должна молча лишать роль доступа.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var config struct {
+		Roles map[string][]string `yaml:"roles"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("could not parse rbac policy: %w", err)
+	}
+	for role, permissions := range config.Roles {
+		for _, permission := range permissions {
+			if !slices.Contains(Permissions, permission) {
+				return nil, fmt.Errorf("role %q: unknown permission %q", role, permission)
+			}
+		}
+	}
+	return &Policy{roles: config.Roles}, nil
+}
+
+// Allows сообщает, есть ли у клиента разрешение: выданное напрямую как scope
+// или через одну из его ролей. Неизвестные политике роли ничего не дают.
+func (p *Policy) Allows(principal Principal, permission string) bool {
+	if principal.HasScope(permission) {
+		return true
+	}
+	for _, role := range principal.Roles {
+		if slices.Contains(p.roles[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanReadUser сообщает, может ли клиент читать данные пользователя userID:
+// игрок — только свои, back-office — при наличии PermUsersRead.
+func (p *Policy) CanReadUser(principal Principal, userID string) bool {
+	if principal.HasScope(ScopeUserRead) && principal.Subject == userID {
+		return true
+	}
+	return p.Allows(principal, PermUsersRead)
+}
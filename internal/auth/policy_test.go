@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+roles:
+  support: [users:read]
+  finance: [transactions:read, reports:read]
+`))
+	require.NoError(t, err)
+
+	support := Principal{Subject: "agent-1", Roles: []string{"support"}}
+	finance := Principal{Subject: "analyst-1", Roles: []string{"finance"}}
+	player := Principal{Subject: "user-42", Scopes: []string{ScopeUserRead}}
+	scoped := Principal{Subject: "backoffice", Scopes: []string{PermTransactionsRead}}
+	unknownRole := Principal{Subject: "agent-2", Roles: []string{"superuser"}}
+
+	t.Run("should grant permissions of roles and scopes", func(t *testing.T) {
+		assert.True(t, policy.Allows(finance, PermTransactionsRead))
+		assert.True(t, policy.Allows(finance, PermReportsRead))
+		assert.True(t, policy.Allows(scoped, PermTransactionsRead))
+		assert.False(t, policy.Allows(support, PermTransactionsRead))
+		assert.False(t, policy.Allows(unknownRole, PermTransactionsRead))
+		assert.False(t, policy.Allows(player, PermUsersRead))
+	})
+
+	t.Run("should let players read only their own data", func(t *testing.T) {
+		assert.True(t, policy.CanReadUser(player, "user-42"))
+		assert.False(t, policy.CanReadUser(player, "user-43"))
+		assert.True(t, policy.CanReadUser(support, "user-43"))
+		assert.False(t, policy.CanReadUser(finance, "user-43"))
+	})
+
+	t.Run("should reject unknown permissions", func(t *testing.T) {
+		_, err := ParsePolicy([]byte(`roles: {support: [users:raed]}`))
+		assert.ErrorContains(t, err, `unknown permission "users:raed"`)
+	})
+
+	t.Run("should load the default policy", func(t *testing.T) {
+		policy := DefaultPolicy()
+		assert.True(t, policy.CanReadUser(Principal{Roles: []string{"support"}}, "user-43"))
+		assert.False(t, policy.Allows(Principal{Roles: []string{"support"}}, PermTransactionsRead))
+		for _, permission := range Permissions {
+			assert.True(t, policy.Allows(Principal{Roles: []string{"admin"}}, permission), permission)
+		}
+	})
+}
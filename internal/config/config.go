@@ -0,0 +1,398 @@
+// Package config собирает настройки сервисов из YAML-файла, переменных окружения
+// и флагов командной строки.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// Service — сервис, для которого загружается конфигурация. От него зависят
+// значения по умолчанию и обязательные параметры.
+type Service string
+
+const (
+	API      Service = "api"
+	Consumer Service = "consumer"
+	Migrate  Service = "migrate"
+)
+
+// Режимы приема транзакций через POST /transactions.
+const (
+	IngestDirect = "direct"
+	IngestKafka  = "kafka"
+)
+
+// Способы распределения сообщений между воркерами consumer'а.
+const (
+	RoutingPartition = "partition"
+	RoutingUser      = "user"
+)
+
+// Config — настройки обоих сервисов. Параметры, не используемые сервисом, игнорируются.
+type Config struct {
+	LogLevel        string `yaml:"log_level"`
+	DefaultCurrency string `yaml:"default_currency"`
+	// MigrateOnStartup — применять миграции схемы при запуске API и consumer'а.
+	MigrateOnStartup bool           `yaml:"migrate_on_startup"`
+	Postgres         PostgresConfig `yaml:"postgres"`
+	Kafka            KafkaConfig    `yaml:"kafka"`
+	API              APIConfig      `yaml:"api"`
+	Consumer         ConsumerConfig `yaml:"consumer"`
+	Metrics          MetricsConfig  `yaml:"metrics"`
+	Auth             AuthConfig     `yaml:"auth"`
+	Tracing          TracingConfig  `yaml:"tracing"`
+
+	service Service
+	args    []string
+}
+
+type PostgresConfig struct {
+	DSN string `yaml:"dsn"`
+}
+
+type KafkaConfig struct {
+	Broker      string `yaml:"broker"`
+	Topic       string `yaml:"topic"`
+	GroupID     string `yaml:"group_id"`
+	DLQTopic    string `yaml:"dlq_topic"`
+	LimitsTopic string `yaml:"limits_topic"`
+}
+
+type APIConfig struct {
+	Host              string `yaml:"host"`
+	Port              int    `yaml:"port"`
+	IngestMode        string `yaml:"ingest_mode"`
+	StrictQueryParams bool   `yaml:"strict_query_params"`
+}
+
+type ConsumerConfig struct {
+	BatchSize         int           `yaml:"batch_size"`
+	BatchLinger       time.Duration `yaml:"batch_linger"`
+	Workers           int           `yaml:"workers"`
+	Routing           string        `yaml:"routing"`
+	RetryUntilStopped bool          `yaml:"retry_until_stopped"`
+}
+
+type MetricsConfig struct {
+	Port int `yaml:"port"`
+}
+
+type AuthConfig struct {
+	JWKSFile    string `yaml:"jwks_file"`
+	JWTIssuer   string `yaml:"jwt_issuer"`
+	JWTAudience string `yaml:"jwt_audience"`
+	RBACConfig  string `yaml:"rbac_config"`
+}
+
+type TracingConfig struct {
+	ServiceName string  `yaml:"service_name"`
+	Endpoint    string  `yaml:"endpoint"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// setting связывает поле Config с переменной окружения и флагом.
+// key — путь в YAML, из него же строится имя флага: kafka.group_id -> -kafka-group-id.
+type setting struct {
+	key   string
+	env   string
+	usage string
+	value any // *string, *int, *bool, *float64 или *time.Duration
+}
+
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// settings перечисляет все параметры в порядке вывода.
+func (c *Config) settings() []setting {
+	return []setting{
+		{"log_level", "LOG_LEVEL", "minimum log level: debug, info, warn or error", &c.LogLevel},
+		{"default_currency", "DEFAULT_CURRENCY", "currency of transactions without one", &c.DefaultCurrency},
+		{"migrate_on_startup", "MIGRATE_ON_STARTUP", "apply pending schema migrations before starting", &c.MigrateOnStartup},
+		{"postgres.dsn", "POSTGRES_DSN", "PostgreSQL connection string", &c.Postgres.DSN},
+		{"kafka.broker", "KAFKA_BROKER", "Kafka broker address", &c.Kafka.Broker},
+		{"kafka.topic", "KAFKA_TOPIC", "transactions topic", &c.Kafka.Topic},
+		{"kafka.group_id", "KAFKA_GROUP_ID", "consumer group of the consumer", &c.Kafka.GroupID},
+		{"kafka.dlq_topic", "KAFKA_DLQ_TOPIC", "dead-letter topic (default <topic>-dlq)", &c.Kafka.DLQTopic},
+		{"kafka.limits_topic", "KAFKA_LIMITS_TOPIC", "limit violations topic", &c.Kafka.LimitsTopic},
+		{"api.host", "API_HOST", "address the API listens on (all interfaces when empty)", &c.API.Host},
+		{"api.port", "API_PORT", "API port", &c.API.Port},
+		{"api.ingest_mode", "INGEST_MODE", "POST /transactions mode: direct or kafka", &c.API.IngestMode},
+		{"api.strict_query_params", "STRICT_QUERY_PARAMS", "reject unknown query parameters", &c.API.StrictQueryParams},
+		{"consumer.batch_size", "CONSUMER_BATCH_SIZE", "messages per batch; batching is enabled above 1", &c.Consumer.BatchSize},
+		{"consumer.batch_linger", "CONSUMER_BATCH_LINGER", "maximum wait for a batch to fill", &c.Consumer.BatchLinger},
+		{"consumer.workers", "CONSUMER_WORKERS", "worker count; concurrency is enabled above 1", &c.Consumer.Workers},
+		{"consumer.routing", "CONSUMER_ROUTING", "worker routing: partition or user", &c.Consumer.Routing},
+		{"consumer.retry_until_stopped", "CONSUMER_RETRY_UNTIL_STOPPED", "retry transient save errors until stopped instead of dead-lettering", &c.Consumer.RetryUntilStopped},
+		{"metrics.port", "METRICS_PORT", "port of the consumer's /metrics endpoint", &c.Metrics.Port},
+		{"auth.jwks_file", "AUTH_JWKS_FILE", "JWKS file; JWTs are rejected when empty", &c.Auth.JWKSFile},
+		{"auth.jwt_issuer", "AUTH_JWT_ISSUER", "expected JWT issuer", &c.Auth.JWTIssuer},
+		{"auth.jwt_audience", "AUTH_JWT_AUDIENCE", "expected JWT audience", &c.Auth.JWTAudience},
+		{"auth.rbac_config", "RBAC_CONFIG", "RBAC policy file; the built-in policy is used when empty", &c.Auth.RBACConfig},
+		{"tracing.service_name", "OTEL_SERVICE_NAME", "service name in traces", &c.Tracing.ServiceName},
+		{"tracing.endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTLP/HTTP collector endpoint; tracing is disabled when empty", &c.Tracing.Endpoint},
+		{"tracing.sample_ratio", "OTEL_TRACES_SAMPLER_ARG", "fraction of traces to sample, 0 to 1", &c.Tracing.SampleRatio},
+	}
+}
+
+// defaults возвращает конфигурацию со значениями по умолчанию.
+func defaults(service Service) *Config {
+	return &Config{
+		LogLevel: "info",
+		Kafka: KafkaConfig{
+			Topic:       "transactions",
+			GroupID:     "transaction-savers",
+			LimitsTopic: "limit-violations",
+		},
+		API: APIConfig{
+			Port:       8080,
+			IngestMode: IngestDirect,
+		},
+		Consumer: ConsumerConfig{
+			BatchSize: 1,
+			Workers:   1,
+			Routing:   RoutingPartition,
+		},
+		Metrics: MetricsConfig{Port: 9091},
+		Tracing: TracingConfig{
+			ServiceName: "casino-" + string(service),
+			SampleRatio: 1,
+		},
+		service: service,
+	}
+}
+
+// Load собирает конфигурацию сервиса. Источники применяются по возрастанию
+// приоритета: значения по умолчанию, YAML-файл (флаг -config или CONFIG_FILE),
+// переменные окружения, флаги из args. Пустая переменная окружения считается незаданной.
+// На -help возвращает flag.ErrHelp, напечатав справку в stderr.
+func Load(service Service, args []string) (*Config, error) {
+	return load(service, args, os.Stderr)
+}
+
+func load(service Service, args []string, output io.Writer) (*Config, error) {
+	cfg := defaults(service)
+	settings := cfg.settings()
+
+	// Флаги разбираются первыми, чтобы узнать путь к файлу, но применяются последними
+	fs := flag.NewFlagSet(string(service), flag.ContinueOnError)
+	fs.SetOutput(output)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration file (env CONFIG_FILE)")
+	flags := make(map[string]string)
+	for _, s := range settings {
+		name := s.flagName()
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		set := func(v string) error {
+			flags[name] = v
+			return nil
+		}
+		if _, ok := s.value.(*bool); ok {
+			fs.BoolFunc(name, usage, set)
+		} else {
+			fs.Func(name, usage, set)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	// Позиционные аргументы — команда migrate; сервисам они не нужны
+	if service != Migrate && fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	cfg.args = fs.Args()
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		if v := os.Getenv(s.env); v != "" {
+			if err := setValue(s.value, v); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if v, ok := flags[s.flagName()]; ok {
+			if err := setValue(s.value, v); err != nil {
+				return nil, fmt.Errorf("-%s: %w", s.flagName(), err)
+			}
+		}
+	}
+
+	// Пустые значения из файла, окружения или флагов заменяются производными по умолчанию
+	if cfg.Kafka.DLQTopic == "" {
+		cfg.Kafka.DLQTopic = cfg.Kafka.Topic + "-dlq"
+	}
+	if cfg.Tracing.ServiceName == "" {
+		cfg.Tracing.ServiceName = "casino-" + string(service)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile накладывает на конфигурацию значения из YAML-файла.
+// Неизвестный ключ — ошибка: опечатка не должна молча оставлять значение по умолчанию.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func setValue(value any, s string) error {
+	var err error
+	switch v := value.(type) {
+	case *string:
+		*v = s
+	case *int:
+		*v, err = strconv.Atoi(s)
+	case *bool:
+		*v, err = strconv.ParseBool(s)
+	case *float64:
+		*v, err = strconv.ParseFloat(s, 64)
+	case *time.Duration:
+		*v, err = time.ParseDuration(s)
+	default:
+		panic(fmt.Sprintf("config: unsupported setting type %T", value))
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q", s)
+	}
+	return nil
+}
+
+// Validate проверяет обязательные параметры и допустимость значений.
+// Возвращает все найденные ошибки сразу.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Postgres.DSN != "", "postgres.dsn (POSTGRES_DSN) is required")
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1,
+		"tracing.sample_ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+
+	switch c.service {
+	case API:
+		check(validPort(c.API.Port), "api.port must be between 1 and 65535, got %d", c.API.Port)
+		check(c.API.IngestMode == IngestDirect || c.API.IngestMode == IngestKafka,
+			"api.ingest_mode must be %q or %q, got %q", IngestDirect, IngestKafka, c.API.IngestMode)
+		if c.API.IngestMode == IngestKafka {
+			check(c.Kafka.Broker != "", "kafka.broker (KAFKA_BROKER) is required when api.ingest_mode is %q", IngestKafka)
+			check(c.Kafka.Topic != "", "kafka.topic must not be empty")
+		}
+	case Consumer:
+		check(c.Kafka.Broker != "", "kafka.broker (KAFKA_BROKER) is required")
+		check(c.Kafka.Topic != "", "kafka.topic must not be empty")
+		check(c.Kafka.GroupID != "", "kafka.group_id must not be empty")
+		check(c.Kafka.LimitsTopic != "", "kafka.limits_topic must not be empty")
+		check(c.Kafka.DLQTopic != c.Kafka.Topic, "kafka.dlq_topic must differ from kafka.topic")
+		check(validPort(c.Metrics.Port), "metrics.port must be between 1 and 65535, got %d", c.Metrics.Port)
+		check(c.Consumer.BatchSize >= 1, "consumer.batch_size must be at least 1, got %d", c.Consumer.BatchSize)
+		check(c.Consumer.BatchLinger >= 0, "consumer.batch_linger must not be negative, got %s", c.Consumer.BatchLinger)
+		check(c.Consumer.Workers >= 1, "consumer.workers must be at least 1, got %d", c.Consumer.Workers)
+		check(c.Consumer.Routing == RoutingPartition || c.Consumer.Routing == RoutingUser,
+			"consumer.routing must be %q or %q, got %q", RoutingPartition, RoutingUser, c.Consumer.Routing)
+	}
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// Level возвращает уровень логирования. Конфигурация из Load уже проверена,
+// поэтому некорректный уровень заменяется на info.
+func (c *Config) Level() slog.Level {
+	level, err := logging.ParseLevel(c.LogLevel)
+	if err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// LogValue выводит действующую конфигурацию в лог, сгруппированную как в YAML.
+// Значения секретов (postgres.dsn и т. п.) заменяются на logging.Redacted.
+func (c *Config) LogValue() slog.Value {
+	var attrs []slog.Attr
+	groups := make(map[string]int)
+	for _, s := range c.settings() {
+		group, name, nested := strings.Cut(s.key, ".")
+		if !nested {
+			attrs = append(attrs, settingAttr(group, s.value))
+			continue
+		}
+		i, ok := groups[group]
+		if !ok {
+			i = len(attrs)
+			groups[group] = i
+			attrs = append(attrs, slog.Group(group))
+		}
+		members := append(attrs[i].Value.Group(), settingAttr(name, s.value))
+		attrs[i] = slog.Attr{Key: group, Value: slog.GroupValue(members...)}
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func settingAttr(key string, value any) slog.Attr {
+	if logging.IsSensitive(key) {
+		return slog.String(key, logging.Redacted)
+	}
+	switch v := value.(type) {
+	case *string:
+		return slog.String(key, *v)
+	case *int:
+		return slog.Int(key, *v)
+	case *bool:
+		return slog.Bool(key, *v)
+	case *float64:
+		return slog.Float64(key, *v)
+	case *time.Duration:
+		return slog.String(key, v.String())
+	}
+	return slog.Any(key, value)
+}
+
+// Args возвращает аргументы командной строки, оставшиеся после флагов.
+func (c *Config) Args() []string {
+	return c.args
+}
+
+// APIAddr возвращает адрес, на котором слушает API.
+func (c *Config) APIAddr() string {
+	return net.JoinHostPort(c.API.Host, strconv.Itoa(c.API.Port))
+}
+
+// MetricsAddr возвращает адрес HTTP-сервера метрик consumer'а.
+func (c *Config) MetricsAddr() string {
+	return net.JoinHostPort("", strconv.Itoa(c.Metrics.Port))
+}
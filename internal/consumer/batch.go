@@ -0,0 +1,155 @@
+package consumer
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/tracing"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultBatchLinger используется, если в WithBatching передано неположительное время ожидания.
+const DefaultBatchLinger = 100 * time.Millisecond
+
+// WithBatching включает пакетную обработку: сообщения накапливаются, пока их не станет
+// maxSize или с момента получения первого не пройдет maxLinger, затем сохраняются
+// одним вызовом CreateTransactions, а offset'ы коммитятся для всей пачки после успеха.
+func WithBatching(maxSize int, maxLinger time.Duration) Option {
+	return func(h *Handler) {
+		h.batchSize = maxSize
+		h.batchLinger = maxLinger
+		if h.batchLinger <= 0 {
+			h.batchLinger = DefaultBatchLinger
+		}
+	}
+}
+
+func (h *Handler) processBatches(ctx context.Context, src source) {
+	for {
+		batch, err := h.fetchBatch(ctx, src)
+		if len(batch) > 0 && ctx.Err() == nil {
+			h.processBatch(ctx, src, batch)
+		}
+		if err != nil {
+			if isStopError(err) {
+				h.logger.InfoContext(ctx, "context cancelled or reader closed, stopping message processing")
+				return
+			}
+			h.logger.ErrorContext(ctx, "could not fetch message", logging.Err(err))
+		}
+	}
+}
+
+// fetchBatch ждет первое сообщение без ограничения по времени, а следующие —
+// не дольше batchLinger. Вместе с ошибкой может вернуть уже прочитанную часть пачки.
+func (h *Handler) fetchBatch(ctx context.Context, src source) ([]kafka.Message, error) {
+	first, err := src.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batch := make([]kafka.Message, 1, h.batchSize)
+	batch[0] = first
+
+	lingerCtx, cancel := context.WithTimeout(ctx, h.batchLinger)
+	defer cancel()
+
+	for len(batch) < h.batchSize {
+		msg, err := src.fetch(lingerCtx)
+		if err != nil {
+			if lingerCtx.Err() != nil && ctx.Err() == nil {
+				// Время ожидания пачки истекло.
+				break
+			}
+			return batch, err
+		}
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
+
+// processBatch обрабатывает пачку и коммитит ее offset'ы. Span пачки связан
+// со span'ами производителей всех ее сообщений.
+func (h *Handler) processBatch(ctx context.Context, src source, batch []kafka.Message) {
+	links := make([]trace.Link, 0, len(batch))
+	for _, msg := range batch {
+		if sc := trace.SpanContextFromContext(tracing.Extract(ctx, msg)); sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc, Attributes: tracing.MessageAttributes(msg)})
+		}
+	}
+	ctx, span := h.tracer.Start(ctx, "process "+batch[0].Topic+" batch",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("messaging.batch.message_count", len(batch))))
+	defer span.End()
+
+	if h.handleBatch(ctx, batch) {
+		h.commitMessages(ctx, src, batch...)
+	}
+}
+
+// handleBatch обрабатывает пачку и возвращает true, если offset'ы всей пачки можно коммитить.
+func (h *Handler) handleBatch(ctx context.Context, msgs []kafka.Message) bool {
+	commit := true
+	txs := make([]models.Transaction, 0, len(msgs))
+	valid := make([]kafka.Message, 0, len(msgs))
+
+	for _, msg := range msgs {
+		msgCtx := logging.WithMessage(ctx, msg)
+		tx, reason, err := h.parseMessage(msgCtx, msg)
+		if err != nil {
+			commit = h.sendToDeadLetter(msgCtx, msg, reason, err, 1) && commit
+			continue
+		}
+		txs = append(txs, tx)
+		valid = append(valid, msg)
+	}
+	if len(txs) == 0 {
+		return commit
+	}
+
+	created, err := h.createTransactions(ctx, txs)
+	if err == nil {
+		h.metrics.Stored(len(created), len(txs)-len(created))
+		h.logger.InfoContext(ctx, "batch saved", slog.Int("transactions", len(txs)), slog.Int("created", len(created)))
+		return commit
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// Пачка отклонена целиком (например, одна из ставок превышает баланс или лимит):
+	// обрабатываем сообщения по одному, чтобы отделить проблемные.
+	h.logger.WarnContext(ctx, "could not save batch, falling back to single saves", slog.Int("transactions", len(txs)), logging.Err(err))
+	for i, msg := range valid {
+		commit = h.saveTransaction(logging.WithMessage(ctx, msg), msg, txs[i]) && commit
+	}
+	return commit
+}
+
+// createTransactions сохраняет пачку с повторами при временных ошибках, но не больше
+// MaxAttempts раз: после этого пачка сохраняется по одной транзакции.
+func (h *Handler) createTransactions(ctx context.Context, txs []models.Transaction) ([]string, error) {
+	ctx, span := h.tracer.Start(ctx, "SaveTransactions", trace.WithAttributes(attribute.Int("transaction.count", len(txs))))
+	defer span.End()
+
+	var created []string
+	attempts, err := h.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = h.repo.CreateTransactions(ctx, txs)
+		return err
+	}, repository.IsTransient)
+	span.SetAttributes(attribute.Int("attempts", attempts), attribute.Int("transaction.created", len(created)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return created, err
+}
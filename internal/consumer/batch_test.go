@@ -0,0 +1,161 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMessage(t *testing.T, offset int64, tx models.Transaction) kafka.Message {
+	value, err := json.Marshal(tx)
+	require.NoError(t, err)
+	return kafka.Message{Offset: offset, Value: value}
+}
+
+// waitForLinger имитирует FetchMessage, который не дождался сообщения до истечения контекста.
+func waitForLinger(args mock.Arguments) {
+	<-args.Get(0).(context.Context).Done()
+}
+
+func TestConsumerHandler_Batching(t *testing.T) {
+	//  Тест 1: Полная пачка сохраняется одним вызовом
+	t.Run("should save full batch at once and commit all offsets", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		msgs := []kafka.Message{
+			newTestMessage(t, 1, models.Transaction{TransactionID: "batch-001", UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"}),
+			newTestMessage(t, 2, models.Transaction{TransactionID: "batch-002", UserID: "u2", TransactionType: "bet", Amount: 200, Currency: "USD"}),
+			newTestMessage(t, 3, models.Transaction{TransactionID: "batch-003", UserID: "u1", TransactionType: "bet", Amount: 50, Currency: "USD"}),
+		}
+		for _, msg := range msgs {
+			mockReader.On("FetchMessage", mock.Anything).Return(msg, nil).Once()
+		}
+		mockReader.On("CommitMessages", mock.Anything, msgs).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+
+		mockRepo.On("CreateTransactions", mock.Anything, mock.MatchedBy(func(txs []models.Transaction) bool {
+			return len(txs) == 3 && txs[0].TransactionID == "batch-001" && txs[2].TransactionID == "batch-003"
+		})).Return([]string{"batch-001", "batch-002", "batch-003"}, nil).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithBatching(3, time.Second))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything, mock.Anything)
+		mockReader.AssertExpectations(t)
+	})
+
+	//  Тест 2: Неполная пачка сохраняется по истечении времени ожидания
+	t.Run("should flush partial batch after linger time", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		msgs := []kafka.Message{
+			newTestMessage(t, 1, models.Transaction{TransactionID: "batch-004", UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"}),
+			newTestMessage(t, 2, models.Transaction{TransactionID: "batch-005", UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"}),
+		}
+		for _, msg := range msgs {
+			mockReader.On("FetchMessage", mock.Anything).Return(msg, nil).Once()
+		}
+		mockReader.On("FetchMessage", mock.Anything).Run(waitForLinger).Return(kafka.Message{}, context.DeadlineExceeded).Once()
+		mockReader.On("CommitMessages", mock.Anything, msgs).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+
+		mockRepo.On("CreateTransactions", mock.Anything, mock.AnythingOfType("[]models.Transaction")).Return([]string(nil), nil).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithBatching(100, 10*time.Millisecond))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+
+		mockRepo.AssertExpectations(t)
+		mockReader.AssertExpectations(t)
+	})
+
+	//  Тест 3: Невалидные сообщения уходят в DLQ, остальные сохраняются
+	t.Run("should dead-letter invalid messages and save the rest", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		dlq := &fakeDeadLetterWriter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		msgs := []kafka.Message{
+			newTestMessage(t, 1, models.Transaction{TransactionID: "batch-006", UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"}),
+			{Offset: 2, Value: []byte("not a json")},
+		}
+		for _, msg := range msgs {
+			mockReader.On("FetchMessage", mock.Anything).Return(msg, nil).Once()
+		}
+		mockReader.On("CommitMessages", mock.Anything, msgs).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+
+		mockRepo.On("CreateTransactions", mock.Anything, mock.MatchedBy(func(txs []models.Transaction) bool {
+			return len(txs) == 1 && txs[0].TransactionID == "batch-006"
+		})).Return([]string{"batch-006"}, nil).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithBatching(2, time.Second), WithDeadLetterWriter(dlq))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockRepo.AssertExpectations(t)
+		mockReader.AssertExpectations(t)
+		published := dlq.Messages()
+		require.Len(t, published, 1)
+		assert.Equal(t, ReasonUnmarshal, headerValue(published[0], HeaderDeadLetterReason))
+	})
+
+	//  Тест 4: Отклоненная пачка обрабатывается по одному сообщению
+	t.Run("should fall back to single saves when batch is rejected", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		dlq := &fakeDeadLetterWriter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		okTx := models.Transaction{TransactionID: "batch-007", UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"}
+		badTx := models.Transaction{TransactionID: "batch-008", UserID: "u2", TransactionType: "bet", Amount: 1000, Currency: "USD"}
+		msgs := []kafka.Message{newTestMessage(t, 1, okTx), newTestMessage(t, 2, badTx)}
+		for _, msg := range msgs {
+			mockReader.On("FetchMessage", mock.Anything).Return(msg, nil).Once()
+		}
+		mockReader.On("CommitMessages", mock.Anything, msgs).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+
+		mockRepo.On("CreateTransactions", mock.Anything, mock.AnythingOfType("[]models.Transaction")).
+			Return([]string(nil), repository.ErrInsufficientFunds).Once()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.MatchedBy(func(tx models.Transaction) bool { return tx.TransactionID == "batch-007" })).
+			Return(true, nil).Once()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.MatchedBy(func(tx models.Transaction) bool { return tx.TransactionID == "batch-008" })).
+			Return(false, repository.ErrInsufficientFunds).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithBatching(2, time.Second), WithDeadLetterWriter(dlq))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockRepo.AssertExpectations(t)
+		mockReader.AssertExpectations(t)
+		published := dlq.Messages()
+		require.Len(t, published, 1)
+		assert.Equal(t, msgs[1].Value, published[0].Value)
+		assert.Equal(t, ReasonRejected, headerValue(published[0], HeaderDeadLetterReason))
+	})
+}
@@ -0,0 +1,162 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"sync"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Routing определяет, как сообщения распределяются между воркерами.
+type Routing int
+
+const (
+	// RouteByPartition отдает все сообщения одной партиции одному воркеру.
+	// Порядок по пользователю сохраняется, если producer использует user_id как ключ.
+	RouteByPartition Routing = iota
+	// RouteByUser распределяет сообщения по хешу user_id, поэтому транзакции
+	// одного пользователя обрабатываются последовательно независимо от партиции.
+	RouteByUser
+)
+
+// maxBufferedMessages — сколько прочитанных сообщений может ждать своих воркеров.
+// Reader не умеет приостанавливать отдельные партиции, поэтому сообщения занятого
+// воркера копятся в памяти, а чтение останавливается только при заполнении этого лимита.
+const maxBufferedMessages = 10000
+
+// WithWorkers включает параллельную обработку в workers воркерах.
+// Сообщения одного ключа маршрутизации (партиции или пользователя) всегда
+// попадают к одному воркеру и обрабатываются по порядку. Offset'ы коммитятся
+// по каждой партиции отдельно, поэтому медленная партиция не задерживает коммиты быстрых.
+// Занятый воркер не останавливает чтение: его сообщения ждут в очереди в памяти,
+// пока всего в очередях меньше maxBufferedMessages сообщений.
+func WithWorkers(workers int, routing Routing) Option {
+	return func(h *Handler) {
+		h.workers = workers
+		h.routing = routing
+	}
+}
+
+func (h *Handler) processConcurrently(ctx context.Context) {
+	tracker := newOffsetTracker(h.reader)
+	// Каждое сообщение занимает место в buffered, пока воркер его не заберет.
+	buffered := make(chan struct{}, maxBufferedMessages)
+	inboxes := make([]chan kafka.Message, h.workers)
+
+	var wg sync.WaitGroup
+	for i := range inboxes {
+		inbox := make(chan kafka.Message)
+		queue := make(chan kafka.Message)
+		inboxes[i] = inbox
+		wg.Go(func() {
+			forward(ctx, inbox, queue)
+		})
+		wg.Go(func() {
+			h.run(ctx, source{fetch: queueFetcher(queue, buffered), commit: tracker.commit})
+		})
+	}
+
+	h.dispatch(ctx, inboxes, tracker, buffered)
+
+	for _, inbox := range inboxes {
+		close(inbox)
+	}
+	wg.Wait()
+}
+
+// dispatch читает сообщения из reader и раскладывает их по очередям воркеров, пока чтение не остановится.
+// Очередь воркера принимает сообщения всегда, поэтому dispatch ждет только свободного места в buffered.
+func (h *Handler) dispatch(ctx context.Context, inboxes []chan kafka.Message, tracker *offsetTracker, buffered chan<- struct{}) {
+	for {
+		msg, err := h.fetchMessage(ctx)
+		if err != nil {
+			if isStopError(err) {
+				h.logger.InfoContext(ctx, "context cancelled or reader closed, stopping message dispatching")
+				return
+			}
+			h.logger.ErrorContext(ctx, "could not fetch message", logging.Err(err))
+			continue
+		}
+
+		// Offset регистрируется до передачи воркеру, чтобы коммиты шли строго по порядку.
+		tracker.track(msg)
+
+		select {
+		case buffered <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case inboxes[h.route(msg, len(inboxes))] <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// forward передает сообщения из in в out по порядку, накапливая их в памяти,
+// пока воркер занят. После закрытия in оставшиеся сообщения дочитываются, затем out закрывается.
+func forward(ctx context.Context, in <-chan kafka.Message, out chan<- kafka.Message) {
+	defer close(out)
+
+	var backlog []kafka.Message
+	for in != nil || len(backlog) > 0 {
+		// Пока очередь пуста, отправка в nil-канал никогда не выбирается.
+		var send chan<- kafka.Message
+		var next kafka.Message
+		if len(backlog) > 0 {
+			send, next = out, backlog[0]
+		}
+
+		select {
+		case msg, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			backlog = append(backlog, msg)
+		case send <- next:
+			backlog = backlog[1:]
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// route возвращает номер воркера для сообщения.
+func (h *Handler) route(msg kafka.Message, workers int) int {
+	if h.routing == RouteByUser {
+		var key struct {
+			UserID string `json:"user_id"`
+		}
+		// Невалидное сообщение маршрутизируется по партиции и уйдет в DLQ у воркера.
+		if err := json.Unmarshal(msg.Value, &key); err == nil && key.UserID != "" {
+			hash := fnv.New32a()
+			_, _ = hash.Write([]byte(key.UserID))
+			return int(hash.Sum32() % uint32(workers))
+		}
+	}
+	return msg.Partition % workers
+}
+
+// queueFetcher возвращает функцию чтения из очереди воркера, освобождающую место в buffered.
+// Закрытая очередь означает остановку и возвращает io.EOF.
+func queueFetcher(queue <-chan kafka.Message, buffered <-chan struct{}) func(ctx context.Context) (kafka.Message, error) {
+	return func(ctx context.Context) (kafka.Message, error) {
+		select {
+		case msg, ok := <-queue:
+			if !ok {
+				return kafka.Message{}, io.EOF
+			}
+			<-buffered
+			return msg, nil
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		}
+	}
+}
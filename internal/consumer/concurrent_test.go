@@ -0,0 +1,203 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumerHandler_Route(t *testing.T) {
+	t.Run("should route by partition", func(t *testing.T) {
+		handler := NewHandler(nil, nil, WithWorkers(4, RouteByPartition))
+
+		assert.Equal(t, 1, handler.route(kafka.Message{Partition: 5}, 4))
+		assert.Equal(t, 3, handler.route(kafka.Message{Partition: 3}, 4))
+	})
+
+	t.Run("should route the same user to the same worker across partitions", func(t *testing.T) {
+		handler := NewHandler(nil, nil, WithWorkers(8, RouteByUser))
+
+		first := newTestMessage(t, 1, models.Transaction{UserID: "user-42", TransactionType: "bet", Amount: 1, Currency: "USD"})
+		first.Partition = 0
+		second := newTestMessage(t, 2, models.Transaction{UserID: "user-42", TransactionType: "win", Amount: 1, Currency: "USD"})
+		second.Partition = 5
+
+		assert.Equal(t, handler.route(first, 8), handler.route(second, 8))
+	})
+
+	t.Run("should fall back to partition for unparseable message", func(t *testing.T) {
+		handler := NewHandler(nil, nil, WithWorkers(4, RouteByUser))
+
+		assert.Equal(t, 2, handler.route(kafka.Message{Partition: 6, Value: []byte("not a json")}, 4))
+	})
+}
+
+func TestConsumerHandler_ProcessConcurrently(t *testing.T) {
+	t.Run("should keep per-partition order and commit each partition", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var msgs []kafka.Message
+		for i, user := range []string{"u1", "u2", "u1", "u2"} {
+			msg := newTestMessage(t, int64(i/2), models.Transaction{
+				TransactionID:   "concurrent-" + string(rune('a'+i)),
+				UserID:          user,
+				TransactionType: "win",
+				Amount:          100,
+				Currency:        "USD",
+			})
+			msg.Topic = "transactions"
+			msg.Partition = i % 2
+			msgs = append(msgs, msg)
+			mockReader.On("FetchMessage", mock.Anything).Return(msg, nil).Once()
+		}
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockReader.On("CommitMessages", mock.Anything, mock.Anything).Return(nil)
+
+		var mu sync.Mutex
+		saved := make(map[string][]string)
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).
+			Run(func(args mock.Arguments) {
+				tx := args.Get(1).(models.Transaction)
+				mu.Lock()
+				saved[tx.UserID] = append(saved[tx.UserID], tx.TransactionID)
+				mu.Unlock()
+			}).
+			Return(true, nil)
+
+		handler := NewHandler(mockReader, mockRepo, WithWorkers(2, RouteByPartition))
+
+		done := make(chan struct{})
+		go func() {
+			handler.ProcessMessages(ctx)
+			close(done)
+		}()
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+		<-done
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []string{"concurrent-a", "concurrent-c"}, saved["u1"])
+		assert.Equal(t, []string{"concurrent-b", "concurrent-d"}, saved["u2"])
+
+		// Последний offset каждой партиции закоммичен
+		mockReader.AssertCalled(t, "CommitMessages", mock.Anything, []kafka.Message{msgs[2]})
+		mockReader.AssertCalled(t, "CommitMessages", mock.Anything, []kafka.Message{msgs[3]})
+	})
+	t.Run("should commit past a message whose dead-letter write failed", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		dlq := &fakeDeadLetterWriter{err: errors.New("broker unavailable"), failures: 3}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var msgs []kafka.Message
+		for offset := range int64(3) {
+			msg := newTestMessage(t, offset, models.Transaction{
+				TransactionID:   fmt.Sprintf("stuck-%d", offset),
+				UserID:          "u1",
+				TransactionType: "win",
+				Amount:          100,
+				Currency:        "USD",
+			})
+			msg.Topic = "transactions"
+			if offset == 1 {
+				msg.Value = []byte("not a json")
+			}
+			msgs = append(msgs, msg)
+			mockReader.On("FetchMessage", mock.Anything).Return(msg, nil).Once()
+		}
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockReader.On("CommitMessages", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).Return(true, nil)
+
+		handler := NewHandler(mockReader, mockRepo,
+			WithWorkers(2, RouteByPartition),
+			WithDeadLetterWriter(dlq),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+
+		done := make(chan struct{})
+		go func() {
+			handler.ProcessMessages(ctx)
+			close(done)
+		}()
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+		<-done
+
+		// Запись в DLQ повторяется до успеха, и партиция коммитится до последнего offset'а
+		require.Len(t, dlq.Messages(), 1)
+		mockReader.AssertCalled(t, "CommitMessages", mock.Anything, []kafka.Message{msgs[2]})
+	})
+	t.Run("should process other partitions while one worker is blocked", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Сообщений партиции 0 больше, чем помещалось в прежнюю очередь воркера
+		for offset := range int64(100) {
+			msg := newTestMessage(t, offset, models.Transaction{
+				TransactionID:   fmt.Sprintf("blocked-%d", offset),
+				UserID:          "u1",
+				TransactionType: "win",
+				Amount:          100,
+				Currency:        "USD",
+			})
+			msg.Topic = "transactions"
+			mockReader.On("FetchMessage", mock.Anything).Return(msg, nil).Once()
+		}
+		free := newTestMessage(t, 0, models.Transaction{
+			TransactionID:   "free-0",
+			UserID:          "u2",
+			TransactionType: "win",
+			Amount:          100,
+			Currency:        "USD",
+		})
+		free.Topic = "transactions"
+		free.Partition = 1
+		mockReader.On("FetchMessage", mock.Anything).Return(free, nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).
+			Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+			Return(kafka.Message{}, context.Canceled).Maybe()
+		committed := make(chan struct{})
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{free}).
+			Run(func(mock.Arguments) { close(committed) }).
+			Return(nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, mock.Anything).Return(nil)
+
+		release := make(chan struct{})
+		mockRepo.On("CreateTransaction", mock.Anything, withID("blocked-0")).
+			Run(func(mock.Arguments) { <-release }).
+			Return(true, nil).Once()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).Return(true, nil)
+
+		handler := NewHandler(mockReader, mockRepo, WithWorkers(2, RouteByPartition))
+
+		done := make(chan struct{})
+		go func() {
+			handler.ProcessMessages(ctx)
+			close(done)
+		}()
+
+		select {
+		case <-committed:
+		case <-time.After(time.Second):
+			t.Error("partition 1 is not committed while partition 0 is blocked")
+		}
+
+		close(release)
+		cancel()
+		<-done
+	})
+}
@@ -0,0 +1,62 @@
+package consumer
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// DeadLetterWriter публикует сообщения, которые не удалось обработать.
+// *kafka.Writer удовлетворяет этому интерфейсу.
+type DeadLetterWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Причины отправки сообщения в dead-letter topic (заголовок HeaderDeadLetterReason).
+const (
+	ReasonUnmarshal  = "unmarshal_error"
+	ReasonValidation = "validation_error"
+	ReasonRejected   = "rejected"
+	// ReasonLimitExceeded — транзакция нарушает лимит ответственной игры или самоисключение.
+	ReasonLimitExceeded = "limit_exceeded"
+	// ReasonSaveFailed — постоянная ошибка сохранения, например нарушение CHECK-ограничения.
+	ReasonSaveFailed = "save_failed"
+	// ReasonRetriesExhausted — временная ошибка сохранения не прошла за все попытки.
+	ReasonRetriesExhausted = "retries_exhausted"
+)
+
+// Заголовки, которые добавляются к исходному сообщению при отправке в dead-letter topic.
+const (
+	HeaderDeadLetterReason    = "dlq-reason"
+	HeaderDeadLetterError     = "dlq-error"
+	HeaderDeadLetterTopic     = "dlq-source-topic"
+	HeaderDeadLetterPartition = "dlq-source-partition"
+	HeaderDeadLetterOffset    = "dlq-source-offset"
+	HeaderDeadLetterAttempts  = "dlq-attempts"
+	HeaderDeadLetterFailedAt  = "dlq-failed-at"
+)
+
+// newDeadLetterMessage копирует ключ, значение и заголовки исходного сообщения
+// и добавляет заголовки с причиной ошибки и его положением в исходном топике.
+// Topic не заполняется: его задает DeadLetterWriter.
+func newDeadLetterMessage(msg kafka.Message, reason string, cause error, attempts int, failedAt time.Time) kafka.Message {
+	headers := make([]kafka.Header, 0, len(msg.Headers)+7)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderDeadLetterReason, Value: []byte(reason)},
+		kafka.Header{Key: HeaderDeadLetterError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderDeadLetterTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderDeadLetterPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderDeadLetterOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: HeaderDeadLetterAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderDeadLetterFailedAt, Value: []byte(failedAt.UTC().Format(time.RFC3339Nano))},
+	)
+
+	return kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+}
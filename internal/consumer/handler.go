@@ -2,19 +2,24 @@ package consumer
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"time"
 
+	"github.com/OlgaPie/casino-transaction-system/internal/limits"
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/metrics"
 	"github.com/OlgaPie/casino-transaction-system/internal/models"
 	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/tracing"
+	"github.com/OlgaPie/casino-transaction-system/internal/validation"
 
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // MessageReader определяет минимальный интерфейс, необходимый для чтения сообщений.
@@ -24,79 +29,323 @@ type MessageReader interface {
 }
 
 type Handler struct {
-	reader MessageReader
-	repo   repository.TransactionRepository
+	reader     MessageReader
+	repo       repository.TransactionRepository
+	deadLetter DeadLetterWriter
+	retry      RetryPolicy
+	// retryUntilStopped снимает ограничение MaxAttempts для сохранения, см. WithRetryUntilStopped.
+	retryUntilStopped bool
+	// batchSize > 1 включает пакетную обработку, см. WithBatching.
+	batchSize   int
+	batchLinger time.Duration
+	// workers > 1 включает параллельную обработку, см. WithWorkers.
+	workers int
+	routing Routing
+	// defaultCurrency подставляется в сообщения без currency, см. WithDefaultCurrency.
+	defaultCurrency string
+	// limits сохраняет нарушения лимитов ответственной игры, см. WithLimits.
+	limits      limits.ViolationRecorder
+	limitEvents EventWriter
+	// metrics считает обработанные сообщения, см. WithMetrics; nil отключает учет.
+	metrics *metrics.Consumer
+	// tracer создает span'ы обработки сообщений, см. WithTracerProvider.
+	tracer trace.Tracer
+	logger *slog.Logger
 }
 
-func NewHandler(reader MessageReader, repo repository.TransactionRepository) *Handler {
-	return &Handler{reader: reader, repo: repo}
+// Option настраивает необязательные зависимости Handler.
+type Option func(*Handler)
+
+// WithDeadLetterWriter включает отправку необрабатываемых сообщений в dead-letter topic.
+// Без него такие сообщения только логируются и коммитятся.
+func WithDeadLetterWriter(w DeadLetterWriter) Option {
+	return func(h *Handler) {
+		h.deadLetter = w
+	}
+}
+
+// WithRetryPolicy задает политику повторов при временных ошибках сохранения.
+// Запись в Kafka повторяется с теми же задержками, пока consumer не остановлен.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(h *Handler) {
+		h.retry = p
+	}
+}
+
+// WithRetryUntilStopped повторяет сохранение транзакции при временных ошибках,
+// пока consumer не остановлен, вместо отправки в dead-letter topic после MaxAttempts.
+// Пока база недоступна, обработка партиции стоит, зато ни одна транзакция не теряется в DLQ.
+func WithRetryUntilStopped() Option {
+	return func(h *Handler) {
+		h.retryUntilStopped = true
+	}
+}
+
+// WithDefaultCurrency задает валюту для сообщений без поля currency.
+// Без него такие сообщения не проходят валидацию.
+func WithDefaultCurrency(code string) Option {
+	return func(h *Handler) {
+		h.defaultCurrency = code
+	}
+}
+
+// WithMetrics включает учет полученных, сохраненных, повторных и отброшенных сообщений.
+func WithMetrics(m *metrics.Consumer) Option {
+	return func(h *Handler) {
+		h.metrics = m
+	}
+}
+
+// WithTracerProvider включает трассировку: span'ы получения, проверки, сохранения
+// и коммита сообщения продолжают трассировку из его заголовков traceparent/tracestate.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(h *Handler) {
+		h.tracer = tracing.Tracer(tp)
+	}
+}
+
+// WithLogger задает логгер. Записи о сообщении получают его topic, partition и offset,
+// а после разбора — user_id и transaction_id. Без него Handler ничего не логирует.
+func WithLogger(logger *slog.Logger) Option {
+	return func(h *Handler) {
+		h.logger = logging.OrDiscard(logger)
+	}
+}
+
+func NewHandler(reader MessageReader, repo repository.TransactionRepository, opts ...Option) *Handler {
+	h := &Handler{reader: reader, repo: repo, retry: DefaultRetryPolicy, tracer: tracing.Tracer(nil), logger: logging.Discard()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *Handler) ProcessMessages(ctx context.Context) {
+	if h.workers > 1 {
+		h.processConcurrently(ctx)
+		return
+	}
+	h.run(ctx, source{fetch: h.fetchMessage, commit: h.reader.CommitMessages})
+}
+
+// fetchMessage читает сообщение из reader и учитывает его в метриках и трассировке.
+// Span FetchMessage создается после чтения, когда известен контекст трассировки сообщения,
+// но начинается в момент вызова.
+func (h *Handler) fetchMessage(ctx context.Context) (kafka.Message, error) {
+	start := time.Now()
+	msg, err := h.reader.FetchMessage(ctx)
+	if err != nil {
+		return msg, err
+	}
+	h.metrics.Fetched(msg.Topic, msg.Partition, msg.Offset, msg.HighWaterMark)
+
+	_, span := h.tracer.Start(tracing.Extract(ctx, msg), "FetchMessage",
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(tracing.MessageAttributes(msg)...))
+	span.End()
+	return msg, nil
+}
+
+// commitMessages подтверждает обработку msgs; ошибка только логируется.
+func (h *Handler) commitMessages(ctx context.Context, src source, msgs ...kafka.Message) {
+	ctx, span := h.tracer.Start(ctx, "CommitMessages", trace.WithAttributes(attribute.Int("messaging.batch.message_count", len(msgs))))
+	defer span.End()
+
+	if err := src.commit(ctx, msgs...); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.logger.ErrorContext(ctx, "failed to commit offsets", slog.Int("messages", len(msgs)), logging.Err(err))
+	}
+}
+
+// source — откуда берутся сообщения и как подтверждается их обработка.
+// При последовательной обработке это сам reader, при параллельной — очередь воркера.
+type source struct {
+	fetch  func(ctx context.Context) (kafka.Message, error)
+	commit func(ctx context.Context, msgs ...kafka.Message) error
+}
+
+func (h *Handler) run(ctx context.Context, src source) {
+	if h.batchSize > 1 {
+		h.processBatches(ctx, src)
+		return
+	}
+
 	for {
-		// FetchMessage получает сообщение без автоматического коммита offset
-		msg, err := h.reader.FetchMessage(ctx)
+		// fetch получает сообщение без автоматического коммита offset
+		msg, err := src.fetch(ctx)
 		if err != nil {
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || err == io.EOF {
-				log.Println("Context cancelled or reader closed, stopping message processing.")
+			if isStopError(err) {
+				h.logger.InfoContext(ctx, "context cancelled or reader closed, stopping message processing")
 				return
 			}
-			log.Printf("could not fetch message: %v", err)
+			h.logger.ErrorContext(ctx, "could not fetch message", logging.Err(err))
 			continue
 		}
 
-		var tx models.Transaction
-		if err := json.Unmarshal(msg.Value, &tx); err != nil {
-			log.Printf("could not unmarshal message: %v. Value: %s", err, string(msg.Value))
-			if err := h.reader.CommitMessages(ctx, msg); err != nil {
-				log.Printf("failed to commit invalid message: %v", err)
-			}
-			continue
+		msgCtx, span := h.tracer.Start(logging.WithMessage(tracing.Extract(ctx, msg), msg), "process "+msg.Topic,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(tracing.MessageAttributes(msg)...))
+		if h.handleMessage(msgCtx, msg) {
+			h.commitMessages(msgCtx, src, msg)
 		}
+		span.End()
+	}
+}
 
-		// Валидация данных.
-		if tx.TransactionType != models.TransactionTypeBet && tx.TransactionType != models.TransactionTypeWin {
-			log.Printf("invalid transaction_type: %s for user_id: %s", tx.TransactionType, tx.UserID)
-			if err := h.reader.CommitMessages(ctx, msg); err != nil {
-				log.Printf("failed to commit invalid message: %v", err)
-			}
-			continue
-		}
-		if tx.Amount <= 0 {
-			log.Printf("invalid amount: %d for user_id: %s", tx.Amount, tx.UserID)
-			if err := h.reader.CommitMessages(ctx, msg); err != nil {
-				log.Printf("failed to commit invalid message: %v", err)
+// handleMessage обрабатывает одно сообщение и возвращает true, если его offset можно коммитить.
+func (h *Handler) handleMessage(ctx context.Context, msg kafka.Message) bool {
+	tx, reason, err := h.parseMessage(ctx, msg)
+	if err != nil {
+		return h.sendToDeadLetter(ctx, msg, reason, err, 1)
+	}
+	return h.saveTransaction(ctx, msg, tx)
+}
+
+// saveTransaction сохраняет транзакцию из сообщения msg с повторами при временных ошибках
+// и возвращает true, если offset сообщения можно коммитить.
+func (h *Handler) saveTransaction(ctx context.Context, msg kafka.Message, tx models.Transaction) bool {
+	ctx = withTransaction(ctx, tx)
+	ctx, span := h.tracer.Start(ctx, "SaveTransaction", trace.WithAttributes(attribute.String("transaction.id", tx.TransactionID)))
+	defer span.End()
+
+	var created bool
+	save := func(ctx context.Context) error {
+		var err error
+		created, err = h.repo.CreateTransaction(ctx, tx)
+		return err
+	}
+	var attempts int
+	var err error
+	if h.retryUntilStopped {
+		// Без ограничения попыток каждая неудача логируется, иначе остановка партиции не видна.
+		attempts, err = h.retry.DoUntilCancelled(ctx, func(ctx context.Context) error {
+			err := save(ctx)
+			if repository.IsTransient(err) && ctx.Err() == nil {
+				h.logger.WarnContext(ctx, "could not save transaction, retrying", logging.Err(err))
 			}
-			continue
-		}
+			return err
+		}, repository.IsTransient)
+	} else {
+		attempts, err = h.retry.Do(ctx, save, repository.IsTransient)
+	}
+	span.SetAttributes(attribute.Int("attempts", attempts), attribute.Bool("transaction.created", created))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return h.handleSaveError(ctx, msg, tx, err, attempts)
+	}
 
-		// Устанавливаем время транзакции, если его нет в сообщении
-		if tx.Timestamp.IsZero() {
-			tx.Timestamp = time.Now()
-		}
+	if !created {
+		h.metrics.Stored(0, 1)
+		h.logger.InfoContext(ctx, "transaction was already saved")
+		return true
+	}
+	h.metrics.Stored(1, 0)
+	h.logger.InfoContext(ctx, "transaction saved", slog.Int64("amount", tx.Amount), slog.String("currency", tx.Currency))
+	return true
+}
 
-		if tx.TransactionID == "" {
-			tx.TransactionID = generateTransactionID(tx)
-			log.Printf("generated transaction_id: %s for user_id: %s", tx.TransactionID, tx.UserID)
-		}
-		if err := h.repo.SaveTransaction(ctx, tx); err != nil {
-			log.Printf("could not save transaction for user %s: %v", tx.UserID, err)
-			// В реальном проекте здесь может быть логика повторных попыток или
-			// отправка сообщения в "очередь мертвых писем" (Dead Letter Queue).
-			continue
-		}
+// handleSaveError обрабатывает ошибку проверки или сохранения транзакции tx
+// и возвращает true, если offset сообщения msg можно коммитить.
+func (h *Handler) handleSaveError(ctx context.Context, msg kafka.Message, tx models.Transaction, err error, attempts int) bool {
+	var violation *limits.ViolationError
+	switch {
+	case ctx.Err() != nil:
+		// Остановка consumer'а: offset не коммитим, сообщение будет прочитано повторно.
+		return false
+	case errors.As(err, &violation):
+		return h.handleViolation(ctx, msg, violation, attempts)
+	case repository.IsRejected(err):
+		// Повторная обработка не поможет: транзакция отклонена.
+		h.logger.WarnContext(ctx, "transaction rejected", logging.Err(err))
+		return h.sendToDeadLetter(ctx, msg, ReasonRejected, err, attempts)
+	case repository.IsTransient(err):
+		h.logger.ErrorContext(ctx, "could not save transaction", slog.Int("attempts", attempts), logging.Err(err))
+		return h.sendToDeadLetter(ctx, msg, ReasonRetriesExhausted, err, attempts)
+	default:
+		h.logger.ErrorContext(ctx, "could not save transaction", logging.Err(err))
+		return h.sendToDeadLetter(ctx, msg, ReasonSaveFailed, err, attempts)
+	}
+}
+
+// sendToDeadLetter публикует сообщение в dead-letter topic и возвращает true,
+// если offset исходного сообщения можно коммитить.
+func (h *Handler) sendToDeadLetter(ctx context.Context, msg kafka.Message, reason string, cause error, attempts int) bool {
+	switch reason {
+	case ReasonUnmarshal, ReasonValidation:
+		h.metrics.Invalid(reason)
+	default:
+		h.metrics.Failed(reason)
+	}
+
+	if h.deadLetter == nil {
+		h.logger.WarnContext(ctx, "dropping message", slog.String("reason", reason))
+		return true
+	}
+
+	dlqMsg := newDeadLetterMessage(msg, reason, cause, attempts, time.Now())
+	if !h.retryWrite(ctx, "failed to write message to dead-letter topic", func(ctx context.Context) error {
+		return h.deadLetter.WriteMessages(ctx, dlqMsg)
+	}) {
+		// Не коммитим, чтобы сообщение не потерялось: оно будет прочитано повторно.
+		return false
+	}
 
-		// Коммитим сообщение только после успешного сохранения в БД
-		if err := h.reader.CommitMessages(ctx, msg); err != nil {
-			log.Printf("failed to commit message after successful save: %v", err)
+	h.logger.WarnContext(ctx, "sent message to dead-letter topic", slog.String("reason", reason))
+	return true
+}
+
+// retryWrite повторяет write с задержкой, пока запись не пройдет, и логирует каждую
+// неудачную попытку как msg. Без ограничения числа попыток сообщение не остается
+// необработанным и не задерживает коммит offset'ов своей партиции.
+// Возвращает false, только если consumer остановлен.
+func (h *Handler) retryWrite(ctx context.Context, msg string, write func(ctx context.Context) error) bool {
+	_, err := h.retry.DoUntilCancelled(ctx, func(ctx context.Context) error {
+		err := write(ctx)
+		if err != nil && ctx.Err() == nil {
+			h.logger.ErrorContext(ctx, msg, logging.Err(err))
 		}
+		return err
+	}, func(error) bool { return true })
+	return err == nil
+}
 
-		log.Printf("Successfully processed and committed transaction for user_id: %s, amount: %d", tx.UserID, tx.Amount)
+// parseMessage декодирует сообщение, дополняет и проверяет его по правилам validation.Normalize.
+// При ошибке возвращает причину для dead-letter topic.
+func (h *Handler) parseMessage(ctx context.Context, msg kafka.Message) (models.Transaction, string, error) {
+	_, span := h.tracer.Start(ctx, "validate")
+	defer span.End()
+
+	var tx models.Transaction
+	if err := json.Unmarshal(msg.Value, &tx); err != nil {
+		// Содержимое сообщения не логируется: оно сохраняется в dead-letter topic.
+		h.logger.WarnContext(ctx, "could not unmarshal message", slog.Int("size", len(msg.Value)), logging.Err(err))
+		span.SetStatus(codes.Error, ReasonUnmarshal)
+		return tx, ReasonUnmarshal, err
 	}
+
+	generated := tx.TransactionID == ""
+	tx, err := validation.Normalize(tx, h.defaultCurrency, time.Now())
+	if err != nil {
+		h.logger.WarnContext(ctx, "invalid transaction", logging.UserID(tx.UserID), logging.Err(err))
+		span.SetStatus(codes.Error, err.Error())
+		return tx, ReasonValidation, err
+	}
+	span.SetAttributes(attribute.String("transaction.id", tx.TransactionID))
+	if generated {
+		h.logger.InfoContext(ctx, "generated transaction_id", logging.UserID(tx.UserID), slog.String(logging.KeyTransactionID, tx.TransactionID))
+	}
+	return tx, "", nil
+}
+
+// withTransaction добавляет к записям лога пользователя и идентификатор транзакции tx.
+func withTransaction(ctx context.Context, tx models.Transaction) context.Context {
+	return logging.With(ctx, logging.UserID(tx.UserID), slog.String(logging.KeyTransactionID, tx.TransactionID))
 }
 
-func generateTransactionID(tx models.Transaction) string {
-	data := fmt.Sprintf("%s:%s:%d:%d", tx.UserID, tx.TransactionType, tx.Amount, tx.Timestamp.UnixNano())
-	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:])
+// isStopError сообщает, что чтение остановлено: контекст отменен или reader закрыт.
+func isStopError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || err == io.EOF
 }
@@ -7,8 +7,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/OlgaPie/casino-transaction-system/internal/migrate"
 	"github.com/OlgaPie/casino-transaction-system/internal/models"
 	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/migrations"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/require"
@@ -52,10 +54,15 @@ func TestConsumerHandler_ProcessMessages(t *testing.T) {
 		UserID:          "test-user-consumer",
 		TransactionType: models.TransactionTypeBet,
 		Amount:          9990,
+		Currency:        "USD",
 	}
 	message, err := json.Marshal(testTx)
 	require.NoError(t, err)
 
+	// Пополняем баланс, иначе ставка будет отклонена
+	_, err = dbpool.Exec(ctx, "INSERT INTO balances (user_id, currency, balance) VALUES ($1, $2, $3)", testTx.UserID, testTx.Currency, 100000)
+	require.NoError(t, err)
+
 	// 4. Настройка нашего consumer'а
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers: []string{kafkaBroker},
@@ -100,6 +107,10 @@ func TestConsumerHandler_ProcessMessages(t *testing.T) {
 	require.Equal(t, testTx.TransactionType, savedTx.TransactionType)
 	require.Equal(t, testTx.Amount, savedTx.Amount)
 
+	var balance int64
+	require.NoError(t, dbpool.QueryRow(ctx, "SELECT balance FROM balances WHERE user_id = $1 AND currency = $2", testTx.UserID, testTx.Currency).Scan(&balance))
+	require.Equal(t, int64(100000)-testTx.Amount, balance)
+
 	// 8. Остановка consumer'а и очистка
 	cancel()
 	<-done
@@ -130,23 +141,22 @@ func setupTestDB(ctx context.Context) (*postgres.PostgresContainer, *pgxpool.Poo
 		return nil, nil, err
 	}
 
-	_, err = dbpool.Exec(ctx, `CREATE TABLE transactions (
-        id SERIAL PRIMARY KEY,
-        transaction_id VARCHAR(255) NOT NULL,
-        user_id VARCHAR(255) NOT NULL,
-        transaction_type VARCHAR(10) NOT NULL CHECK (transaction_type IN ('bet', 'win')),
-        amount BIGINT NOT NULL,
-        "timestamp" TIMESTAMPTZ NOT NULL
-    );
-    CREATE UNIQUE INDEX idx_transactions_transaction_id ON transactions (transaction_id);
-    CREATE INDEX idx_transactions_user_id ON transactions (user_id);`)
-	if err != nil {
+	if err := applyMigrations(ctx, dbpool); err != nil {
 		return nil, nil, err
 	}
 
 	return pgContainer, dbpool, nil
 }
 
+// applyMigrations применяет миграции из migrations/ так же, как сервисы при запуске.
+func applyMigrations(ctx context.Context, dbpool *pgxpool.Pool) error {
+	migrator, err := migrate.New(dbpool, migrations.FS, nil)
+	if err != nil {
+		return err
+	}
+	return migrator.Up(ctx)
+}
+
 func createTestTopic(t *testing.T, broker, topic string) {
 	conn, err := kafka.Dial("tcp", broker)
 	require.NoError(t, err)
@@ -4,13 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
 	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockMessageReader struct {
@@ -30,6 +36,42 @@ func (m *MockMessageReader) CommitMessages(ctx context.Context, msgs ...kafka.Me
 	return args.Error(0)
 }
 
+// fakeDeadLetterWriter хранит отправленные в dead-letter topic сообщения в памяти.
+type fakeDeadLetterWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	err      error
+	// failures — сколько первых вызовов возвращают err; 0 — все вызовы.
+	failures int
+	calls    int
+}
+
+func (w *fakeDeadLetterWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.calls++
+	if w.err != nil && (w.failures == 0 || w.calls <= w.failures) {
+		return w.err
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeDeadLetterWriter) Messages() []kafka.Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]kafka.Message(nil), w.messages...)
+}
+
+func headerValue(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
 func TestConsumerHandler_ErrorScenarios(t *testing.T) {
 	//  Тест 1: Ошибка парсинга JSON
 	t.Run("should skip message on unmarshal error", func(t *testing.T) {
@@ -49,7 +91,7 @@ func TestConsumerHandler_ErrorScenarios(t *testing.T) {
 		time.Sleep(50 * time.Millisecond)
 		cancel()
 
-		mockRepo.AssertNotCalled(t, "SaveTransaction", mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything, mock.Anything)
 		mockReader.AssertExpectations(t)
 	})
 
@@ -59,7 +101,7 @@ func TestConsumerHandler_ErrorScenarios(t *testing.T) {
 		mockRepo := new(mocks.TransactionRepository)
 		ctx, cancel := context.WithCancel(context.Background())
 
-		tx := models.Transaction{TransactionID: "test-001", UserID: "u1", TransactionType: "refund", Amount: 10000}
+		tx := models.Transaction{TransactionID: "test-001", UserID: "u1", TransactionType: "bonus", Amount: 10000, Currency: "USD"}
 		msgBytes, _ := json.Marshal(tx)
 		message := kafka.Message{Value: msgBytes}
 		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
@@ -72,7 +114,7 @@ func TestConsumerHandler_ErrorScenarios(t *testing.T) {
 		time.Sleep(50 * time.Millisecond)
 		cancel()
 
-		mockRepo.AssertNotCalled(t, "SaveTransaction", mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything, mock.Anything)
 		mockReader.AssertExpectations(t)
 	})
 
@@ -82,7 +124,7 @@ func TestConsumerHandler_ErrorScenarios(t *testing.T) {
 		mockRepo := new(mocks.TransactionRepository)
 		ctx, cancel := context.WithCancel(context.Background())
 
-		tx := models.Transaction{TransactionID: "test-002", UserID: "u1", TransactionType: "bet", Amount: -5000}
+		tx := models.Transaction{TransactionID: "test-002", UserID: "u1", TransactionType: "bet", Amount: -5000, Currency: "USD"}
 		msgBytes, _ := json.Marshal(tx)
 		message := kafka.Message{Value: msgBytes}
 		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
@@ -95,23 +137,81 @@ func TestConsumerHandler_ErrorScenarios(t *testing.T) {
 		time.Sleep(50 * time.Millisecond)
 		cancel()
 
-		mockRepo.AssertNotCalled(t, "SaveTransaction", mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything, mock.Anything)
+		mockReader.AssertExpectations(t)
+	})
+
+	//  Тест 4: Временная ошибка БД повторяется
+	t.Run("should retry transient repository errors and commit after success", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tx := models.Transaction{TransactionID: "test-003", UserID: "u1", TransactionType: "win", Amount: 20000, Currency: "USD"}
+		msgBytes, _ := json.Marshal(tx)
+		message := kafka.Message{Value: msgBytes}
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{message}).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+
+		serializationFailure := &pgconn.PgError{Code: "40001"}
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).Return(false, serializationFailure).Twice()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).Return(true, nil).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockRepo.AssertExpectations(t)
 		mockReader.AssertExpectations(t)
 	})
 
-	//  Тест 4: Ошибка сохранения в БД
-	t.Run("should not commit offset when repository fails", func(t *testing.T) {
+	//  Тест 4.1: Остановка во время повторов
+	t.Run("should not commit offset when stopped during retries", func(t *testing.T) {
 		mockReader := new(MockMessageReader)
 		mockRepo := new(mocks.TransactionRepository)
 		ctx, cancel := context.WithCancel(context.Background())
 
-		tx := models.Transaction{TransactionID: "test-003", UserID: "u1", TransactionType: "win", Amount: 20000}
+		tx := models.Transaction{TransactionID: "test-003-1", UserID: "u1", TransactionType: "win", Amount: 20000, Currency: "USD"}
 		msgBytes, _ := json.Marshal(tx)
 		message := kafka.Message{Value: msgBytes}
 		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
 		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
 
-		mockRepo.On("SaveTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).Return(errors.New("db error")).Once()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).
+			Return(false, &pgconn.PgError{Code: "40P01"}).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		time.Sleep(50 * time.Millisecond)
+
+		mockRepo.AssertExpectations(t)
+		// CommitMessages не должен вызываться, пока сохранение не завершилось
+		mockReader.AssertNotCalled(t, "CommitMessages", mock.Anything, mock.Anything)
+		mockReader.AssertExpectations(t)
+	})
+
+	//  Тест 5: Ставка превышает баланс
+	t.Run("should commit message when bet is rejected for insufficient funds", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tx := models.Transaction{TransactionID: "test-004", UserID: "u1", TransactionType: "bet", Amount: 20000, Currency: "USD"}
+		msgBytes, _ := json.Marshal(tx)
+		message := kafka.Message{Value: msgBytes}
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, mock.Anything).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).
+			Return(false, fmt.Errorf("could not save transaction: %w", repository.ErrInsufficientFunds)).Once()
 
 		handler := NewHandler(mockReader, mockRepo)
 
@@ -119,10 +219,244 @@ func TestConsumerHandler_ErrorScenarios(t *testing.T) {
 		time.Sleep(50 * time.Millisecond)
 		cancel()
 
+		mockRepo.AssertExpectations(t)
+		mockReader.AssertExpectations(t)
+	})
+}
+
+func TestConsumerHandler_DeadLetter(t *testing.T) {
+	//  Тест 1: Невалидное сообщение уходит в DLQ с описанием ошибки
+	t.Run("should publish invalid message to dead-letter topic and commit", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		dlq := &fakeDeadLetterWriter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		message := kafka.Message{
+			Topic:     "transactions",
+			Partition: 3,
+			Offset:    42,
+			Key:       []byte("u1"),
+			Value:     []byte("not a json"),
+			Headers:   []kafka.Header{{Key: "source", Value: []byte("provider-a")}},
+		}
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{message}).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+
+		handler := NewHandler(mockReader, mockRepo, WithDeadLetterWriter(dlq))
+
+		go handler.ProcessMessages(ctx)
 		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockReader.AssertExpectations(t)
+		published := dlq.Messages()
+		require.Len(t, published, 1)
+		dlqMsg := published[0]
+		assert.Equal(t, message.Key, dlqMsg.Key)
+		assert.Equal(t, message.Value, dlqMsg.Value)
+		assert.Equal(t, "provider-a", headerValue(dlqMsg, "source"))
+		assert.Equal(t, ReasonUnmarshal, headerValue(dlqMsg, HeaderDeadLetterReason))
+		assert.NotEmpty(t, headerValue(dlqMsg, HeaderDeadLetterError))
+		assert.Equal(t, "transactions", headerValue(dlqMsg, HeaderDeadLetterTopic))
+		assert.Equal(t, "3", headerValue(dlqMsg, HeaderDeadLetterPartition))
+		assert.Equal(t, "42", headerValue(dlqMsg, HeaderDeadLetterOffset))
+		assert.Equal(t, "1", headerValue(dlqMsg, HeaderDeadLetterAttempts))
+		assert.NotEmpty(t, headerValue(dlqMsg, HeaderDeadLetterFailedAt))
+	})
+
+	//  Тест 2: Отклоненная ставка уходит в DLQ
+	t.Run("should publish rejected bet to dead-letter topic", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		dlq := &fakeDeadLetterWriter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tx := models.Transaction{TransactionID: "test-dlq-001", UserID: "u1", TransactionType: "bet", Amount: 500, Currency: "USD"}
+		msgBytes, _ := json.Marshal(tx)
+		message := kafka.Message{Value: msgBytes}
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, mock.Anything).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).
+			Return(false, repository.ErrInsufficientFunds).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithDeadLetterWriter(dlq))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockReader.AssertExpectations(t)
+		published := dlq.Messages()
+		require.Len(t, published, 1)
+		assert.Equal(t, ReasonRejected, headerValue(published[0], HeaderDeadLetterReason))
+	})
+
+	//  Тест 2.0: Неизвестная валюта отклоняется при сохранении
+	t.Run("should publish transaction in unknown currency to dead-letter topic", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		dlq := &fakeDeadLetterWriter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tx := models.Transaction{TransactionID: "test-dlq-004", UserID: "u1", TransactionType: "win", Amount: 500, Currency: "XXX"}
+		msgBytes, _ := json.Marshal(tx)
+		message := kafka.Message{Value: msgBytes}
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, mock.Anything).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).
+			Return(false, fmt.Errorf("could not save transaction: %w", repository.ErrUnknownCurrency)).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithDeadLetterWriter(dlq))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockRepo.AssertExpectations(t)
+		mockReader.AssertExpectations(t)
+		published := dlq.Messages()
+		require.Len(t, published, 1)
+		assert.Equal(t, ReasonRejected, headerValue(published[0], HeaderDeadLetterReason))
+	})
+
+	//  Тест 2.0.1: Валюта по умолчанию подставляется в сообщения без currency
+	t.Run("should apply default currency to message without currency", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		message := kafka.Message{Value: []byte(`{"transaction_id":"test-cur-001","user_id":"u1","transaction_type":"win","amount":500}`)}
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, mock.Anything).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.MatchedBy(func(tx models.Transaction) bool {
+			return tx.TransactionID == "test-cur-001" && tx.Currency == "EUR"
+		})).Return(true, nil).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithDefaultCurrency("EUR"))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockRepo.AssertExpectations(t)
+		mockReader.AssertExpectations(t)
+	})
+
+	//  Тест 2.1: Постоянная ошибка БД не повторяется
+	t.Run("should publish permanently failing message without retrying", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		dlq := &fakeDeadLetterWriter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tx := models.Transaction{TransactionID: "test-dlq-002", UserID: "u1", TransactionType: "win", Amount: 500, Currency: "USD"}
+		msgBytes, _ := json.Marshal(tx)
+		message := kafka.Message{Value: msgBytes}
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, mock.Anything).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).
+			Return(false, &pgconn.PgError{Code: "23514"}).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithDeadLetterWriter(dlq))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockRepo.AssertExpectations(t)
+		mockReader.AssertExpectations(t)
+		published := dlq.Messages()
+		require.Len(t, published, 1)
+		assert.Equal(t, ReasonSaveFailed, headerValue(published[0], HeaderDeadLetterReason))
+		assert.Equal(t, "1", headerValue(published[0], HeaderDeadLetterAttempts))
+	})
+
+	//  Тест 2.2: Временная ошибка БД не прошла за все попытки
+	t.Run("should publish message after retries are exhausted", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		dlq := &fakeDeadLetterWriter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tx := models.Transaction{UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"}
+		msgBytes, _ := json.Marshal(tx)
+		message := kafka.Message{Value: msgBytes}
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{message}).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).
+			Return(false, &pgconn.PgError{Code: "08006"}).Times(3)
+
+		handler := NewHandler(mockReader, mockRepo,
+			WithDeadLetterWriter(dlq),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
 
 		mockRepo.AssertExpectations(t)
-		// CommitMessages не должен вызываться при ошибке БД
+		mockReader.AssertExpectations(t)
+		published := dlq.Messages()
+		require.Len(t, published, 1)
+		assert.Equal(t, ReasonRetriesExhausted, headerValue(published[0], HeaderDeadLetterReason))
+		assert.Equal(t, "3", headerValue(published[0], HeaderDeadLetterAttempts))
+	})
+
+	//  Тест 2.3: С WithRetryUntilStopped временная ошибка повторяется дольше MaxAttempts
+	t.Run("should keep retrying transient errors until stopped when enabled", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		dlq := &fakeDeadLetterWriter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tx := models.Transaction{UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"}
+		msgBytes, _ := json.Marshal(tx)
+		message := kafka.Message{Value: msgBytes}
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{message}).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).
+			Return(false, &pgconn.PgError{Code: "08006"}).Times(10)
+		mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).Return(true, nil).Once()
+
+		handler := NewHandler(mockReader, mockRepo,
+			WithDeadLetterWriter(dlq),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+			WithRetryUntilStopped())
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+
+		mockRepo.AssertExpectations(t)
+		mockReader.AssertExpectations(t)
+		assert.Empty(t, dlq.Messages())
+	})
+
+	//  Тест 3: Ошибка записи в DLQ
+	t.Run("should not commit when dead-letter write fails", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		dlq := &fakeDeadLetterWriter{err: errors.New("broker unavailable")}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		message := kafka.Message{Value: []byte("not a json")}
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+
+		handler := NewHandler(mockReader, mockRepo, WithDeadLetterWriter(dlq))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
 		mockReader.AssertNotCalled(t, "CommitMessages", mock.Anything, mock.Anything)
 		mockReader.AssertExpectations(t)
 	})
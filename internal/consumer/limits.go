@@ -0,0 +1,54 @@
+package consumer
+
+import (
+	"context"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/limits"
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// EventWriter публикует события о нарушениях лимитов.
+// *kafka.Writer удовлетворяет этому интерфейсу.
+type EventWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// WithLimits включает обработку нарушений лимитов ответственной игры, которые
+// репозиторий возвращает при сохранении (см. repository.WithSaveChecker).
+// Нарушения сохраняются через recorder и публикуются в events (если он задан),
+// а исходное сообщение уходит в dead-letter topic с причиной ReasonLimitExceeded.
+func WithLimits(recorder limits.ViolationRecorder, events EventWriter) Option {
+	return func(h *Handler) {
+		h.limits = recorder
+		h.limitEvents = events
+	}
+}
+
+// handleViolation сохраняет и публикует нарушение лимита, после чего отправляет
+// сообщение в dead-letter topic. Запись нарушения и события повторяется до успеха.
+// Возвращает true, если offset сообщения можно коммитить.
+func (h *Handler) handleViolation(ctx context.Context, msg kafka.Message, violation *limits.ViolationError, attempts int) bool {
+	h.logger.WarnContext(ctx, "transaction violates a responsible gambling limit", logging.Err(violation))
+
+	if h.limits != nil && !h.retryWrite(ctx, "could not record limit violation", func(ctx context.Context) error {
+		return h.limits.RecordViolation(ctx, violation.Violation)
+	}) {
+		return false
+	}
+
+	if h.limitEvents != nil {
+		event, err := limits.ViolationEvent(violation.Violation)
+		if err != nil {
+			// Событие не публикуется, но нарушение уже сохранено и уйдет в dead-letter topic.
+			h.logger.ErrorContext(ctx, "could not encode limit violation event", logging.Err(err))
+		} else if !h.retryWrite(ctx, "could not publish limit violation event", func(ctx context.Context) error {
+			return h.limitEvents.WriteMessages(ctx, event)
+		}) {
+			return false
+		}
+	}
+
+	return h.sendToDeadLetter(ctx, msg, ReasonLimitExceeded, violation, attempts)
+}
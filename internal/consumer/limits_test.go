@@ -0,0 +1,169 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/limits"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeViolationRecorder запоминает сохраненные нарушения.
+type fakeViolationRecorder struct {
+	mu         sync.Mutex
+	recordErr  error
+	violations []models.LimitViolation
+}
+
+func (r *fakeViolationRecorder) RecordViolation(_ context.Context, v models.LimitViolation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recordErr != nil {
+		return r.recordErr
+	}
+	r.violations = append(r.violations, v)
+	return nil
+}
+
+func (r *fakeViolationRecorder) Violations() []models.LimitViolation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.LimitViolation(nil), r.violations...)
+}
+
+// wagerViolation — ошибка, которую репозиторий возвращает для ставки tx сверх лимита.
+func wagerViolation(tx models.Transaction, limit int64) error {
+	return fmt.Errorf("could not save transaction: %w", &limits.ViolationError{Violation: models.LimitViolation{
+		UserID:          tx.UserID,
+		TransactionID:   tx.TransactionID,
+		ViolationType:   models.ViolationWagerLimit,
+		Period:          models.LimitPeriodDay,
+		Currency:        tx.Currency,
+		LimitAmount:     limit,
+		AttemptedAmount: tx.Amount,
+	}})
+}
+
+// withID сопоставляет транзакцию по transaction_id.
+func withID(id string) any {
+	return mock.MatchedBy(func(tx models.Transaction) bool { return tx.TransactionID == id })
+}
+
+func TestConsumerHandler_Limits(t *testing.T) {
+	//  Тест 1: Ставка сверх лимита не сохраняется, нарушение записывается и публикуется
+	t.Run("should reject bet over limit, record and publish violation", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		recorder := &fakeViolationRecorder{}
+		events := &fakeDeadLetterWriter{}
+		dlq := &fakeDeadLetterWriter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tx := models.Transaction{TransactionID: "lim-001", UserID: "u1", TransactionType: "bet", Amount: 5000, Currency: "USD"}
+		message := newTestMessage(t, 1, tx)
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{message}).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockRepo.On("CreateTransaction", mock.Anything, withID("lim-001")).Return(false, wagerViolation(tx, 1000)).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithDeadLetterWriter(dlq), WithLimits(recorder, events))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		// Нарушение лимита не повторяется
+		mockRepo.AssertExpectations(t)
+		mockReader.AssertExpectations(t)
+
+		violations := recorder.Violations()
+		require.Len(t, violations, 1)
+		assert.Equal(t, "lim-001", violations[0].TransactionID)
+
+		published := events.Messages()
+		require.Len(t, published, 1)
+		assert.Equal(t, []byte("u1"), published[0].Key)
+		var event models.LimitViolation
+		require.NoError(t, json.Unmarshal(published[0].Value, &event))
+		assert.Equal(t, models.ViolationWagerLimit, event.ViolationType)
+
+		dead := dlq.Messages()
+		require.Len(t, dead, 1)
+		assert.Equal(t, ReasonLimitExceeded, headerValue(dead[0], HeaderDeadLetterReason))
+	})
+
+	//  Тест 2: Ошибка записи нарушения не дает закоммитить offset
+	t.Run("should not commit when violation cannot be recorded", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		recorder := &fakeViolationRecorder{recordErr: errors.New("database is down")}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tx := models.Transaction{TransactionID: "lim-002", UserID: "u1", TransactionType: "bet", Amount: 5000, Currency: "USD"}
+		message := newTestMessage(t, 1, tx)
+		mockReader.On("FetchMessage", mock.Anything).Return(message, nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockRepo.On("CreateTransaction", mock.Anything, withID("lim-002")).Return(false, wagerViolation(tx, 1000)).Once()
+
+		handler := NewHandler(mockReader, mockRepo, WithLimits(recorder, nil))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockReader.AssertNotCalled(t, "CommitMessages", mock.Anything, mock.Anything)
+	})
+
+	//  Тест 3: Пачка с нарушением сохраняется по одной транзакции, нарушитель уходит в DLQ
+	t.Run("should save batch one by one when it violates a limit", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		mockRepo := new(mocks.TransactionRepository)
+		recorder := &fakeViolationRecorder{}
+		dlq := &fakeDeadLetterWriter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		txs := []models.Transaction{
+			{TransactionID: "lim-003", UserID: "u1", TransactionType: "bet", Amount: 500, Currency: "USD"},
+			{TransactionID: "lim-004", UserID: "u1", TransactionType: "bet", Amount: 5000, Currency: "USD"},
+			{TransactionID: "lim-005", UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"},
+		}
+		var msgs []kafka.Message
+		for i, tx := range txs {
+			msg := newTestMessage(t, int64(i+1), tx)
+			msgs = append(msgs, msg)
+			mockReader.On("FetchMessage", mock.Anything).Return(msg, nil).Once()
+		}
+		mockReader.On("CommitMessages", mock.Anything, msgs).Return(nil).Once()
+		mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+		mockRepo.On("CreateTransactions", mock.Anything, mock.Anything).Return([]string(nil), wagerViolation(txs[1], 1000)).Once()
+		mockRepo.On("CreateTransaction", mock.Anything, withID("lim-003")).Return(true, nil).Once()
+		mockRepo.On("CreateTransaction", mock.Anything, withID("lim-004")).Return(false, wagerViolation(txs[1], 1000)).Once()
+		mockRepo.On("CreateTransaction", mock.Anything, withID("lim-005")).Return(true, nil).Once()
+
+		handler := NewHandler(mockReader, mockRepo,
+			WithDeadLetterWriter(dlq),
+			WithBatching(3, time.Second),
+			WithLimits(recorder, nil))
+
+		go handler.ProcessMessages(ctx)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		mockRepo.AssertExpectations(t)
+		mockReader.AssertExpectations(t)
+		require.Len(t, recorder.Violations(), 1)
+		dead := dlq.Messages()
+		require.Len(t, dead, 1)
+		assert.Equal(t, "2", headerValue(dead[0], HeaderDeadLetterOffset))
+	})
+}
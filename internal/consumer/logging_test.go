@@ -0,0 +1,86 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer — bytes.Buffer, в который можно писать из горутины consumer'а.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records возвращает записи лога с сообщением msg.
+func (b *syncBuffer) records(t *testing.T, msg string) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(b.buf.Bytes()))
+	for dec.More() {
+		var record map[string]any
+		require.NoError(t, dec.Decode(&record))
+		if record["msg"] == msg {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestConsumerHandler_Logging(t *testing.T) {
+	mockReader := new(MockMessageReader)
+	mockRepo := new(mocks.TransactionRepository)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tx := models.Transaction{TransactionID: "log-001", UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"}
+	value, _ := json.Marshal(tx)
+	saved := kafka.Message{Topic: "transactions", Partition: 2, Offset: 40, Value: value}
+	invalid := kafka.Message{Topic: "transactions", Partition: 2, Offset: 41, Value: []byte(`{"user_id": "u1", "password": "hunter2"`)}
+	mockReader.On("FetchMessage", mock.Anything).Return(saved, nil).Once()
+	mockReader.On("FetchMessage", mock.Anything).Return(invalid, nil).Once()
+	mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+	mockReader.On("CommitMessages", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).Return(true, nil).Once()
+
+	var out syncBuffer
+	handler := NewHandler(mockReader, mockRepo, WithLogger(logging.New(&out, slog.LevelInfo)))
+
+	go handler.ProcessMessages(ctx)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	records := out.records(t, "transaction saved")
+	require.Len(t, records, 1)
+	assert.Equal(t, float64(2), records[0][logging.KeyPartition])
+	assert.Equal(t, float64(40), records[0][logging.KeyOffset])
+	assert.Equal(t, "u1", records[0][logging.KeyUserID])
+	assert.Equal(t, "log-001", records[0][logging.KeyTransactionID])
+
+	// Содержимое нераспознанного сообщения не попадает в лог.
+	records = out.records(t, "could not unmarshal message")
+	require.Len(t, records, 1)
+	assert.Equal(t, float64(41), records[0][logging.KeyOffset])
+	out.mu.Lock()
+	assert.NotContains(t, out.buf.String(), "hunter2")
+	out.mu.Unlock()
+}
@@ -0,0 +1,69 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/metrics"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestConsumerHandler_Metrics(t *testing.T) {
+	mockReader := new(MockMessageReader)
+	mockRepo := new(mocks.TransactionRepository)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	newMessage := func(offset int64, tx models.Transaction) kafka.Message {
+		value, _ := json.Marshal(tx)
+		return kafka.Message{Topic: "transactions", Partition: 2, Offset: offset, HighWaterMark: 10, Value: value}
+	}
+	fresh := newMessage(5, models.Transaction{TransactionID: "met-001", UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"})
+	duplicate := newMessage(6, models.Transaction{TransactionID: "met-002", UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"})
+	invalid := kafka.Message{Topic: "transactions", Partition: 2, Offset: 7, HighWaterMark: 10, Value: []byte("not json")}
+
+	for _, msg := range []kafka.Message{fresh, duplicate, invalid} {
+		mockReader.On("FetchMessage", mock.Anything).Return(msg, nil).Once()
+	}
+	mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+	mockReader.On("CommitMessages", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("CreateTransaction", mock.Anything, mock.MatchedBy(func(tx models.Transaction) bool { return tx.TransactionID == "met-001" })).
+		Return(true, nil).Once()
+	mockRepo.On("CreateTransaction", mock.Anything, mock.MatchedBy(func(tx models.Transaction) bool { return tx.TransactionID == "met-002" })).
+		Return(false, nil).Once()
+
+	reg := prometheus.NewRegistry()
+	handler := NewHandler(mockReader, mockRepo, WithMetrics(metrics.NewConsumer(reg)))
+
+	go handler.ProcessMessages(ctx)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	mockRepo.AssertExpectations(t)
+	expected := `
+# HELP casino_consumer_messages_fetched_total Messages fetched from Kafka.
+# TYPE casino_consumer_messages_fetched_total counter
+casino_consumer_messages_fetched_total 3
+# HELP casino_consumer_transactions_saved_total Transactions inserted into the database.
+# TYPE casino_consumer_transactions_saved_total counter
+casino_consumer_transactions_saved_total 1
+# HELP casino_consumer_transactions_duplicate_total Transactions skipped because their transaction_id was already stored.
+# TYPE casino_consumer_transactions_duplicate_total counter
+casino_consumer_transactions_duplicate_total 1
+# HELP casino_consumer_messages_invalid_total Messages that could not be decoded or failed validation, by reason.
+# TYPE casino_consumer_messages_invalid_total counter
+casino_consumer_messages_invalid_total{reason="unmarshal_error"} 1
+# HELP casino_consumer_lag_messages Messages behind the partition's high watermark as of the last fetched message.
+# TYPE casino_consumer_lag_messages gauge
+casino_consumer_lag_messages{partition="2",topic="transactions"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+}
@@ -0,0 +1,114 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// offsetTracker коммитит offset'ы партиции только до первого необработанного сообщения.
+// Сообщения одной партиции могут обрабатываться разными воркерами и завершаться
+// не по порядку, а коммит offset N в Kafka подтверждает все сообщения до N.
+type offsetTracker struct {
+	reader MessageReader
+
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+type partitionOffsets struct {
+	// mu удерживается и во время коммита, чтобы коммиты партиции не переупорядочились.
+	mu sync.Mutex
+	// pending — полученные, но еще не закоммиченные сообщения в порядке чтения.
+	pending []kafka.Message
+	// done содержит offset'ы всех сообщений из pending; true — сообщение обработано.
+	done map[int64]bool
+}
+
+func newOffsetTracker(reader MessageReader) *offsetTracker {
+	return &offsetTracker{reader: reader, partitions: make(map[partitionKey]*partitionOffsets)}
+}
+
+func (t *offsetTracker) partition(msg kafka.Message) *partitionOffsets {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := partitionKey{topic: msg.Topic, partition: msg.Partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		t.partitions[key] = p
+	}
+	return p
+}
+
+// track регистрирует полученное сообщение. Вызывается в порядке чтения.
+// Offset не больше последнего полученного означает, что после ребалансировки
+// партиция снова назначена и читается с закоммиченного offset'а: прежние сообщения
+// будут прочитаны заново, и их состояние сбрасывается.
+func (t *offsetTracker) track(msg kafka.Message) {
+	p := t.partition(msg)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if n := len(p.pending); n > 0 && msg.Offset <= p.pending[n-1].Offset {
+		p.pending = nil
+		clear(p.done)
+	}
+	p.pending = append(p.pending, msg)
+	p.done[msg.Offset] = false
+}
+
+// commit отмечает сообщения обработанными и коммитит в каждой затронутой партиции
+// наибольший offset, до которого все сообщения обработаны.
+func (t *offsetTracker) commit(ctx context.Context, msgs ...kafka.Message) error {
+	byPartition := make(map[*partitionOffsets][]kafka.Message)
+	for _, msg := range msgs {
+		p := t.partition(msg)
+		byPartition[p] = append(byPartition[p], msg)
+	}
+
+	var firstErr error
+	for p, partitionMsgs := range byPartition {
+		if err := p.commit(ctx, t.reader, partitionMsgs); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (p *partitionOffsets) commit(ctx context.Context, reader MessageReader, msgs []kafka.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, msg := range msgs {
+		// Сообщения, сброшенные в track, уже не ожидают коммита.
+		if _, ok := p.done[msg.Offset]; ok {
+			p.done[msg.Offset] = true
+		}
+	}
+
+	n := 0
+	for n < len(p.pending) && p.done[p.pending[n].Offset] {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+
+	// Обработанные сообщения удаляются и при ошибке коммита: коммит в Kafka накопительный,
+	// и их offset подтвердит следующий успешный коммит партиции. Если партиция отозвана,
+	// коммиты падают, а ее состояние не копится.
+	for _, msg := range p.pending[:n] {
+		delete(p.done, msg.Offset)
+	}
+	last := p.pending[n-1]
+	p.pending = p.pending[n:]
+	return reader.CommitMessages(ctx, last)
+}
@@ -0,0 +1,100 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	msg := func(partition int, offset int64) kafka.Message {
+		return kafka.Message{Topic: "transactions", Partition: partition, Offset: offset}
+	}
+
+	t.Run("should commit only contiguous processed offsets", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		tracker := newOffsetTracker(mockReader)
+		for offset := int64(0); offset < 3; offset++ {
+			tracker.track(msg(0, offset))
+		}
+
+		// Offset 1 обработан раньше 0: коммитить нечего
+		require.NoError(t, tracker.commit(context.Background(), msg(0, 1)))
+		mockReader.AssertNotCalled(t, "CommitMessages", mock.Anything, mock.Anything)
+
+		// После обработки 0 коммитится сразу до 1
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{msg(0, 1)}).Return(nil).Once()
+		require.NoError(t, tracker.commit(context.Background(), msg(0, 0)))
+
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{msg(0, 2)}).Return(nil).Once()
+		require.NoError(t, tracker.commit(context.Background(), msg(0, 2)))
+
+		mockReader.AssertExpectations(t)
+	})
+
+	t.Run("should commit partitions independently", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		tracker := newOffsetTracker(mockReader)
+		tracker.track(msg(0, 10))
+		tracker.track(msg(1, 20))
+		tracker.track(msg(1, 21))
+
+		// Партиция 0 еще обрабатывается, но это не мешает коммиту партиции 1
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{msg(1, 21)}).Return(nil).Once()
+		require.NoError(t, tracker.commit(context.Background(), msg(1, 20), msg(1, 21)))
+
+		mockReader.AssertExpectations(t)
+	})
+
+	t.Run("should retry failed commit with the next one", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		tracker := newOffsetTracker(mockReader)
+		tracker.track(msg(0, 0))
+		tracker.track(msg(0, 1))
+
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{msg(0, 0)}).Return(context.DeadlineExceeded).Once()
+		require.Error(t, tracker.commit(context.Background(), msg(0, 0)))
+
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{msg(0, 1)}).Return(nil).Once()
+		require.NoError(t, tracker.commit(context.Background(), msg(0, 1)))
+
+		mockReader.AssertExpectations(t)
+	})
+	t.Run("should drop state of a partition read again after rebalance", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		tracker := newOffsetTracker(mockReader)
+		for offset := int64(0); offset < 3; offset++ {
+			tracker.track(msg(0, offset))
+		}
+
+		// Offset 1 так и не обработан: коммитить дальше 0 нельзя
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{msg(0, 0)}).Return(nil).Once()
+		require.NoError(t, tracker.commit(context.Background(), msg(0, 0), msg(0, 2)))
+
+		// Партиция снова назначена и читается с закоммиченного offset'а
+		tracker.track(msg(0, 1))
+		tracker.track(msg(0, 2))
+		require.NoError(t, tracker.commit(context.Background(), msg(0, 2)))
+
+		mockReader.On("CommitMessages", mock.Anything, []kafka.Message{msg(0, 2)}).Return(nil).Once()
+		require.NoError(t, tracker.commit(context.Background(), msg(0, 1)))
+
+		mockReader.AssertExpectations(t)
+		assert.Empty(t, tracker.partition(msg(0, 0)).pending)
+		assert.Empty(t, tracker.partition(msg(0, 0)).done)
+	})
+
+	t.Run("should ignore offsets that are not tracked", func(t *testing.T) {
+		mockReader := new(MockMessageReader)
+		tracker := newOffsetTracker(mockReader)
+		tracker.track(msg(0, 5))
+
+		require.NoError(t, tracker.commit(context.Background(), msg(0, 4)))
+		mockReader.AssertNotCalled(t, "CommitMessages", mock.Anything, mock.Anything)
+		assert.Len(t, tracker.partition(msg(0, 5)).done, 1)
+	})
+}
@@ -0,0 +1,78 @@
+package consumer
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy задает повторные попытки с экспоненциальной задержкой.
+type RetryPolicy struct {
+	// MaxAttempts — общее число попыток в Do, включая первую.
+	MaxAttempts int
+	// BaseDelay — задержка перед второй попыткой; дальше она удваивается.
+	BaseDelay time.Duration
+	// MaxDelay ограничивает задержку сверху.
+	MaxDelay time.Duration
+	// Jitter — доля задержки от 0 до 1, на которую она случайно уменьшается,
+	// чтобы повторы разных consumer'ов не совпадали по времени.
+	Jitter float64
+}
+
+// DefaultRetryPolicy используется, если политика не задана через WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.2,
+}
+
+// Do вызывает fn, пока она возвращает ошибку, для которой retryable возвращает true,
+// но не больше MaxAttempts раз. Возвращает число сделанных попыток и последнюю ошибку.
+// Ожидание между попытками прерывается отменой ctx, тогда возвращается ctx.Err().
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error, retryable func(error) bool) (int, error) {
+	return p.do(ctx, fn, retryable, max(p.MaxAttempts, 1))
+}
+
+// DoUntilCancelled работает как Do, но не ограничивает число попыток:
+// fn повторяется, пока не выполнится, не вернет неповторяемую ошибку или не будет отменен ctx.
+func (p RetryPolicy) DoUntilCancelled(ctx context.Context, fn func(ctx context.Context) error, retryable func(error) bool) (int, error) {
+	return p.do(ctx, fn, retryable, 0)
+}
+
+// do повторяет fn не больше maxAttempts раз; 0 означает без ограничения.
+func (p RetryPolicy) do(ctx context.Context, fn func(ctx context.Context) error, retryable func(error) bool, maxAttempts int) (int, error) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return attempt, nil
+		}
+		if (maxAttempts > 0 && attempt >= maxAttempts) || !retryable(err) {
+			return attempt, err
+		}
+
+		timer := time.NewTimer(p.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// delay возвращает задержку после попытки с номером attempt (начиная с 1).
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay) && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(float64(d) * min(p.Jitter, 1) * rand.Float64())
+	}
+	return d
+}
@@ -0,0 +1,126 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Do(t *testing.T) {
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+	isTransient := func(err error) bool { return errors.Is(err, errTransient) }
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	t.Run("should return after first success", func(t *testing.T) {
+		calls := 0
+		attempts, err := policy.Do(context.Background(), func(context.Context) error {
+			calls++
+			return nil
+		}, isTransient)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempts)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should retry transient errors until success", func(t *testing.T) {
+		calls := 0
+		attempts, err := policy.Do(context.Background(), func(context.Context) error {
+			calls++
+			if calls < 3 {
+				return errTransient
+			}
+			return nil
+		}, isTransient)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("should give up after max attempts", func(t *testing.T) {
+		attempts, err := policy.Do(context.Background(), func(context.Context) error {
+			return errTransient
+		}, isTransient)
+
+		assert.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("should not retry permanent errors", func(t *testing.T) {
+		attempts, err := policy.Do(context.Background(), func(context.Context) error {
+			return errPermanent
+		}, isTransient)
+
+		assert.ErrorIs(t, err, errPermanent)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("should stop waiting when context is cancelled", func(t *testing.T) {
+		slow := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		attempts, err := slow.Do(ctx, func(context.Context) error {
+			return errTransient
+		}, isTransient)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestRetryPolicy_DoUntilCancelled(t *testing.T) {
+	errTransient := errors.New("transient")
+	isTransient := func(err error) bool { return errors.Is(err, errTransient) }
+	policy := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	t.Run("should retry past max attempts until success", func(t *testing.T) {
+		calls := 0
+		attempts, err := policy.DoUntilCancelled(context.Background(), func(context.Context) error {
+			calls++
+			if calls < 10 {
+				return errTransient
+			}
+			return nil
+		}, isTransient)
+
+		require.NoError(t, err)
+		assert.Equal(t, 10, attempts)
+	})
+
+	t.Run("should stop when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := policy.DoUntilCancelled(ctx, func(context.Context) error {
+			return errTransient
+		}, isTransient)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	assert.Equal(t, 100*time.Millisecond, policy.delay(1))
+	assert.Equal(t, 200*time.Millisecond, policy.delay(2))
+	assert.Equal(t, 800*time.Millisecond, policy.delay(4))
+	assert.Equal(t, time.Second, policy.delay(5))
+	assert.Equal(t, time.Second, policy.delay(60))
+
+	// Без MaxDelay задержка перестает расти до переполнения
+	assert.Positive(t, RetryPolicy{BaseDelay: time.Millisecond}.delay(1000))
+
+	jittered := RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+	for range 100 {
+		d := jittered.delay(1)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 100*time.Millisecond)
+	}
+}
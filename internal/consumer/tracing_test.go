@@ -0,0 +1,68 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestConsumerHandler_Tracing(t *testing.T) {
+	mockReader := new(MockMessageReader)
+	mockRepo := new(mocks.TransactionRepository)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tx := models.Transaction{TransactionID: "trace-001", UserID: "u1", TransactionType: "win", Amount: 100, Currency: "USD"}
+	value, _ := json.Marshal(tx)
+	msg := kafka.Message{
+		Topic: "transactions", Partition: 1, Offset: 42, Value: value,
+		Headers: []kafka.Header{{Key: "traceparent", Value: []byte("00-4bf92f3577b34da6a3ce929b0e0e4736-00f067aa0ba902b7-01")}},
+	}
+	mockReader.On("FetchMessage", mock.Anything).Return(msg, nil).Once()
+	mockReader.On("FetchMessage", mock.Anything).Return(kafka.Message{}, context.Canceled).Maybe()
+	mockReader.On("CommitMessages", mock.Anything, []kafka.Message{msg}).Return(nil).Once()
+
+	// Запрос к БД должен выполняться в контексте span'а SaveTransaction.
+	saveSpan := make(chan trace.SpanContext, 1)
+	mockRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("models.Transaction")).
+		Run(func(args mock.Arguments) {
+			saveSpan <- trace.SpanContextFromContext(args.Get(0).(context.Context))
+		}).
+		Return(true, nil).Once()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	handler := NewHandler(mockReader, mockRepo, WithTracerProvider(tp))
+
+	go handler.ProcessMessages(ctx)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	mockReader.AssertExpectations(t)
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929b0e0e4736", span.SpanContext().TraceID().String(), span.Name())
+		spans[span.Name()] = span
+	}
+	require.Len(t, spans, 5)
+
+	process := spans["process transactions"]
+	require.NotNil(t, process)
+	assert.Equal(t, "00f067aa0ba902b7", process.Parent().SpanID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans["FetchMessage"].Parent().SpanID().String())
+	for _, name := range []string{"validate", "SaveTransaction", "CommitMessages"} {
+		require.Contains(t, spans, name)
+		assert.Equal(t, process.SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+	}
+	assert.Equal(t, spans["SaveTransaction"].SpanContext().SpanID(), (<-saveSpan).SpanID())
+}
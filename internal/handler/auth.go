@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/auth"
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Authenticate проверяет API-ключ или bearer-токен и сохраняет клиента в контексте запроса.
+// Запросы без учетных данных или с недействительными отклоняются с 401.
+// Записи лога, сделанные дальше с контекстом запроса, и запись logging.Middleware
+// о завершении запроса получают атрибут subject.
+func Authenticate(a *auth.Authenticator, logger *slog.Logger) func(http.Handler) http.Handler {
+	logger = logging.OrDiscard(logger)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := a.Authenticate(r)
+			switch {
+			case errors.Is(err, auth.ErrMissingCredentials), errors.Is(err, auth.ErrInvalidCredentials):
+				w.Header().Set("WWW-Authenticate", `Bearer realm="casino"`)
+				WriteProblem(w, r, http.StatusUnauthorized, codeUnauthorized, err.Error())
+				return
+			case err != nil:
+				logger.ErrorContext(r.Context(), "could not authenticate request", logging.Err(err))
+				writeInternalError(w, r)
+				return
+			}
+			subject := slog.String("subject", principal.Subject)
+			logging.AddRequestAttrs(r.Context(), subject)
+			ctx := logging.With(auth.NewContext(r.Context(), principal), subject)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AccessControl проверяет разрешения клиента на отдельных маршрутах
+// и записывает отказы в журнал аудита.
+type AccessControl struct {
+	policy *auth.Policy
+	audit  repository.AuditRepository
+	logger *slog.Logger
+}
+
+func NewAccessControl(policy *auth.Policy, audit repository.AuditRepository, logger *slog.Logger) *AccessControl {
+	return &AccessControl{policy: policy, audit: audit, logger: logging.OrDiscard(logger)}
+}
+
+// Require пропускает только клиентов с разрешением permission; остальным отвечает 403.
+func (a *AccessControl) Require(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := auth.FromContext(r.Context())
+			if !a.policy.Allows(principal, permission) {
+				a.deny(w, r, principal, permission, "permission "+permission+" is required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireUserAccess пропускает к /users/{userID}/... самого игрока и back-office
+// с разрешением users:read.
+func (a *AccessControl) RequireUserAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.FromContext(r.Context())
+		if !a.policy.CanReadUser(principal, chi.URLParam(r, "userID")) {
+			a.deny(w, r, principal, auth.PermUsersRead, "access to this user is not allowed")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// deny отвечает 403 и записывает отказ в журнал аудита. Сбой записи не меняет ответ.
+func (a *AccessControl) deny(w http.ResponseWriter, r *http.Request, principal auth.Principal, permission, detail string) {
+	denial := models.AccessDenial{
+		Subject:    principal.Subject,
+		AuthMethod: string(principal.Method),
+		Roles:      principal.Roles,
+		Permission: permission,
+		HTTPMethod: r.Method,
+		Path:       r.URL.Path,
+		RequestID:  middleware.GetReqID(r.Context()),
+		OccurredAt: time.Now().UTC(),
+	}
+	a.logger.WarnContext(r.Context(), "access denied",
+		slog.Any("roles", denial.Roles), slog.String("permission", denial.Permission),
+		slog.String("method", denial.HTTPMethod), slog.String("path", denial.Path))
+	if err := a.audit.SaveAccessDenial(r.Context(), denial); err != nil {
+		a.logger.ErrorContext(r.Context(), "could not save access denial", logging.Err(err))
+	}
+	WriteProblem(w, r, http.StatusForbidden, codeForbidden, detail)
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/auth"
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	keys := new(mocks.APIKeyRepository)
+	keys.On("GetAPIKey", mock.Anything, auth.HashAPIKey("player-key")).
+		Return(models.APIKey{Name: "user-42", Scopes: []string{auth.ScopeUserRead}}, nil)
+	keys.On("GetAPIKey", mock.Anything, auth.HashAPIKey("support-key")).
+		Return(models.APIKey{Name: "agent-1", Roles: []string{"support"}}, nil)
+	keys.On("GetAPIKey", mock.Anything, auth.HashAPIKey("finance-key")).
+		Return(models.APIKey{Name: "analyst-1", Roles: []string{"finance"}}, nil)
+	keys.On("GetAPIKey", mock.Anything, auth.HashAPIKey("broken-key")).
+		Return(models.APIKey{}, errors.New("database is down"))
+	keys.On("GetAPIKey", mock.Anything, mock.Anything).
+		Return(models.APIKey{}, repository.ErrAPIKeyNotFound)
+
+	audit := new(mocks.AuditRepository)
+	audit.On("SaveAccessDenial", mock.Anything, mock.Anything).Return(nil)
+	access := NewAccessControl(auth.DefaultPolicy(), audit, nil)
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Group(func(r chi.Router) {
+		r.Use(Authenticate(auth.NewAuthenticator(auth.NewAPIKeyVerifier(keys), nil), nil))
+		r.With(access.Require(auth.PermTransactionsRead)).Get("/transactions", ok)
+		r.Route("/users/{userID}", func(r chi.Router) {
+			r.Use(access.RequireUserAccess)
+			r.Get("/balance", ok)
+		})
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		status int
+		code   string
+	}{
+		{"missing credentials", "/transactions", "", http.StatusUnauthorized, codeUnauthorized},
+		{"unknown key", "/transactions", "wrong-key", http.StatusUnauthorized, codeUnauthorized},
+		{"key store failure", "/transactions", "broken-key", http.StatusInternalServerError, CodeInternal},
+		{"finance reads all transactions", "/transactions", "finance-key", http.StatusOK, ""},
+		{"support cannot read all transactions", "/transactions", "support-key", http.StatusForbidden, codeForbidden},
+		{"player cannot read all transactions", "/transactions", "player-key", http.StatusForbidden, codeForbidden},
+		{"player reads own balance", "/users/user-42/balance", "player-key", http.StatusOK, ""},
+		{"player cannot read another user", "/users/user-43/balance", "player-key", http.StatusForbidden, codeForbidden},
+		{"support reads any user", "/users/user-43/balance", "support-key", http.StatusOK, ""},
+		{"finance cannot read a user", "/users/user-43/balance", "finance-key", http.StatusForbidden, codeForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(auth.APIKeyHeader, tt.key)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			if tt.code != "" {
+				assert.Contains(t, rr.Body.String(), `"code":"`+tt.code+`"`)
+			}
+			if tt.status == http.StatusUnauthorized {
+				assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	t.Run("should record denials in the audit trail", func(t *testing.T) {
+		audit.AssertNumberOfCalls(t, "SaveAccessDenial", 4)
+		audit.AssertCalled(t, "SaveAccessDenial", mock.Anything, mock.MatchedBy(func(d models.AccessDenial) bool {
+			return d.Subject == "agent-1" && d.AuthMethod == string(auth.MethodAPIKey) &&
+				d.Permission == auth.PermTransactionsRead && d.HTTPMethod == "GET" &&
+				d.Path == "/transactions" && d.RequestID != "" && len(d.Roles) == 1 && d.Roles[0] == "support"
+		}))
+	})
+}
+
+func TestAccessControl_AuditFailure(t *testing.T) {
+	audit := new(mocks.AuditRepository)
+	audit.On("SaveAccessDenial", mock.Anything, mock.Anything).Return(errors.New("database is down")).Once()
+	access := NewAccessControl(auth.DefaultPolicy(), audit, nil)
+
+	handler := access.Require(auth.PermLimitsWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be called")
+	}))
+	req := httptest.NewRequest("PUT", "/admin/users/user-42/limits", nil)
+	req = req.WithContext(auth.NewContext(req.Context(), auth.Principal{Subject: "agent-1", Roles: []string{"support"}}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	audit.AssertExpectations(t)
+}
+
+func TestAuthMiddleware_RequestLog(t *testing.T) {
+	keys := new(mocks.APIKeyRepository)
+	keys.On("GetAPIKey", mock.Anything, auth.HashAPIKey("player-key")).
+		Return(models.APIKey{Name: "user-42", Scopes: []string{auth.ScopeUserRead}}, nil)
+
+	var buf bytes.Buffer
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(logging.Middleware(logging.New(&buf, slog.LevelInfo)))
+	router.Group(func(r chi.Router) {
+		r.Use(Authenticate(auth.NewAuthenticator(auth.NewAPIKeyVerifier(keys), nil), nil))
+		r.Get("/users/{userID}/balance", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	})
+
+	req := httptest.NewRequest("GET", "/users/user-42/balance", nil)
+	req.Header.Set(auth.APIKeyHeader, "player-key")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Запись о запросе получает атрибуты, добавленные внутри цепочки middleware
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "request completed", record["msg"])
+	assert.Equal(t, "user-42", record["subject"])
+	assert.Equal(t, "user-42", record[logging.KeyUserID])
+}
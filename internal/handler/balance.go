@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+)
+
+type BalanceHandler struct {
+	repo   repository.WalletRepository
+	logger *slog.Logger
+}
+
+func NewBalanceHandler(repo repository.WalletRepository, logger *slog.Logger) *BalanceHandler {
+	return &BalanceHandler{repo: repo, logger: logging.OrDiscard(logger)}
+}
+
+// userBalances — ответ со всеми балансами пользователя, по одному на валюту.
+type userBalances struct {
+	UserID   string           `json:"user_id"`
+	Balances []models.Balance `json:"balances"`
+}
+
+// GetUserBalance возвращает балансы пользователя по всем валютам,
+// а с параметром currency — баланс только в этой валюте.
+func (h *BalanceHandler) GetUserBalance(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserID(r)
+	if err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	currency := r.URL.Query().Get("currency")
+	if currency != "" && !models.IsCurrencyCode(currency) {
+		writeInvalidRequest(w, r, codeInvalidParameter, fieldErrors{{Field: "currency", Message: errInvalidCurrency.Error()}})
+		return
+	}
+
+	balances, err := h.repo.GetBalances(r.Context(), userID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "could not fetch balance", logging.UserID(userID), logging.Err(err))
+		writeInternalError(w, r)
+		return
+	}
+
+	var response any = userBalances{UserID: userID, Balances: balances}
+	if currency != "" {
+		response = balanceInCurrency(userID, currency, balances)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.ErrorContext(r.Context(), "could not encode response", logging.Err(err))
+	}
+}
+
+// balanceInCurrency выбирает баланс в валюте currency.
+// Пользователь без движений в этой валюте имеет нулевой баланс.
+func balanceInCurrency(userID, currency string, balances []models.Balance) models.Balance {
+	for _, b := range balances {
+		if b.Currency == currency {
+			return b
+		}
+	}
+	return models.Balance{UserID: userID, Currency: currency}
+}
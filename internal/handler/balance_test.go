@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBalanceHandler_GetUserBalance(t *testing.T) {
+	mockRepo := new(mocks.WalletRepository)
+	handler := NewBalanceHandler(mockRepo, nil)
+	router := chi.NewRouter()
+	router.Get("/users/{userID}/balance", handler.GetUserBalance)
+
+	updatedAt := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	balances := []models.Balance{
+		{UserID: "user123", Currency: "EUR", Balance: 2000, UpdatedAt: &updatedAt},
+		{UserID: "user123", Currency: "USD", Balance: 15075, UpdatedAt: &updatedAt},
+	}
+
+	t.Run("successful retrieval of user balances", func(t *testing.T) {
+		mockRepo.On("GetBalances", mock.Anything, "user123").Return(balances, nil).Once()
+
+		req := httptest.NewRequest("GET", "/users/user123/balance", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"user_id":"user123","balances":[
+			{"user_id":"user123","currency":"EUR","balance":2000,"updated_at":"2025-05-01T10:00:00Z"},
+			{"user_id":"user123","currency":"USD","balance":15075,"updated_at":"2025-05-01T10:00:00Z"}
+		]}`, rr.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return balance in requested currency", func(t *testing.T) {
+		mockRepo.On("GetBalances", mock.Anything, "user123").Return(balances, nil).Once()
+
+		req := httptest.NewRequest("GET", "/users/user123/balance?currency=USD", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"user_id":"user123","currency":"USD","balance":15075,"updated_at":"2025-05-01T10:00:00Z"}`, rr.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return zero balance for currency without activity", func(t *testing.T) {
+		mockRepo.On("GetBalances", mock.Anything, "new-user").Return([]models.Balance{}, nil).Once()
+
+		req := httptest.NewRequest("GET", "/users/new-user/balance?currency=JPY", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"user_id":"new-user","currency":"JPY","balance":0}`, rr.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return bad request for invalid currency", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/users/user123/balance?currency=usd", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("repository returns an error for balance", func(t *testing.T) {
+		mockRepo.On("GetBalances", mock.Anything, "user-error").
+			Return([]models.Balance(nil), errors.New("database is down")).
+			Once()
+
+		req := httptest.NewRequest("GET", "/users/user-error/balance", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+)
+
+type CurrencyHandler struct {
+	repo   repository.CurrencyRepository
+	logger *slog.Logger
+}
+
+func NewCurrencyHandler(repo repository.CurrencyRepository, logger *slog.Logger) *CurrencyHandler {
+	return &CurrencyHandler{repo: repo, logger: logging.OrDiscard(logger)}
+}
+
+// GetCurrencies возвращает поддерживаемые валюты с числом знаков дробной части,
+// по которому клиенты переводят суммы из минимальных единиц.
+func (h *CurrencyHandler) GetCurrencies(w http.ResponseWriter, r *http.Request) {
+	currencies, err := h.repo.GetCurrencies(r.Context())
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "could not fetch currencies", logging.Err(err))
+		writeInternalError(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(currencies); err != nil {
+		h.logger.ErrorContext(r.Context(), "could not encode response", logging.Err(err))
+	}
+}
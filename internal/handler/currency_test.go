@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCurrencyHandler_GetCurrencies(t *testing.T) {
+	mockRepo := new(mocks.CurrencyRepository)
+	handler := NewCurrencyHandler(mockRepo, nil)
+	router := chi.NewRouter()
+	router.Get("/currencies", handler.GetCurrencies)
+
+	t.Run("successful retrieval of currencies", func(t *testing.T) {
+		mockRepo.On("GetCurrencies", mock.Anything).
+			Return([]models.Currency{{Code: "JPY", MinorUnits: 0}, {Code: "USD", MinorUnits: 2}}, nil).
+			Once()
+
+		req := httptest.NewRequest("GET", "/currencies", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `[{"code":"JPY","minor_units":0},{"code":"USD","minor_units":2}]`, rr.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("repository returns an error for currencies", func(t *testing.T) {
+		mockRepo.On("GetCurrencies", mock.Anything).
+			Return([]models.Currency(nil), errors.New("database is down")).
+			Once()
+
+		req := httptest.NewRequest("GET", "/currencies", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+)
+
+const (
+	contentTypeCSV    = "text/csv"
+	contentTypeNDJSON = "application/x-ndjson"
+	// exportFlushRows — через сколько строк выгрузка отправляется клиенту.
+	exportFlushRows = 100
+)
+
+var csvHeader = []string{
+	"id", "transaction_id", "user_id", "transaction_type", "amount", "currency", "timestamp",
+	"reference_transaction_id", "round_id", "game_id", "provider_id", "session_id",
+}
+
+// exportContentType выбирает по заголовку Accept формат потоковой выгрузки:
+// text/csv или application/x-ndjson. Пустая строка означает обычный JSON со страницами.
+// Из нескольких подходящих типов выбирается тип с наибольшим q.
+func exportContentType(r *http.Request) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case contentTypeCSV, contentTypeNDJSON:
+		case "application/json":
+			mediaType = ""
+		default:
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
+
+// exportTransactions построчно выгружает все транзакции, подходящие под filter,
+// в формате contentType. Параметры limit и cursor не применяются.
+// Строки отправляются клиенту пачками по exportFlushRows, поэтому ошибка чтения
+// после начала выгрузки приводит к обрыву ответа, а не к коду 500.
+func (h *TransactionHandler) exportTransactions(w http.ResponseWriter, r *http.Request, contentType, userID string, filter repository.TransactionFilter) {
+	rc := http.NewResponseController(w)
+	out := &trackingWriter{w: w}
+	var encode func(models.Transaction) error
+	var flush func() error
+
+	switch contentType {
+	case contentTypeCSV:
+		cw := csv.NewWriter(out)
+		// Заголовок остается в буфере csv.Writer до первой отправки.
+		if err := cw.Write(csvHeader); err != nil {
+			h.logger.ErrorContext(r.Context(), "could not encode export", logging.Err(err))
+			return
+		}
+		encode = func(tx models.Transaction) error {
+			return cw.Write(csvRecord(tx))
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="transactions.csv"`)
+	default:
+		enc := json.NewEncoder(out)
+		encode = func(tx models.Transaction) error {
+			return enc.Encode(tx)
+		}
+		flush = func() error { return nil }
+	}
+	w.Header().Set("Content-Type", contentType)
+
+	rows := 0
+	send := func() error {
+		if err := flush(); err != nil {
+			return err
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	}
+
+	err := h.repo.StreamTransactions(r.Context(), userID, filter, func(tx models.Transaction) error {
+		if err := encode(tx); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			return send()
+		}
+		return nil
+	})
+	if err == nil {
+		err = send()
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "could not export transactions", slog.Int("rows", rows), logging.Err(err))
+		if !out.written {
+			w.Header().Del("Content-Disposition")
+			writeInternalError(w, r)
+		}
+	}
+}
+
+// trackingWriter запоминает, было ли что-то записано в ответ.
+type trackingWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	t.written = true
+	return t.w.Write(p)
+}
+
+func csvRecord(tx models.Transaction) []string {
+	return []string{
+		strconv.FormatInt(tx.ID, 10),
+		tx.TransactionID,
+		tx.UserID,
+		string(tx.TransactionType),
+		strconv.FormatInt(tx.Amount, 10),
+		tx.Currency,
+		tx.Timestamp.UTC().Format(time.RFC3339Nano),
+		tx.ReferenceTransactionID,
+		tx.RoundID,
+		tx.GameID,
+		tx.ProviderID,
+		tx.SessionID,
+	}
+}
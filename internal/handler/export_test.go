@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportContentType(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"application/json", ""},
+		{"text/csv", contentTypeCSV},
+		{"application/x-ndjson", contentTypeNDJSON},
+		{"text/csv;q=0.5, application/x-ndjson", contentTypeNDJSON},
+		{"application/json, text/csv;q=0.9", ""},
+		{"text/html, text/csv", contentTypeCSV},
+		{"*/*", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/transactions", nil)
+		req.Header.Set("Accept", tt.accept)
+		assert.Equal(t, tt.want, exportContentType(req), tt.accept)
+	}
+}
+
+func TestTransactionHandler_Export(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepository)
+	handler := NewTransactionHandler(mockRepo, nil)
+	router := chi.NewRouter()
+	router.Get("/transactions", handler.GetAllTransactions)
+	router.Get("/users/{userID}/transactions", handler.GetUserTransactions)
+
+	ts := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	txs := []models.Transaction{
+		{ID: 2, TransactionID: "tx-2", UserID: "user123", TransactionType: models.TransactionTypeWin, Amount: 500, Currency: "USD", Timestamp: ts, RoundID: "round-1"},
+		{ID: 1, TransactionID: "tx-1", UserID: "user123", TransactionType: models.TransactionTypeBet, Amount: 1000, Currency: "USD", Timestamp: ts.Add(-time.Minute), RoundID: "round-1"},
+	}
+
+	t.Run("should stream csv", func(t *testing.T) {
+		filter := repository.TransactionFilter{Types: []models.TransactionType{models.TransactionTypeBet, models.TransactionTypeWin}}
+		mockRepo.On("StreamTransactions", mock.Anything, "", filter).Return(txs, nil).Once()
+
+		req := httptest.NewRequest("GET", "/transactions?type=bet,win&limit=1", nil)
+		req.Header.Set("Accept", "text/csv")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, contentTypeCSV, rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), "transactions.csv")
+		lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, strings.Join(csvHeader, ","), lines[0])
+		assert.Equal(t, "2,tx-2,user123,win,500,USD,2025-06-01T12:00:00Z,,round-1,,,", lines[1])
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should stream ndjson with flushing", func(t *testing.T) {
+		many := make([]models.Transaction, exportFlushRows+1)
+		for i := range many {
+			many[i] = txs[i%2]
+		}
+		mockRepo.On("StreamTransactions", mock.Anything, "user123", repository.TransactionFilter{}).Return(many, nil).Once()
+
+		req := httptest.NewRequest("GET", "/users/user123/transactions", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, contentTypeNDJSON, rr.Header().Get("Content-Type"))
+		assert.True(t, rr.Flushed)
+
+		count := 0
+		scanner := bufio.NewScanner(rr.Body)
+		for scanner.Scan() {
+			var tx models.Transaction
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &tx))
+			count++
+		}
+		assert.Equal(t, exportFlushRows+1, count)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return internal error if nothing was sent", func(t *testing.T) {
+		mockRepo.On("StreamTransactions", mock.Anything, "user-error", repository.TransactionFilter{}).
+			Return([]models.Transaction{}, errors.New("database is down")).Once()
+
+		req := httptest.NewRequest("GET", "/users/user-error/transactions", nil)
+		req.Header.Set("Accept", "text/csv")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Empty(t, rr.Header().Get("Content-Disposition"))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject invalid filter before streaming", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/transactions?currency=usd", nil)
+		req.Header.Set("Accept", "text/csv")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/ingest"
+	"github.com/OlgaPie/casino-transaction-system/internal/limits"
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/validation"
+)
+
+const (
+	// MaxIngestBatch — наибольшее число транзакций в одном запросе.
+	MaxIngestBatch = 500
+	// maxIngestBodyBytes ограничивает размер тела запроса.
+	maxIngestBodyBytes = 1 << 20
+)
+
+// IngestHandler принимает транзакции через HTTP от провайдеров, которые не могут писать в Kafka.
+type IngestHandler struct {
+	sink            ingest.Sink
+	defaultCurrency string
+	logger          *slog.Logger
+}
+
+// NewIngestHandler создает обработчик; defaultCurrency подставляется в транзакции без currency,
+// как consumer.WithDefaultCurrency.
+func NewIngestHandler(sink ingest.Sink, defaultCurrency string, logger *slog.Logger) *IngestHandler {
+	return &IngestHandler{sink: sink, defaultCurrency: defaultCurrency, logger: logging.OrDiscard(logger)}
+}
+
+// ingestResult — статус одной принятой транзакции.
+type ingestResult struct {
+	TransactionID string        `json:"transaction_id"`
+	Status        ingest.Status `json:"status"`
+}
+
+type ingestBatchResponse struct {
+	Results []ingestResult `json:"results"`
+}
+
+// PostTransactions принимает одну транзакцию (JSON-объект) или пачку (JSON-массив).
+// Транзакции дополняются и проверяются по тем же правилам, что и сообщения из Kafka.
+// Ответ: 201, если сохранена хотя бы одна новая транзакция, 200, если все уже были сохранены,
+// и 202, если транзакции опубликованы в Kafka.
+func (h *IngestHandler) PostTransactions(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIngestBodyBytes)).Decode(&body); err != nil {
+		WriteProblem(w, r, http.StatusBadRequest, codeInvalidBody, errInvalidRequestBody.Error())
+		return
+	}
+
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+	var txs []models.Transaction
+	if batch {
+		if err := json.Unmarshal(body, &txs); err != nil {
+			WriteProblem(w, r, http.StatusBadRequest, codeInvalidBody, errInvalidRequestBody.Error())
+			return
+		}
+		if len(txs) == 0 || len(txs) > MaxIngestBatch {
+			WriteProblem(w, r, http.StatusBadRequest, codeInvalidBatchSize, fmt.Sprintf("batch must contain from 1 to %d transactions", MaxIngestBatch))
+			return
+		}
+	} else {
+		var tx models.Transaction
+		if err := json.Unmarshal(body, &tx); err != nil {
+			WriteProblem(w, r, http.StatusBadRequest, codeInvalidBody, errInvalidRequestBody.Error())
+			return
+		}
+		txs = []models.Transaction{tx}
+	}
+
+	now := time.Now()
+	var errs fieldErrors
+	for i := range txs {
+		tx, err := validation.Normalize(txs[i], h.defaultCurrency, now)
+		if err != nil {
+			field := "transaction"
+			if batch {
+				field = fmt.Sprintf("[%d]", i)
+			}
+			errs.add(field, err)
+			continue
+		}
+		txs[i] = tx
+	}
+	if err := errs.err(); err != nil {
+		writeInvalidRequest(w, r, codeInvalidTransaction, err)
+		return
+	}
+
+	statuses, err := h.sink.Submit(r.Context(), txs)
+	if err != nil {
+		h.writeSubmitError(w, r, err)
+		return
+	}
+
+	results := make([]ingestResult, len(txs))
+	code := http.StatusOK
+	for i, tx := range txs {
+		results[i] = ingestResult{TransactionID: tx.TransactionID, Status: statuses[i]}
+		switch statuses[i] {
+		case ingest.StatusCreated:
+			code = http.StatusCreated
+		case ingest.StatusAccepted:
+			code = http.StatusAccepted
+		}
+	}
+
+	var response any = ingestBatchResponse{Results: results}
+	if !batch {
+		response = results[0]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.ErrorContext(r.Context(), "could not encode response", logging.Err(err))
+	}
+}
+
+// writeSubmitError отвечает на ошибку приема: отклоненные транзакции — 422,
+// временные ошибки БД — 503, остальное — 500.
+func (h *IngestHandler) writeSubmitError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, limits.ErrLimitExceeded):
+		WriteProblem(w, r, http.StatusUnprocessableEntity, codeLimitExceeded, err.Error())
+	case repository.IsRejected(err):
+		WriteProblem(w, r, http.StatusUnprocessableEntity, codeRejected, err.Error())
+	case repository.IsTransient(err):
+		h.logger.WarnContext(r.Context(), "temporary error ingesting transactions", logging.Err(err))
+		WriteProblem(w, r, http.StatusServiceUnavailable, CodeUnavailable, "temporary error, retry the request")
+	default:
+		h.logger.ErrorContext(r.Context(), "could not ingest transactions", logging.Err(err))
+		writeInternalError(w, r)
+	}
+}
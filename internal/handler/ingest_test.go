@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/ingest"
+	"github.com/OlgaPie/casino-transaction-system/internal/limits"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSink запоминает принятые транзакции и возвращает заданные статусы или ошибку.
+type fakeSink struct {
+	submitted []models.Transaction
+	status    ingest.Status
+	err       error
+}
+
+func (s *fakeSink) Submit(_ context.Context, txs []models.Transaction) ([]ingest.Status, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.submitted = append(s.submitted, txs...)
+	statuses := make([]ingest.Status, len(txs))
+	for i := range statuses {
+		statuses[i] = s.status
+	}
+	return statuses, nil
+}
+
+func TestIngestHandler_PostTransactions(t *testing.T) {
+	post := func(sink *fakeSink, body string) *httptest.ResponseRecorder {
+		router := chi.NewRouter()
+		router.Post("/transactions", NewIngestHandler(sink, "EUR", nil).PostTransactions)
+		req := httptest.NewRequest("POST", "/transactions", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should create single transaction with defaults applied", func(t *testing.T) {
+		sink := &fakeSink{status: ingest.StatusCreated}
+		rr := post(sink, `{"user_id": "u1", "transaction_type": "bet", "amount": 100}`)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		require.Len(t, sink.submitted, 1)
+		assert.Equal(t, "EUR", sink.submitted[0].Currency)
+		assert.False(t, sink.submitted[0].Timestamp.IsZero())
+
+		var result ingestResult
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+		assert.Equal(t, sink.submitted[0].TransactionID, result.TransactionID)
+		assert.Equal(t, ingest.StatusCreated, result.Status)
+	})
+
+	t.Run("should return ok for duplicate", func(t *testing.T) {
+		rr := post(&fakeSink{status: ingest.StatusDuplicate}, `{"transaction_id": "tx-1", "user_id": "u1", "transaction_type": "win", "amount": 100, "currency": "USD"}`)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should accept batch published to kafka", func(t *testing.T) {
+		sink := &fakeSink{status: ingest.StatusAccepted}
+		rr := post(sink, `[{"transaction_id": "tx-1", "user_id": "u1", "transaction_type": "bet", "amount": 100},
+			{"transaction_id": "tx-2", "user_id": "u1", "transaction_type": "win", "amount": 300}]`)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		var response ingestBatchResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, "tx-2", response.Results[1].TransactionID)
+	})
+
+	t.Run("should reject invalid requests without submitting", func(t *testing.T) {
+		tooLarge := "[" + strings.Repeat(`{"user_id": "u1", "transaction_type": "bet", "amount": 1},`, MaxIngestBatch) + `{"user_id": "u1", "transaction_type": "bet", "amount": 1}]`
+		for name, body := range map[string]string{
+			"malformed json":       `{"user_id":`,
+			"empty batch":          `[]`,
+			"too large batch":      tooLarge,
+			"invalid type":         `{"user_id": "u1", "transaction_type": "bonus", "amount": 100}`,
+			"invalid batch item":   `[{"user_id": "u1", "transaction_type": "bet", "amount": 100}, {"user_id": "u1", "transaction_type": "bet", "amount": -1}]`,
+			"refund without a ref": `{"user_id": "u1", "transaction_type": "refund", "amount": 100}`,
+			"missing user":         `{"transaction_type": "bet", "amount": 100}`,
+			"invalid user":         `{"user_id": "u 1", "transaction_type": "bet", "amount": 100}`,
+		} {
+			sink := &fakeSink{status: ingest.StatusCreated}
+			rr := post(sink, body)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, name)
+			assert.Empty(t, sink.submitted, name)
+		}
+	})
+
+	t.Run("should map submit errors", func(t *testing.T) {
+		tests := []struct {
+			err  error
+			code int
+		}{
+			{&limits.ViolationError{Violation: models.LimitViolation{ViolationType: models.ViolationSelfExclusion}}, http.StatusUnprocessableEntity},
+			{fmt.Errorf("could not save transactions: %w", repository.ErrInsufficientFunds), http.StatusUnprocessableEntity},
+			{errors.New("database is down"), http.StatusInternalServerError},
+		}
+		for _, tt := range tests {
+			rr := post(&fakeSink{err: tt.err}, `{"user_id": "u1", "transaction_type": "bet", "amount": 100}`)
+			assert.Equal(t, tt.code, rr.Code, tt.err.Error())
+		}
+	})
+}
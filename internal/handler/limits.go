@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+)
+
+var (
+	errInvalidRequestBody   = errors.New("request body must be valid JSON")
+	errInvalidExcludedUntil = errors.New("excluded_until must be in the future")
+)
+
+// LimitHandler — административные эндпоинты лимитов ответственной игры.
+type LimitHandler struct {
+	repo   repository.LimitRepository
+	logger *slog.Logger
+}
+
+func NewLimitHandler(repo repository.LimitRepository, logger *slog.Logger) *LimitHandler {
+	return &LimitHandler{repo: repo, logger: logging.OrDiscard(logger)}
+}
+
+type setLimitsRequest struct {
+	Limits []models.Limit `json:"limits"`
+}
+
+type setSelfExclusionRequest struct {
+	ExcludedUntil time.Time `json:"excluded_until"`
+}
+
+func (h *LimitHandler) GetUserLimits(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserID(r)
+	if err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+	h.writeUserLimits(w, r, userID)
+}
+
+// SetUserLimits заменяет все лимиты пользователя переданными в теле запроса.
+// Пустой список снимает все лимиты.
+func (h *LimitHandler) SetUserLimits(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserID(r)
+	if err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	var req setLimitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteProblem(w, r, http.StatusBadRequest, codeInvalidBody, errInvalidRequestBody.Error())
+		return
+	}
+	if err := validateLimits(req.Limits); err != nil {
+		writeInvalidRequest(w, r, codeInvalidBody, err)
+		return
+	}
+
+	err = h.repo.SetLimits(r.Context(), userID, req.Limits)
+	if errors.Is(err, repository.ErrUnknownCurrency) {
+		WriteProblem(w, r, http.StatusBadRequest, codeUnknownCurrency, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "could not set limits", logging.UserID(userID), logging.Err(err))
+		writeInternalError(w, r)
+		return
+	}
+	h.writeUserLimits(w, r, userID)
+}
+
+// SetSelfExclusion исключает пользователя из игры до excluded_until.
+// Действующее самоисключение можно только продлить.
+func (h *LimitHandler) SetSelfExclusion(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserID(r)
+	if err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	var req setSelfExclusionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteProblem(w, r, http.StatusBadRequest, codeInvalidBody, errInvalidRequestBody.Error())
+		return
+	}
+	if !req.ExcludedUntil.After(time.Now()) {
+		writeInvalidRequest(w, r, codeInvalidBody, fieldErrors{{Field: "excluded_until", Message: errInvalidExcludedUntil.Error()}})
+		return
+	}
+
+	if err := h.repo.SetSelfExclusion(r.Context(), userID, req.ExcludedUntil); err != nil {
+		h.logger.ErrorContext(r.Context(), "could not set self-exclusion", logging.UserID(userID), logging.Err(err))
+		writeInternalError(w, r)
+		return
+	}
+	h.writeUserLimits(w, r, userID)
+}
+
+func (h *LimitHandler) writeUserLimits(w http.ResponseWriter, r *http.Request, userID string) {
+	limits, err := h.repo.GetUserLimits(r.Context(), userID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "could not fetch limits", logging.UserID(userID), logging.Err(err))
+		writeInternalError(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(limits); err != nil {
+		h.logger.ErrorContext(r.Context(), "could not encode response", logging.Err(err))
+	}
+}
+
+// validateLimits проверяет типы, периоды, валюты и суммы лимитов
+// и запрещает повторять лимит одного типа за один период в одной валюте.
+// Ошибки возвращаются как fieldErrors с индексом лимита в поле.
+func validateLimits(limits []models.Limit) error {
+	var errs fieldErrors
+	seen := make(map[models.Limit]bool, len(limits))
+	for i, l := range limits {
+		field := fmt.Sprintf("limits[%d]", i)
+		if !l.LimitType.Valid() {
+			errs.add(field+".limit_type", fmt.Errorf("invalid limit_type: %q", l.LimitType))
+		}
+		if !l.Period.Valid() {
+			errs.add(field+".period", fmt.Errorf("invalid period: %q", l.Period))
+		}
+		if !models.IsCurrencyCode(l.Currency) {
+			errs.add(field+".currency", errInvalidCurrency)
+		}
+		if l.Amount <= 0 {
+			errs.add(field+".amount", fmt.Errorf("invalid amount: %d", l.Amount))
+		}
+		key := models.Limit{LimitType: l.LimitType, Period: l.Period, Currency: l.Currency}
+		if seen[key] {
+			errs.add(field, fmt.Errorf("duplicate %s %s limit in %s", l.Period, l.LimitType, l.Currency))
+		}
+		seen[key] = true
+	}
+	return errs.err()
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLimitHandler(t *testing.T) {
+	mockRepo := new(mocks.LimitRepository)
+	handler := NewLimitHandler(mockRepo, nil)
+	router := chi.NewRouter()
+	router.Get("/admin/users/{userID}/limits", handler.GetUserLimits)
+	router.Put("/admin/users/{userID}/limits", handler.SetUserLimits)
+	router.Put("/admin/users/{userID}/self-exclusion", handler.SetSelfExclusion)
+
+	dailyLoss := models.Limit{LimitType: models.LimitTypeLoss, Period: models.LimitPeriodDay, Currency: "USD", Amount: 10000}
+
+	t.Run("successful retrieval of user limits", func(t *testing.T) {
+		until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		mockRepo.On("GetUserLimits", mock.Anything, "user123").Return(models.UserLimits{
+			UserID:        "user123",
+			Limits:        []models.Limit{dailyLoss},
+			SelfExclusion: &models.SelfExclusion{ExcludedUntil: until, CreatedAt: created},
+		}, nil).Once()
+
+		req := httptest.NewRequest("GET", "/admin/users/user123/limits", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"user_id":"user123",
+			"limits":[{"limit_type":"loss","period":"day","currency":"USD","amount":10000}],
+			"self_exclusion":{"excluded_until":"2030-01-01T00:00:00Z","created_at":"2025-01-01T00:00:00Z"}}`, rr.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should replace user limits", func(t *testing.T) {
+		mockRepo.On("SetLimits", mock.Anything, "user123", []models.Limit{dailyLoss}).Return(nil).Once()
+		mockRepo.On("GetUserLimits", mock.Anything, "user123").
+			Return(models.UserLimits{UserID: "user123", Limits: []models.Limit{dailyLoss}}, nil).Once()
+
+		body := `{"limits":[{"limit_type":"loss","period":"day","currency":"USD","amount":10000}]}`
+		req := httptest.NewRequest("PUT", "/admin/users/user123/limits", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return bad request for invalid limits", func(t *testing.T) {
+		for _, body := range []string{
+			`not a json`,
+			`{"limits":[{"limit_type":"deposit","period":"day","currency":"USD","amount":100}]}`,
+			`{"limits":[{"limit_type":"loss","period":"year","currency":"USD","amount":100}]}`,
+			`{"limits":[{"limit_type":"loss","period":"day","currency":"usd","amount":100}]}`,
+			`{"limits":[{"limit_type":"loss","period":"day","currency":"USD","amount":0}]}`,
+			`{"limits":[{"limit_type":"loss","period":"day","currency":"USD","amount":100},{"limit_type":"loss","period":"day","currency":"USD","amount":200}]}`,
+		} {
+			req := httptest.NewRequest("PUT", "/admin/users/user123/limits", strings.NewReader(body))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+		}
+	})
+
+	t.Run("should return bad request for unknown currency", func(t *testing.T) {
+		mockRepo.On("SetLimits", mock.Anything, "user123", mock.Anything).
+			Return(fmt.Errorf("could not set limits: %w", repository.ErrUnknownCurrency)).Once()
+
+		body := `{"limits":[{"limit_type":"wager","period":"week","currency":"XXX","amount":100}]}`
+		req := httptest.NewRequest("PUT", "/admin/users/user123/limits", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should set self-exclusion", func(t *testing.T) {
+		until := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+		mockRepo.On("SetSelfExclusion", mock.Anything, "user123", mock.MatchedBy(until.Equal)).Return(nil).Once()
+		mockRepo.On("GetUserLimits", mock.Anything, "user123").Return(models.UserLimits{UserID: "user123"}, nil).Once()
+
+		body := `{"excluded_until":"` + until.Format(time.RFC3339) + `"}`
+		req := httptest.NewRequest("PUT", "/admin/users/user123/self-exclusion", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject self-exclusion in the past", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/admin/users/user123/self-exclusion", strings.NewReader(`{"excluded_until":"2020-01-01T00:00:00Z"}`))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("repository returns an error for limits", func(t *testing.T) {
+		mockRepo.On("GetUserLimits", mock.Anything, "user-error").Return(models.UserLimits{}, errors.New("database is down")).Once()
+
+		req := httptest.NewRequest("GET", "/admin/users/user-error/limits", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+}
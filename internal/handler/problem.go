@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const contentTypeProblem = "application/problem+json"
+
+// Стабильные коды ошибок: клиенты различают ошибки по полю code, а не по тексту.
+const (
+	codeInvalidParameter    = "invalid_parameter"
+	codeInvalidBody         = "invalid_body"
+	codeInvalidBatchSize    = "invalid_batch_size"
+	codeInvalidTransaction  = "invalid_transaction"
+	codeUnauthorized        = "unauthorized"
+	codeForbidden           = "forbidden"
+	codeTransactionNotFound = "transaction_not_found"
+	codeRoundNotFound       = "round_not_found"
+	codeUnknownCurrency     = "unknown_currency"
+	codeLimitExceeded       = "limit_exceeded"
+	codeRejected            = "rejected"
+	// CodeUnavailable и CodeInternal используются и вне пакета, например в /ready.
+	CodeUnavailable = "unavailable"
+	CodeInternal    = "internal_error"
+)
+
+// problem — тело ответа об ошибке по RFC 7807 (application/problem+json)
+// с расширениями code, request_id и errors.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// RequestID совпадает с заголовком X-Request-Id и строкой в логе запроса.
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []fieldError `json:"errors,omitempty"`
+}
+
+// fieldError описывает ошибку в одном параметре запроса или поле тела.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// fieldErrors собирает ошибки всех параметров запроса, чтобы вернуть их клиенту разом.
+type fieldErrors []fieldError
+
+func (e fieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, f := range e {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *fieldErrors) add(field string, err error) {
+	*e = append(*e, fieldError{Field: field, Message: err.Error()})
+}
+
+// err возвращает nil, если ошибок нет; иначе сами ошибки.
+func (e fieldErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// WriteProblem отвечает ошибкой в формате application/problem+json.
+// Все обработчики API, включая служебные вне пакета, сообщают об ошибках только через него.
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string, fields ...fieldError) {
+	requestID := middleware.GetReqID(r.Context())
+	if requestID != "" {
+		w.Header().Set("X-Request-Id", requestID)
+	}
+	w.Header().Set("Content-Type", contentTypeProblem)
+	w.WriteHeader(status)
+
+	body := problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: requestID,
+		Errors:    fields,
+	}
+	// Статус уже отправлен: если тело не удалось записать, клиент просто получит его обрезанным.
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeInvalidRequest отвечает 400 на ошибку разбора запроса.
+// Ошибки отдельных параметров (fieldErrors) перечисляются в errors.
+func writeInvalidRequest(w http.ResponseWriter, r *http.Request, code string, err error) {
+	var fields fieldErrors
+	if errors.As(err, &fields) {
+		WriteProblem(w, r, http.StatusBadRequest, code, "request has invalid fields", fields...)
+		return
+	}
+	WriteProblem(w, r, http.StatusBadRequest, code, err.Error())
+}
+
+// writeInternalError отвечает 500, не раскрывая клиенту причину; она должна быть залогирована.
+func writeInternalError(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// Recoverer перехватывает панику обработчика, логирует ее со стеком и отвечает 500
+// в формате application/problem+json. http.ErrAbortHandler пробрасывается дальше,
+// чтобы сервер оборвал соединение.
+func Recoverer(logger *slog.Logger) func(http.Handler) http.Handler {
+	logger = logging.OrDiscard(logger)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+				logger.ErrorContext(r.Context(), "request handler panicked",
+					slog.Any("panic", rvr), slog.String("stack", string(debug.Stack())))
+				writeInternalError(w, r)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
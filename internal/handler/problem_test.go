@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteProblem(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Get("/invalid", func(w http.ResponseWriter, r *http.Request) {
+		var errs fieldErrors
+		errs.add("limit", errInvalidLimit)
+		writeInvalidRequest(w, r, codeInvalidParameter, errs.err())
+	})
+	router.Get("/plain", func(w http.ResponseWriter, r *http.Request) {
+		writeInvalidRequest(w, r, codeInvalidBody, errors.New("bad body"))
+	})
+
+	t.Run("should include request id and field errors", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/invalid", nil)
+		req.Header.Set(middleware.RequestIDHeader, "req-42")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, contentTypeProblem, rr.Header().Get("Content-Type"))
+		assert.Equal(t, "req-42", rr.Header().Get("X-Request-Id"))
+
+		var body problem
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, problem{
+			Type:      "about:blank",
+			Title:     "Bad Request",
+			Status:    http.StatusBadRequest,
+			Detail:    "request has invalid fields",
+			Instance:  "/invalid",
+			Code:      codeInvalidParameter,
+			RequestID: "req-42",
+			Errors:    []fieldError{{Field: "limit", Message: errInvalidLimit.Error()}},
+		}, body)
+	})
+
+	t.Run("should use error text as detail without field errors", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/plain", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var body problem
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "bad body", body.Detail)
+		assert.Equal(t, codeInvalidBody, body.Code)
+		assert.Empty(t, body.Errors)
+		assert.NotEmpty(t, body.RequestID)
+		assert.Equal(t, body.RequestID, rr.Header().Get("X-Request-Id"))
+	})
+}
+
+func TestRecoverer(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(Recoverer(nil))
+	router.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	router.Get("/abort", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	t.Run("should answer panic with internal error problem", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/panic", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, contentTypeProblem, rr.Header().Get("Content-Type"))
+		var body problem
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, CodeInternal, body.Code)
+		assert.NotContains(t, rr.Body.String(), "boom")
+	})
+
+	t.Run("should let aborted handler panic", func(t *testing.T) {
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+		})
+	})
+}
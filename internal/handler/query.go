@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	errInvalidUserID      = errors.New("user ID must be 1-255 letters, digits or -_.:@")
+	errUnknownParameter   = errors.New("unknown query parameter")
+	errInvalidLimit       = errors.New("limit must be a positive integer")
+	errInvalidCursor      = errors.New("cursor is invalid")
+	errInvalidCurrency    = errors.New("currency must be an ISO 4217 code")
+	errInvalidFrom        = errors.New("from must be an RFC 3339 timestamp")
+	errInvalidTo          = errors.New("to must be an RFC 3339 timestamp")
+	errInvalidTimeRange   = errors.New("from must be before to")
+	errInvalidMinAmount   = errors.New("min_amount must be an integer")
+	errInvalidMaxAmount   = errors.New("max_amount must be an integer")
+	errInvalidAmountRange = errors.New("min_amount must not exceed max_amount")
+)
+
+// Параметры запроса, которые принимают endpoint'ы; в строгом режиме остальные отклоняются.
+var (
+	pageParams      = []string{"limit", "cursor"}
+	timeRangeParams = []string{"from", "to"}
+	filterParams    = []string{
+		"type", "currency", "from", "to", "min_amount", "max_amount",
+		"transaction_id_prefix", "round_id", "game_id", "provider_id", "session_id",
+	}
+)
+
+// parseUserID читает userID из пути и проверяет его формат.
+func parseUserID(r *http.Request) (string, error) {
+	userID := chi.URLParam(r, "userID")
+	if !models.IsUserID(userID) {
+		return "", fieldErrors{{Field: "user_id", Message: errInvalidUserID.Error()}}
+	}
+	return userID, nil
+}
+
+// checkUnknownParams возвращает ошибку для каждого параметра запроса, не входящего в known,
+// в алфавитном порядке.
+func checkUnknownParams(query url.Values, known ...[]string) error {
+	allowed := make(map[string]bool)
+	for _, names := range known {
+		for _, name := range names {
+			allowed[name] = true
+		}
+	}
+
+	var unknown []string
+	for name := range query {
+		if !allowed[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+
+	var errs fieldErrors
+	for _, name := range unknown {
+		errs.add(name, errUnknownParameter)
+	}
+	return errs.err()
+}
+
+// parsePageRequest читает параметры limit и cursor.
+// Значения limit больше repository.MaxPageLimit урезаются в репозитории.
+// Ошибки возвращаются как fieldErrors.
+func parsePageRequest(r *http.Request) (repository.PageRequest, error) {
+	var page repository.PageRequest
+	var errs fieldErrors
+	query := r.URL.Query()
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			errs.add("limit", errInvalidLimit)
+		}
+		page.Limit = limit
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := repository.DecodeCursor(raw)
+		if err != nil {
+			errs.add("cursor", errInvalidCursor)
+		}
+		page.After = &cursor
+	}
+
+	return page, errs.err()
+}
+
+// parseTransactionFilter читает параметры фильтрации:
+// type и currency (можно повторять или перечислять через запятую), from, to,
+// min_amount, max_amount, transaction_id_prefix, round_id, game_id, provider_id и session_id.
+// Ошибки всех параметров возвращаются вместе как fieldErrors.
+func parseTransactionFilter(r *http.Request) (repository.TransactionFilter, error) {
+	var filter repository.TransactionFilter
+	var errs fieldErrors
+	query := r.URL.Query()
+
+	for _, value := range query["type"] {
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t == "" {
+				continue
+			}
+			if !models.TransactionType(t).Valid() {
+				errs.add("type", fmt.Errorf("unknown transaction type %q", t))
+				continue
+			}
+			filter.Types = append(filter.Types, models.TransactionType(t))
+		}
+	}
+
+	for _, value := range query["currency"] {
+		for _, c := range strings.Split(value, ",") {
+			if c = strings.TrimSpace(c); c == "" {
+				continue
+			}
+			if !models.IsCurrencyCode(c) {
+				errs.add("currency", errInvalidCurrency)
+				continue
+			}
+			filter.Currencies = append(filter.Currencies, c)
+		}
+	}
+
+	filter.From, filter.To = parseTimeRange(query, &errs)
+
+	var err error
+	if filter.MinAmount, err = parseAmountParam(query, "min_amount", errInvalidMinAmount); err != nil {
+		errs.add("min_amount", err)
+	}
+	if filter.MaxAmount, err = parseAmountParam(query, "max_amount", errInvalidMaxAmount); err != nil {
+		errs.add("max_amount", err)
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		errs.add("min_amount", errInvalidAmountRange)
+	}
+
+	filter.TransactionIDPrefix = query.Get("transaction_id_prefix")
+	filter.RoundID = query.Get("round_id")
+	filter.GameID = query.Get("game_id")
+	filter.ProviderID = query.Get("provider_id")
+	filter.SessionID = query.Get("session_id")
+
+	return filter, errs.err()
+}
+
+// parseTimeRange читает параметры from и to; from должен быть раньше to.
+// Ошибки добавляются в errs.
+func parseTimeRange(query url.Values, errs *fieldErrors) (*time.Time, *time.Time) {
+	from, err := parseTimeParam(query, "from", errInvalidFrom)
+	if err != nil {
+		errs.add("from", err)
+	}
+	to, err := parseTimeParam(query, "to", errInvalidTo)
+	if err != nil {
+		errs.add("to", err)
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		errs.add("from", errInvalidTimeRange)
+	}
+	return from, to
+}
+
+func parseTimeParam(query url.Values, name string, errInvalid error) (*time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, errInvalid
+	}
+	return &t, nil
+}
+
+func parseAmountParam(query url.Values, name string, errInvalid error) (*int64, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, errInvalid
+	}
+	return &amount, nil
+}
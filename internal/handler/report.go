@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/reporting"
+)
+
+var (
+	errMissingFrom = errors.New("from is required")
+	errMissingTo   = errors.New("to is required")
+)
+
+type ReportHandler struct {
+	reporter *reporting.Reporter
+	logger   *slog.Logger
+}
+
+func NewReportHandler(reporter *reporting.Reporter, logger *slog.Logger) *ReportHandler {
+	return &ReportHandler{reporter: reporter, logger: logging.OrDiscard(logger)}
+}
+
+// GetGGR возвращает отчет о GGR по валютам за период from–to
+// с шагом granularity (day по умолчанию или hour).
+func (h *ReportHandler) GetGGR(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var errs fieldErrors
+	from, to := parseTimeRange(query, &errs)
+	if from == nil && query.Get("from") == "" {
+		errs.add("from", errMissingFrom)
+	}
+	if to == nil && query.Get("to") == "" {
+		errs.add("to", errMissingTo)
+	}
+
+	granularity := models.GranularityDay
+	if raw := query.Get("granularity"); raw != "" {
+		granularity = models.Granularity(raw)
+	}
+	if !granularity.Valid() {
+		errs.add("granularity", reporting.ErrInvalidGranularity)
+	}
+	if err := errs.err(); err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	report, err := h.reporter.GGR(r.Context(), *from, *to, granularity)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "could not build GGR report", logging.Err(err))
+		writeInternalError(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.logger.ErrorContext(r.Context(), "could not encode response", logging.Err(err))
+	}
+}
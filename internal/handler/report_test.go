@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/reporting"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportHandler_GetGGR(t *testing.T) {
+	mockRepo := new(mocks.ReportRepository)
+	handler := NewReportHandler(reporting.NewReporter(mockRepo), nil)
+	router := chi.NewRouter()
+	router.Get("/reports/ggr", handler.GetGGR)
+
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should return hourly report", func(t *testing.T) {
+		rows := []models.GGRRow{
+			{PeriodStart: from.Add(time.Hour), Currency: "USD", GGRAmounts: models.GGRAmounts{TotalWagered: 1000, TotalWon: 400, GGR: 600, BetCount: 3, WinCount: 1}},
+		}
+		mockRepo.On("GetGGR", mock.Anything, models.GranularityHour, from, to).Return(rows, nil).Once()
+
+		req := httptest.NewRequest("GET", "/reports/ggr?from=2025-06-01T00:00:00Z&to=2025-06-02T00:00:00Z&granularity=hour", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var report models.GGRReport
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		assert.Equal(t, models.GranularityHour, report.Granularity)
+		require.Len(t, report.Rows, 1)
+		assert.Equal(t, int64(600), report.Rows[0].GGR)
+		require.Len(t, report.Totals, 1)
+		assert.Equal(t, int64(3), report.Totals[0].BetCount)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject invalid parameters", func(t *testing.T) {
+		for _, query := range []string{
+			"",
+			"?from=2025-06-01T00:00:00Z",
+			"?from=2025-06-02T00:00:00Z&to=2025-06-01T00:00:00Z",
+			"?from=2025-06-01T00:00:00Z&to=2025-06-02T00:00:00Z&granularity=week",
+		} {
+			req := httptest.NewRequest("GET", "/reports/ggr"+query, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		}
+	})
+
+	t.Run("repository returns an error for report", func(t *testing.T) {
+		mockRepo.On("GetGGR", mock.Anything, models.GranularityDay, from, to).Return([]models.GGRRow(nil), errors.New("database is down")).Once()
+
+		req := httptest.NewRequest("GET", "/reports/ggr?from=2025-06-01T00:00:00Z&to=2025-06-02T00:00:00Z", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -2,57 +2,214 @@ package handler
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
 	"github.com/OlgaPie/casino-transaction-system/internal/repository"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type TransactionHandler struct {
-	repo repository.TransactionRepository
+	repo   repository.TransactionRepository
+	strict bool
+	logger *slog.Logger
 }
 
-func NewTransactionHandler(repo repository.TransactionRepository) *TransactionHandler {
-	return &TransactionHandler{repo: repo}
+// TransactionHandlerOption настраивает необязательное поведение TransactionHandler.
+type TransactionHandlerOption func(*TransactionHandler)
+
+// WithStrictQuery включает строгий режим: параметры запроса, которых endpoint не знает,
+// отклоняются с 400, а не игнорируются. Так опечатка в имени фильтра не вернет все транзакции.
+func WithStrictQuery() TransactionHandlerOption {
+	return func(h *TransactionHandler) {
+		h.strict = true
+	}
+}
+
+func NewTransactionHandler(repo repository.TransactionRepository, logger *slog.Logger, opts ...TransactionHandlerOption) *TransactionHandler {
+	h := &TransactionHandler{repo: repo, logger: logging.OrDiscard(logger)}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// checkQuery в строгом режиме отклоняет параметры запроса, не входящие в known.
+func (h *TransactionHandler) checkQuery(r *http.Request, known ...[]string) error {
+	if !h.strict {
+		return nil
+	}
+	return checkUnknownParams(r.URL.Query(), known...)
 }
 
 func (h *TransactionHandler) GetUserTransactions(w http.ResponseWriter, r *http.Request) {
-	userID := chi.URLParam(r, "userID")
-	if userID == "" {
-		http.Error(w, "User ID is required", http.StatusBadRequest)
+	userID, err := parseUserID(r)
+	if err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+	if err := h.checkQuery(r, filterParams, pageParams); err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
 		return
 	}
 
-	txType := r.URL.Query().Get("type")
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	if contentType := exportContentType(r); contentType != "" {
+		h.exportTransactions(w, r, contentType, userID, filter)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
 
-	transactions, err := h.repo.GetTransactionsByUserID(r.Context(), userID, txType)
+	transactions, err := h.repo.GetTransactionsByUserID(r.Context(), userID, filter, page)
 	if err != nil {
-		log.Printf("Error fetching transactions for user %s: %v", userID, err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "could not fetch transactions", logging.UserID(userID), logging.Err(err))
+		writeInternalError(w, r)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(transactions); err != nil {
-		log.Printf("Error encoding response: %v", err)
+		h.logger.ErrorContext(r.Context(), "could not encode response", logging.Err(err))
 		// Если произошла ошибка здесь, скорее всего уже поздно отправлять HTTP ошибку.
 	}
 }
 
 func (h *TransactionHandler) GetAllTransactions(w http.ResponseWriter, r *http.Request) {
-	txType := r.URL.Query().Get("type")
+	if err := h.checkQuery(r, filterParams, pageParams); err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	if contentType := exportContentType(r); contentType != "" {
+		h.exportTransactions(w, r, contentType, "", filter)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
 
-	transactions, err := h.repo.GetAllTransactions(r.Context(), txType)
+	transactions, err := h.repo.GetAllTransactions(r.Context(), filter, page)
 	if err != nil {
-		log.Printf("Error fetching all transactions: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "could not fetch transactions", logging.Err(err))
+		writeInternalError(w, r)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(transactions); err != nil {
-		log.Printf("Error encoding response: %v", err)
+		h.logger.ErrorContext(r.Context(), "could not encode response", logging.Err(err))
+	}
+}
+
+// GetTransaction возвращает транзакцию по transaction_id вместе с возвратами и отменой ставки.
+func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	transactionID := chi.URLParam(r, "transactionID")
+	if transactionID == "" {
+		WriteProblem(w, r, http.StatusBadRequest, codeInvalidParameter, "transaction ID is required")
+		return
+	}
+	if err := h.checkQuery(r); err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	details, err := h.repo.GetTransactionByID(r.Context(), transactionID)
+	if errors.Is(err, repository.ErrTransactionNotFound) {
+		WriteProblem(w, r, http.StatusNotFound, codeTransactionNotFound, "transaction "+transactionID+" not found")
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "could not fetch transaction", slog.String(logging.KeyTransactionID, transactionID), logging.Err(err))
+		writeInternalError(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(details); err != nil {
+		h.logger.ErrorContext(r.Context(), "could not encode response", logging.Err(err))
+	}
+}
+
+// GetRound возвращает все транзакции раунда вместе с его итогом и статусом.
+func (h *TransactionHandler) GetRound(w http.ResponseWriter, r *http.Request) {
+	roundID := chi.URLParam(r, "roundID")
+	if roundID == "" {
+		WriteProblem(w, r, http.StatusBadRequest, codeInvalidParameter, "round ID is required")
+		return
+	}
+	if err := h.checkQuery(r); err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	round, err := h.repo.GetRound(r.Context(), roundID)
+	if errors.Is(err, repository.ErrRoundNotFound) {
+		WriteProblem(w, r, http.StatusNotFound, codeRoundNotFound, "round "+roundID+" not found")
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "could not fetch round", slog.String("round_id", roundID), logging.Err(err))
+		writeInternalError(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(round); err != nil {
+		h.logger.ErrorContext(r.Context(), "could not encode response", logging.Err(err))
+	}
+}
+
+// GetUserSummary возвращает статистику пользователя по каждой валюте,
+// при необходимости ограниченную параметрами from и to.
+func (h *TransactionHandler) GetUserSummary(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserID(r)
+	if err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+	if err := h.checkQuery(r, timeRangeParams); err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	var errs fieldErrors
+	from, to := parseTimeRange(r.URL.Query(), &errs)
+	if err := errs.err(); err != nil {
+		writeInvalidRequest(w, r, codeInvalidParameter, err)
+		return
+	}
+
+	summary, err := h.repo.GetUserSummary(r.Context(), userID, from, to)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "could not fetch summary", logging.UserID(userID), logging.Err(err))
+		writeInternalError(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		h.logger.ErrorContext(r.Context(), "could not encode response", logging.Err(err))
 	}
 }
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
 	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -18,7 +20,7 @@ import (
 
 func TestTransactionHandler_GetUserTransactions(t *testing.T) {
 	mockRepo := new(mocks.TransactionRepository)
-	handler := NewTransactionHandler(mockRepo)
+	handler := NewTransactionHandler(mockRepo, nil)
 	router := chi.NewRouter()
 	router.Get("/users/{userID}/transactions", handler.GetUserTransactions)
 
@@ -26,8 +28,8 @@ func TestTransactionHandler_GetUserTransactions(t *testing.T) {
 		expectedTxs := []models.Transaction{
 			{ID: 1, TransactionID: "test-handler-001", UserID: "user123", TransactionType: "bet", Amount: 1000, Timestamp: time.Now()},
 		}
-		mockRepo.On("GetTransactionsByUserID", mock.Anything, "user123", "").
-			Return(expectedTxs, nil).
+		mockRepo.On("GetTransactionsByUserID", mock.Anything, "user123", repository.TransactionFilter{}, repository.PageRequest{}).
+			Return(repository.TransactionPage{Transactions: expectedTxs}, nil).
 			Once()
 
 		req := httptest.NewRequest("GET", "/users/user123/transactions", nil)
@@ -36,11 +38,13 @@ func TestTransactionHandler_GetUserTransactions(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, rr.Code)
 
-		var returnedTxs []models.Transaction
-		err := json.Unmarshal(rr.Body.Bytes(), &returnedTxs)
+		var returnedPage repository.TransactionPage
+		err := json.Unmarshal(rr.Body.Bytes(), &returnedPage)
 		require.NoError(t, err)
 
+		returnedTxs := returnedPage.Transactions
 		require.Len(t, returnedTxs, 1)
+		assert.Empty(t, returnedPage.NextCursor)
 		assert.Equal(t, expectedTxs[0].ID, returnedTxs[0].ID)
 		assert.Equal(t, expectedTxs[0].UserID, returnedTxs[0].UserID)
 		assert.Equal(t, expectedTxs[0].TransactionType, returnedTxs[0].TransactionType)
@@ -50,9 +54,50 @@ func TestTransactionHandler_GetUserTransactions(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("should pass limit and cursor to repository", func(t *testing.T) {
+		cursor := repository.Cursor{Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), ID: 42}
+		expectedPage := repository.PageRequest{Limit: 10, After: &cursor}
+		mockRepo.On("GetTransactionsByUserID", mock.Anything, "user123", repository.TransactionFilter{Types: []models.TransactionType{models.TransactionTypeBet}}, expectedPage).
+			Return(repository.TransactionPage{Transactions: []models.Transaction{}, NextCursor: "next"}, nil).
+			Once()
+
+		req := httptest.NewRequest("GET", "/users/user123/transactions?type=bet&limit=10&cursor="+cursor.Encode(), nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"transactions":[],"next_cursor":"next"}`, rr.Body.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return bad request for invalid pagination parameters", func(t *testing.T) {
+		for _, query := range []string{"limit=abc", "limit=0", "limit=-5", "cursor=not-a-cursor"} {
+			req := httptest.NewRequest("GET", "/users/user123/transactions?"+query, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		}
+	})
+
+	t.Run("should report every invalid filter parameter", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/users/user123/transactions?type=jackpot&min_amount=abc", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var body problem
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, codeInvalidParameter, body.Code)
+		assert.Equal(t, []fieldError{
+			{Field: "type", Message: `unknown transaction type "jackpot"`},
+			{Field: "min_amount", Message: errInvalidMinAmount.Error()},
+		}, body.Errors)
+	})
+
 	t.Run("repository returns an error for user transactions", func(t *testing.T) {
-		mockRepo.On("GetTransactionsByUserID", mock.Anything, "user-error", "").
-			Return([]models.Transaction{}, errors.New("database is down")).
+		mockRepo.On("GetTransactionsByUserID", mock.Anything, "user-error", repository.TransactionFilter{}, repository.PageRequest{}).
+			Return(repository.TransactionPage{}, errors.New("database is down")).
 			Once()
 
 		req := httptest.NewRequest("GET", "/users/user-error/transactions", nil)
@@ -60,7 +105,8 @@ func TestTransactionHandler_GetUserTransactions(t *testing.T) {
 		router.ServeHTTP(rr, req)
 
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
-		assert.Contains(t, rr.Body.String(), "Internal Server Error")
+		assert.Equal(t, contentTypeProblem, rr.Header().Get("Content-Type"))
+		assert.NotContains(t, rr.Body.String(), "database is down")
 		mockRepo.AssertExpectations(t)
 	})
 
@@ -71,11 +117,79 @@ func TestTransactionHandler_GetUserTransactions(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
 	})
+
+	t.Run("should return bad request for malformed user id", func(t *testing.T) {
+		for _, userID := range []string{"user%20123", "user'--", strings.Repeat("u", models.MaxUserIDLength+1)} {
+			req := httptest.NewRequest("GET", "/users/"+userID+"/transactions", nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, userID)
+			var body problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, []fieldError{{Field: "user_id", Message: errInvalidUserID.Error()}}, body.Errors)
+		}
+	})
+}
+
+func TestTransactionHandler_StrictQuery(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepository)
+	router := chi.NewRouter()
+	router.Get("/transactions", NewTransactionHandler(mockRepo, nil, WithStrictQuery()).GetAllTransactions)
+	router.Get("/lenient/transactions", NewTransactionHandler(mockRepo, nil).GetAllTransactions)
+	router.Get("/users/{userID}/summary", NewTransactionHandler(mockRepo, nil, WithStrictQuery()).GetUserSummary)
+
+	t.Run("should reject unknown parameters", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/transactions?typ=bet&limit=10&sort=asc", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var body problem
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, []fieldError{
+			{Field: "sort", Message: errUnknownParameter.Error()},
+			{Field: "typ", Message: errUnknownParameter.Error()},
+		}, body.Errors)
+	})
+
+	t.Run("should reject parameters of another endpoint", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/users/user123/summary?type=bet", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should accept known parameters", func(t *testing.T) {
+		filter := repository.TransactionFilter{Types: []models.TransactionType{models.TransactionTypeBet}}
+		mockRepo.On("GetAllTransactions", mock.Anything, filter, repository.PageRequest{Limit: 10}).
+			Return(repository.TransactionPage{}, nil).Once()
+
+		req := httptest.NewRequest("GET", "/transactions?type=bet&limit=10", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should ignore unknown parameters without strict mode", func(t *testing.T) {
+		mockRepo.On("GetAllTransactions", mock.Anything, repository.TransactionFilter{}, repository.PageRequest{}).
+			Return(repository.TransactionPage{}, nil).Once()
+
+		req := httptest.NewRequest("GET", "/lenient/transactions?typ=bet", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
 }
 
 func TestTransactionHandler_GetAllTransactions(t *testing.T) {
 	mockRepo := new(mocks.TransactionRepository)
-	handler := NewTransactionHandler(mockRepo)
+	handler := NewTransactionHandler(mockRepo, nil)
 	router := chi.NewRouter()
 	router.Get("/transactions", handler.GetAllTransactions)
 
@@ -84,8 +198,8 @@ func TestTransactionHandler_GetAllTransactions(t *testing.T) {
 			{ID: 1, TransactionID: "test-handler-002", UserID: "user1", TransactionType: "bet", Amount: 1000, Timestamp: time.Now()},
 			{ID: 2, TransactionID: "test-handler-003", UserID: "user2", TransactionType: "win", Amount: 5000, Timestamp: time.Now()},
 		}
-		mockRepo.On("GetAllTransactions", mock.Anything, "").
-			Return(expectedTxs, nil).
+		mockRepo.On("GetAllTransactions", mock.Anything, repository.TransactionFilter{}, repository.PageRequest{}).
+			Return(repository.TransactionPage{Transactions: expectedTxs, NextCursor: "abc"}, nil).
 			Once()
 
 		req := httptest.NewRequest("GET", "/transactions", nil)
@@ -93,11 +207,13 @@ func TestTransactionHandler_GetAllTransactions(t *testing.T) {
 		router.ServeHTTP(rr, req)
 
 		assert.Equal(t, http.StatusOK, rr.Code)
-		var returnedTxs []models.Transaction
-		err := json.Unmarshal(rr.Body.Bytes(), &returnedTxs)
+		var returnedPage repository.TransactionPage
+		err := json.Unmarshal(rr.Body.Bytes(), &returnedPage)
 		require.NoError(t, err)
 
+		returnedTxs := returnedPage.Transactions
 		require.Len(t, returnedTxs, len(expectedTxs))
+		assert.Equal(t, "abc", returnedPage.NextCursor)
 		for i := range expectedTxs {
 			assert.Equal(t, expectedTxs[i].ID, returnedTxs[i].ID)
 			assert.Equal(t, expectedTxs[i].UserID, returnedTxs[i].UserID)
@@ -111,8 +227,8 @@ func TestTransactionHandler_GetAllTransactions(t *testing.T) {
 		expectedTxs := []models.Transaction{
 			{ID: 2, TransactionID: "test-handler-004", UserID: "user2", TransactionType: "win", Amount: 5000, Timestamp: time.Now()},
 		}
-		mockRepo.On("GetAllTransactions", mock.Anything, "win").
-			Return(expectedTxs, nil).
+		mockRepo.On("GetAllTransactions", mock.Anything, repository.TransactionFilter{Types: []models.TransactionType{models.TransactionTypeWin}}, repository.PageRequest{}).
+			Return(repository.TransactionPage{Transactions: expectedTxs}, nil).
 			Once()
 
 		req := httptest.NewRequest("GET", "/transactions?type=win", nil)
@@ -120,9 +236,10 @@ func TestTransactionHandler_GetAllTransactions(t *testing.T) {
 		router.ServeHTTP(rr, req)
 
 		assert.Equal(t, http.StatusOK, rr.Code)
-		var returnedTxs []models.Transaction
-		err := json.Unmarshal(rr.Body.Bytes(), &returnedTxs)
+		var returnedPage repository.TransactionPage
+		err := json.Unmarshal(rr.Body.Bytes(), &returnedPage)
 		require.NoError(t, err)
+		returnedTxs := returnedPage.Transactions
 		require.Len(t, returnedTxs, 1)
 		assert.Equal(t, expectedTxs[0].UserID, returnedTxs[0].UserID)
 		assert.True(t, expectedTxs[0].Timestamp.Truncate(time.Millisecond).Equal(returnedTxs[0].Timestamp.Truncate(time.Millisecond)))
@@ -130,9 +247,69 @@ func TestTransactionHandler_GetAllTransactions(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("should pass all filters to repository", func(t *testing.T) {
+		from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		minAmount, maxAmount := int64(100), int64(5000)
+		expectedFilter := repository.TransactionFilter{
+			Types:               []models.TransactionType{models.TransactionTypeBet, models.TransactionTypeWin},
+			Currencies:          []string{"EUR", "GBP"},
+			From:                &from,
+			To:                  &to,
+			MinAmount:           &minAmount,
+			MaxAmount:           &maxAmount,
+			TransactionIDPrefix: "tx-10",
+			GameID:              "slots-1",
+			ProviderID:          "prov-1",
+		}
+		mockRepo.On("GetAllTransactions", mock.Anything, expectedFilter, repository.PageRequest{}).
+			Return(repository.TransactionPage{Transactions: []models.Transaction{}}, nil).
+			Once()
+
+		req := httptest.NewRequest("GET", "/transactions?type=bet&type=win&currency=EUR,GBP&from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z&min_amount=100&max_amount=5000&transaction_id_prefix=tx-10&game_id=slots-1&provider_id=prov-1", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should accept comma separated types", func(t *testing.T) {
+		expectedFilter := repository.TransactionFilter{Types: []models.TransactionType{models.TransactionTypeBet, models.TransactionTypeWin}}
+		mockRepo.On("GetAllTransactions", mock.Anything, expectedFilter, repository.PageRequest{}).
+			Return(repository.TransactionPage{Transactions: []models.Transaction{}}, nil).
+			Once()
+
+		req := httptest.NewRequest("GET", "/transactions?type=bet,win", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return bad request for invalid filters", func(t *testing.T) {
+		for _, query := range []string{
+			"from=yesterday",
+			"to=2025-13-01T00:00:00Z",
+			"from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z",
+			"min_amount=1.5",
+			"max_amount=abc",
+			"min_amount=500&max_amount=100",
+			"currency=eur",
+			"currency=EURO",
+		} {
+			req := httptest.NewRequest("GET", "/transactions?"+query, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		}
+	})
+
 	t.Run("repository returns an error for all transactions", func(t *testing.T) {
-		mockRepo.On("GetAllTransactions", mock.Anything, "").
-			Return([]models.Transaction{}, errors.New("something went wrong")).
+		mockRepo.On("GetAllTransactions", mock.Anything, repository.TransactionFilter{}, repository.PageRequest{}).
+			Return(repository.TransactionPage{}, errors.New("something went wrong")).
 			Once()
 		req := httptest.NewRequest("GET", "/transactions", nil)
 		rr := httptest.NewRecorder()
@@ -142,3 +319,175 @@ func TestTransactionHandler_GetAllTransactions(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestTransactionHandler_GetRound(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepository)
+	handler := NewTransactionHandler(mockRepo, nil)
+	router := chi.NewRouter()
+	router.Get("/rounds/{roundID}", handler.GetRound)
+
+	t.Run("successful retrieval of round", func(t *testing.T) {
+		ts := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+		round := models.Round{
+			RoundID:   "round-1",
+			UserID:    "user123",
+			Currency:  "USD",
+			GameID:    "slots-1",
+			Status:    models.RoundStatusClosed,
+			TotalBet:  100,
+			TotalWin:  250,
+			NetResult: 150,
+			Transactions: []models.Transaction{
+				{ID: 1, TransactionID: "bet-1", UserID: "user123", TransactionType: "bet", Amount: 100, Currency: "USD", Timestamp: ts, RoundID: "round-1", GameID: "slots-1"},
+				{ID: 2, TransactionID: "win-1", UserID: "user123", TransactionType: "win", Amount: 250, Currency: "USD", Timestamp: ts, RoundID: "round-1", GameID: "slots-1"},
+			},
+		}
+		mockRepo.On("GetRound", mock.Anything, "round-1").Return(round, nil).Once()
+
+		req := httptest.NewRequest("GET", "/rounds/round-1", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var returned models.Round
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &returned))
+		assert.Equal(t, models.RoundStatusClosed, returned.Status)
+		assert.Equal(t, int64(150), returned.NetResult)
+		require.Len(t, returned.Transactions, 2)
+		assert.Equal(t, "round-1", returned.Transactions[1].RoundID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return not found for unknown round", func(t *testing.T) {
+		mockRepo.On("GetRound", mock.Anything, "missing").Return(models.Round{}, repository.ErrRoundNotFound).Once()
+
+		req := httptest.NewRequest("GET", "/rounds/missing", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("repository returns an error for round", func(t *testing.T) {
+		mockRepo.On("GetRound", mock.Anything, "round-error").Return(models.Round{}, errors.New("database is down")).Once()
+
+		req := httptest.NewRequest("GET", "/rounds/round-error", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTransactionHandler_GetUserSummary(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepository)
+	handler := NewTransactionHandler(mockRepo, nil)
+	router := chi.NewRouter()
+	router.Get("/users/{userID}/summary", handler.GetUserSummary)
+
+	t.Run("should pass date range to repository", func(t *testing.T) {
+		from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		summary := models.UserSummary{
+			UserID: "user123",
+			From:   &from,
+			To:     &to,
+			Currencies: []models.CurrencySummary{
+				{Currency: "USD", TotalWagered: 1000, TotalWon: 400, NetResult: 600, BetCount: 2, WinCount: 1, LargestWin: 400},
+			},
+		}
+		mockRepo.On("GetUserSummary", mock.Anything, "user123", &from, &to).Return(summary, nil).Once()
+
+		req := httptest.NewRequest("GET", "/users/user123/summary?from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var returned models.UserSummary
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &returned))
+		require.Len(t, returned.Currencies, 1)
+		assert.Equal(t, int64(600), returned.Currencies[0].NetResult)
+		assert.Equal(t, int64(400), returned.Currencies[0].LargestWin)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject inverted date range", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/users/user123/summary?from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), errInvalidTimeRange.Error())
+	})
+
+	t.Run("repository returns an error for summary", func(t *testing.T) {
+		mockRepo.On("GetUserSummary", mock.Anything, "user-error", (*time.Time)(nil), (*time.Time)(nil)).
+			Return(models.UserSummary{}, errors.New("database is down")).Once()
+
+		req := httptest.NewRequest("GET", "/users/user-error/summary", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTransactionHandler_GetTransaction(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepository)
+	handler := NewTransactionHandler(mockRepo, nil)
+	router := chi.NewRouter()
+	router.Get("/transactions/{transactionID}", handler.GetTransaction)
+
+	t.Run("should return transaction with reversals", func(t *testing.T) {
+		details := models.TransactionDetails{
+			Transaction: models.Transaction{ID: 1, TransactionID: "bet-1", UserID: "user123", TransactionType: models.TransactionTypeBet, Amount: 1000, Currency: "USD"},
+			Reversals: []models.Transaction{
+				{ID: 2, TransactionID: "refund-1", UserID: "user123", TransactionType: models.TransactionTypeRefund, Amount: 400, Currency: "USD", ReferenceTransactionID: "bet-1"},
+			},
+		}
+		mockRepo.On("GetTransactionByID", mock.Anything, "bet-1").Return(details, nil).Once()
+
+		req := httptest.NewRequest("GET", "/transactions/bet-1", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var returned models.TransactionDetails
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &returned))
+		assert.Equal(t, "bet-1", returned.TransactionID)
+		require.Len(t, returned.Reversals, 1)
+		assert.Equal(t, "refund-1", returned.Reversals[0].TransactionID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return structured not found error", func(t *testing.T) {
+		mockRepo.On("GetTransactionByID", mock.Anything, "missing").Return(models.TransactionDetails{}, repository.ErrTransactionNotFound).Once()
+
+		req := httptest.NewRequest("GET", "/transactions/missing", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, contentTypeProblem, rr.Header().Get("Content-Type"))
+		var body problem
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, codeTransactionNotFound, body.Code)
+		assert.Equal(t, http.StatusNotFound, body.Status)
+		assert.Equal(t, "/transactions/missing", body.Instance)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("repository returns an error for transaction", func(t *testing.T) {
+		mockRepo.On("GetTransactionByID", mock.Anything, "tx-error").Return(models.TransactionDetails{}, errors.New("database is down")).Once()
+
+		req := httptest.NewRequest("GET", "/transactions/tx-error", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockRepo.AssertExpectations(t)
+	})
+}
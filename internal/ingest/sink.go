@@ -0,0 +1,137 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/limits"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+	"github.com/OlgaPie/casino-transaction-system/internal/tracing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Status — результат приема одной транзакции.
+type Status string
+
+const (
+	// StatusCreated — транзакция сохранена впервые.
+	StatusCreated Status = "created"
+	// StatusDuplicate — транзакция с таким transaction_id уже была сохранена.
+	StatusDuplicate Status = "duplicate"
+	// StatusAccepted — транзакция опубликована в Kafka и будет сохранена consumer'ом;
+	// новая она или повторная, станет известно только при сохранении.
+	StatusAccepted Status = "accepted"
+)
+
+// Sink принимает проверенные транзакции, полученные через API.
+type Sink interface {
+	// Submit принимает пачку и возвращает статус каждой транзакции в порядке следования.
+	// При ошибке повтор запроса безопасен: транзакции идемпотентны по transaction_id.
+	Submit(ctx context.Context, txs []models.Transaction) ([]Status, error)
+}
+
+// RepositorySink сохраняет транзакции напрямую через репозиторий. Пачка сохраняется
+// в одной транзакции БД: либо вся, либо ни одной транзакции. Лимиты проверяет
+// репозиторий при сохранении (см. repository.WithSaveChecker), так же как для consumer'а.
+type RepositorySink struct {
+	repo       repository.TransactionRepository
+	violations limits.ViolationRecorder
+	events     MessageWriter
+}
+
+// NewRepositorySink создает Sink с прямой записью в БД. Нарушения лимитов сохраняются
+// через recorder и публикуются в events, как это делает consumer; nil отключает
+// соответственно журнал или события.
+func NewRepositorySink(repo repository.TransactionRepository, recorder limits.ViolationRecorder, events MessageWriter) *RepositorySink {
+	return &RepositorySink{repo: repo, violations: recorder, events: events}
+}
+
+// Submit возвращает *limits.ViolationError, если какая-либо транзакция нарушает лимиты;
+// нарушение при этом сохраняется в журнал и публикуется, а пачка отклоняется целиком.
+func (s *RepositorySink) Submit(ctx context.Context, txs []models.Transaction) ([]Status, error) {
+	createdIDs, err := s.repo.CreateTransactions(ctx, txs)
+	var violation *limits.ViolationError
+	if errors.As(err, &violation) {
+		if err := s.reportViolation(ctx, violation.Violation); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	created := make(map[string]bool, len(createdIDs))
+	for _, id := range createdIDs {
+		created[id] = true
+	}
+	statuses := make([]Status, len(txs))
+	for i, tx := range txs {
+		statuses[i] = StatusDuplicate
+		if created[tx.TransactionID] {
+			statuses[i] = StatusCreated
+			// Повтор transaction_id внутри пачки вставлен только один раз.
+			delete(created, tx.TransactionID)
+		}
+	}
+	return statuses, nil
+}
+
+// reportViolation сохраняет нарушение в журнал и публикует событие о нем.
+// При ошибке повтор запроса снова найдет нарушение и повторит обе записи.
+func (s *RepositorySink) reportViolation(ctx context.Context, violation models.LimitViolation) error {
+	if s.violations != nil {
+		if err := s.violations.RecordViolation(ctx, violation); err != nil {
+			return fmt.Errorf("could not record limit violation: %w", err)
+		}
+	}
+	if s.events != nil {
+		event, err := limits.ViolationEvent(violation)
+		if err != nil {
+			return err
+		}
+		if err := s.events.WriteMessages(ctx, event); err != nil {
+			return fmt.Errorf("could not publish limit violation event: %w", err)
+		}
+	}
+	return nil
+}
+
+// MessageWriter публикует сообщения в Kafka. *kafka.Writer удовлетворяет этому интерфейсу.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// KafkaSink публикует транзакции в topic, который читает consumer.
+// Сообщения получают ключ user_id, чтобы транзакции пользователя попадали в одну партицию.
+// Контекст трассировки запроса передается в заголовках сообщений.
+// При ошибке часть пачки может быть уже опубликована.
+type KafkaSink struct {
+	writer MessageWriter
+}
+
+func NewKafkaSink(writer MessageWriter) *KafkaSink {
+	return &KafkaSink{writer: writer}
+}
+
+func (s *KafkaSink) Submit(ctx context.Context, txs []models.Transaction) ([]Status, error) {
+	msgs := make([]kafka.Message, len(txs))
+	statuses := make([]Status, len(txs))
+	for i, tx := range txs {
+		value, err := json.Marshal(tx)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode transaction %s: %w", tx.TransactionID, err)
+		}
+		msgs[i] = kafka.Message{Key: []byte(tx.UserID), Value: value}
+		tracing.Inject(ctx, &msgs[i])
+		statuses[i] = StatusAccepted
+	}
+
+	if err := s.writer.WriteMessages(ctx, msgs...); err != nil {
+		return nil, fmt.Errorf("could not publish transactions: %w", err)
+	}
+	return statuses, nil
+}
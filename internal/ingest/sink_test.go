@@ -0,0 +1,145 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/limits"
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/OlgaPie/casino-transaction-system/internal/tracing"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeViolationRecorder запоминает сохраненные нарушения.
+type fakeViolationRecorder struct {
+	recorded []models.LimitViolation
+}
+
+func (r *fakeViolationRecorder) RecordViolation(_ context.Context, v models.LimitViolation) error {
+	r.recorded = append(r.recorded, v)
+	return nil
+}
+
+// fakeMessageWriter хранит опубликованные сообщения в памяти.
+type fakeMessageWriter struct {
+	messages []kafka.Message
+	err      error
+}
+
+func (w *fakeMessageWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func TestRepositorySink_Submit(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	txs := []models.Transaction{
+		{TransactionID: "s-1", UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD", Timestamp: ts},
+		{TransactionID: "s-2", UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: 200, Currency: "USD", Timestamp: ts},
+		{TransactionID: "s-1", UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD", Timestamp: ts},
+	}
+
+	t.Run("should report created and duplicate transactions", func(t *testing.T) {
+		repo := new(mocks.TransactionRepository)
+		repo.On("CreateTransactions", ctx, txs).Return([]string{"s-1"}, nil).Once()
+		recorder := &fakeViolationRecorder{}
+
+		statuses, err := NewRepositorySink(repo, recorder, nil).Submit(ctx, txs)
+		require.NoError(t, err)
+		assert.Equal(t, []Status{StatusCreated, StatusDuplicate, StatusDuplicate}, statuses)
+		assert.Empty(t, recorder.recorded)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should record violation that rejected the batch", func(t *testing.T) {
+		repo := new(mocks.TransactionRepository)
+		violation := &limits.ViolationError{Violation: models.LimitViolation{TransactionID: "s-2", UserID: "u1", ViolationType: models.ViolationSelfExclusion}}
+		repo.On("CreateTransactions", ctx, txs).Return([]string(nil), fmt.Errorf("could not save transactions: %w", violation)).Once()
+		recorder := &fakeViolationRecorder{}
+		events := &fakeMessageWriter{}
+
+		_, err := NewRepositorySink(repo, recorder, events).Submit(ctx, txs)
+		require.ErrorIs(t, err, limits.ErrLimitExceeded)
+		require.Len(t, recorder.recorded, 1)
+		assert.Equal(t, "s-2", recorder.recorded[0].TransactionID)
+		require.Len(t, events.messages, 1)
+		assert.Equal(t, "u1", string(events.messages[0].Key))
+		var published models.LimitViolation
+		require.NoError(t, json.Unmarshal(events.messages[0].Value, &published))
+		assert.Equal(t, "s-2", published.TransactionID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should fail when violation event cannot be published", func(t *testing.T) {
+		repo := new(mocks.TransactionRepository)
+		violation := &limits.ViolationError{Violation: models.LimitViolation{TransactionID: "s-2", UserID: "u1", ViolationType: models.ViolationSelfExclusion}}
+		repo.On("CreateTransactions", ctx, txs).Return([]string(nil), fmt.Errorf("could not save transactions: %w", violation)).Once()
+
+		_, err := NewRepositorySink(repo, &fakeViolationRecorder{}, &fakeMessageWriter{err: errors.New("broker unavailable")}).Submit(ctx, txs)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, limits.ErrLimitExceeded)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should return repository error", func(t *testing.T) {
+		repo := new(mocks.TransactionRepository)
+		repo.On("CreateTransactions", ctx, txs[:1]).Return([]string(nil), errors.New("database is down")).Once()
+
+		_, err := NewRepositorySink(repo, nil, nil).Submit(ctx, txs[:1])
+		assert.Error(t, err)
+	})
+}
+
+func TestKafkaSink_Submit(t *testing.T) {
+	ctx := context.Background()
+	txs := []models.Transaction{
+		{TransactionID: "k-1", UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD"},
+		{TransactionID: "k-2", UserID: "u2", TransactionType: models.TransactionTypeDeposit, Amount: 500, Currency: "EUR"},
+	}
+
+	t.Run("should publish transactions keyed by user", func(t *testing.T) {
+		writer := &fakeMessageWriter{}
+
+		statuses, err := NewKafkaSink(writer).Submit(ctx, txs)
+		require.NoError(t, err)
+		assert.Equal(t, []Status{StatusAccepted, StatusAccepted}, statuses)
+		require.Len(t, writer.messages, 2)
+		assert.Equal(t, "u2", string(writer.messages[1].Key))
+
+		var published models.Transaction
+		require.NoError(t, json.Unmarshal(writer.messages[1].Value, &published))
+		assert.Equal(t, txs[1], published)
+	})
+
+	t.Run("should propagate trace context in headers", func(t *testing.T) {
+		writer := &fakeMessageWriter{}
+		tp := sdktrace.NewTracerProvider()
+		spanCtx, span := tp.Tracer("test").Start(ctx, "POST /transactions")
+		defer span.End()
+
+		_, err := NewKafkaSink(writer).Submit(spanCtx, txs)
+		require.NoError(t, err)
+		for _, msg := range writer.messages {
+			extracted := trace.SpanContextFromContext(tracing.Extract(ctx, msg))
+			assert.Equal(t, span.SpanContext().TraceID(), extracted.TraceID())
+		}
+	})
+
+	t.Run("should return publish error", func(t *testing.T) {
+		_, err := NewKafkaSink(&fakeMessageWriter{err: errors.New("broker unavailable")}).Submit(ctx, txs)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,140 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+)
+
+// ErrLimitExceeded возвращается (через ViolationError), если транзакция нарушает
+// лимит ответственной игры или самоисключение пользователя.
+var ErrLimitExceeded = errors.New("responsible gambling limit exceeded")
+
+// ViolationError описывает нарушение; errors.Is(err, ErrLimitExceeded) для нее истинно.
+type ViolationError struct {
+	Violation models.LimitViolation
+}
+
+func (e *ViolationError) Error() string {
+	v := e.Violation
+	if v.ViolationType == models.ViolationSelfExclusion {
+		return fmt.Sprintf("%v: user %s is self-excluded", ErrLimitExceeded, v.UserID)
+	}
+	return fmt.Sprintf("%v: %s %s of %d %s, current %d, attempted %d",
+		ErrLimitExceeded, v.Period, v.ViolationType, v.LimitAmount, v.Currency, v.CurrentAmount, v.AttemptedAmount)
+}
+
+func (e *ViolationError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// ViolationRecorder сохраняет нарушения лимитов, которые репозиторий транзакций
+// возвращает при сохранении. *Checker удовлетворяет этому интерфейсу.
+type ViolationRecorder interface {
+	RecordViolation(ctx context.Context, violation models.LimitViolation) error
+}
+
+// Checker проверяет транзакции против лимитов и самоисключения пользователя
+// до их сохранения. Самоисключение запрещает ставки и депозиты,
+// лимиты на проигрыш и сумму ставок применяются только к ставкам.
+// Для проверки при сохранении он передается в repository.WithSaveChecker.
+type Checker struct {
+	repo repository.LimitRepository
+}
+
+func NewChecker(repo repository.LimitRepository) *Checker {
+	return &Checker{repo: repo}
+}
+
+// Check возвращает *ViolationError, если tx нарушает ограничения пользователя.
+// pending — еще не сохраненные транзакции, которые будут сохранены раньше tx
+// (предыдущие транзакции той же пачки); они учитываются в суммах за период.
+// Check не атомарна с сохранением; при сохранении транзакции проверяются через CheckSave.
+func (c *Checker) Check(ctx context.Context, tx models.Transaction, pending []models.Transaction) error {
+	return check(ctx, c.repo, tx, pending)
+}
+
+// CheckSave проверяет tx так же, как Check, но читает данные через repo —
+// в транзакции БД, в которой tx сохраняется (repository.SaveChecker).
+func (c *Checker) CheckSave(ctx context.Context, repo repository.LimitRepository, tx models.Transaction, pending []models.Transaction) error {
+	return check(ctx, repo, tx, pending)
+}
+
+func check(ctx context.Context, repo repository.LimitRepository, tx models.Transaction, pending []models.Transaction) error {
+	if tx.TransactionType != models.TransactionTypeBet && tx.TransactionType != models.TransactionTypeDeposit {
+		return nil
+	}
+
+	userLimits, err := repo.GetUserLimits(ctx, tx.UserID)
+	if err != nil {
+		return err
+	}
+	excluded := userLimits.ExcludedAt(tx.Timestamp)
+	if !excluded && (tx.TransactionType != models.TransactionTypeBet || len(userLimits.Limits) == 0) {
+		return nil
+	}
+
+	// Повторно доставленная транзакция уже прошла проверку при первой обработке.
+	exists, err := repo.TransactionExists(ctx, tx.TransactionID)
+	if err != nil || exists {
+		return err
+	}
+
+	if excluded {
+		return violation(tx, models.ViolationSelfExclusion, "", 0, 0)
+	}
+
+	totals := make(map[models.LimitPeriod]models.PlayTotals)
+	for _, limit := range userLimits.Limits {
+		if limit.Currency != tx.Currency {
+			continue
+		}
+
+		periodTotals, ok := totals[limit.Period]
+		if !ok {
+			from, to := limit.Period.Bounds(tx.Timestamp)
+			periodTotals, err = repo.GetPlayTotals(ctx, tx.UserID, tx.Currency, from, to)
+			if err != nil {
+				return err
+			}
+			for _, p := range pending {
+				if p.UserID == tx.UserID && p.Currency == tx.Currency && !p.Timestamp.Before(from) && p.Timestamp.Before(to) {
+					periodTotals.Add(p)
+				}
+			}
+			totals[limit.Period] = periodTotals
+		}
+
+		current, violationType := periodTotals.Wagered(), models.ViolationWagerLimit
+		if limit.LimitType == models.LimitTypeLoss {
+			current, violationType = periodTotals.Loss(), models.ViolationLossLimit
+		}
+		if current+tx.Amount > limit.Amount {
+			return violation(tx, violationType, limit.Period, limit.Amount, current)
+		}
+	}
+	return nil
+}
+
+// RecordViolation сохраняет нарушение в журнал.
+func (c *Checker) RecordViolation(ctx context.Context, v models.LimitViolation) error {
+	return c.repo.SaveLimitViolation(ctx, v)
+}
+
+func violation(tx models.Transaction, violationType models.ViolationType, period models.LimitPeriod, limit, current int64) *ViolationError {
+	return &ViolationError{Violation: models.LimitViolation{
+		UserID:          tx.UserID,
+		TransactionID:   tx.TransactionID,
+		ViolationType:   violationType,
+		Period:          period,
+		Currency:        tx.Currency,
+		LimitAmount:     limit,
+		CurrentAmount:   current,
+		AttemptedAmount: tx.Amount,
+		OccurredAt:      time.Now().UTC(),
+	}}
+}
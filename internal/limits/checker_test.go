@@ -0,0 +1,187 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Check(t *testing.T) {
+	ctx := context.Background()
+	// Среда, 11 июня 2025
+	ts := time.Date(2025, 6, 11, 15, 30, 0, 0, time.UTC)
+	dayStart, dayEnd := time.Date(2025, 6, 11, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 12, 0, 0, 0, 0, time.UTC)
+	weekStart, weekEnd := time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC)
+
+	bet := models.Transaction{TransactionID: "bet-1", UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: 1000, Currency: "USD", Timestamp: ts}
+
+	t.Run("should allow bet for user without limits", func(t *testing.T) {
+		repo := new(mocks.LimitRepository)
+		repo.On("GetUserLimits", ctx, "u1").Return(models.UserLimits{UserID: "u1"}, nil).Once()
+
+		require.NoError(t, NewChecker(repo).Check(ctx, bet, nil))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should not check wins", func(t *testing.T) {
+		repo := new(mocks.LimitRepository)
+		win := bet
+		win.TransactionType = models.TransactionTypeWin
+
+		require.NoError(t, NewChecker(repo).Check(ctx, win, nil))
+		repo.AssertNotCalled(t, "GetUserLimits", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject deposit of self-excluded user", func(t *testing.T) {
+		repo := new(mocks.LimitRepository)
+		repo.On("GetUserLimits", ctx, "u1").Return(models.UserLimits{
+			UserID:        "u1",
+			SelfExclusion: &models.SelfExclusion{ExcludedUntil: ts.Add(time.Hour)},
+		}, nil).Once()
+		repo.On("TransactionExists", ctx, "dep-1").Return(false, nil).Once()
+		deposit := models.Transaction{TransactionID: "dep-1", UserID: "u1", TransactionType: models.TransactionTypeDeposit, Amount: 500, Currency: "USD", Timestamp: ts}
+
+		err := NewChecker(repo).Check(ctx, deposit, nil)
+
+		require.ErrorIs(t, err, ErrLimitExceeded)
+		var violation *ViolationError
+		require.ErrorAs(t, err, &violation)
+		assert.Equal(t, models.ViolationSelfExclusion, violation.Violation.ViolationType)
+		assert.Equal(t, "dep-1", violation.Violation.TransactionID)
+		assert.Equal(t, int64(500), violation.Violation.AttemptedAmount)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should allow bet after self-exclusion has expired", func(t *testing.T) {
+		repo := new(mocks.LimitRepository)
+		repo.On("GetUserLimits", ctx, "u1").Return(models.UserLimits{
+			UserID:        "u1",
+			SelfExclusion: &models.SelfExclusion{ExcludedUntil: ts.Add(-time.Hour)},
+		}, nil).Once()
+
+		require.NoError(t, NewChecker(repo).Check(ctx, bet, nil))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should skip redelivered transaction", func(t *testing.T) {
+		repo := new(mocks.LimitRepository)
+		repo.On("GetUserLimits", ctx, "u1").Return(models.UserLimits{
+			UserID: "u1",
+			Limits: []models.Limit{{LimitType: models.LimitTypeWager, Period: models.LimitPeriodDay, Currency: "USD", Amount: 1}},
+		}, nil).Once()
+		repo.On("TransactionExists", ctx, "bet-1").Return(true, nil).Once()
+
+		require.NoError(t, NewChecker(repo).Check(ctx, bet, nil))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should reject bet exceeding daily wager limit", func(t *testing.T) {
+		repo := new(mocks.LimitRepository)
+		repo.On("GetUserLimits", ctx, "u1").Return(models.UserLimits{
+			UserID: "u1",
+			Limits: []models.Limit{
+				{LimitType: models.LimitTypeWager, Period: models.LimitPeriodDay, Currency: "USD", Amount: 5000},
+				{LimitType: models.LimitTypeWager, Period: models.LimitPeriodDay, Currency: "EUR", Amount: 1},
+			},
+		}, nil).Once()
+		repo.On("TransactionExists", ctx, "bet-1").Return(false, nil).Once()
+		repo.On("GetPlayTotals", ctx, "u1", "USD", dayStart, dayEnd).
+			Return(models.PlayTotals{Bets: 5000, Reversals: 500}, nil).Once()
+
+		err := NewChecker(repo).Check(ctx, bet, nil)
+
+		var violation *ViolationError
+		require.ErrorAs(t, err, &violation)
+		assert.Equal(t, models.ViolationWagerLimit, violation.Violation.ViolationType)
+		assert.Equal(t, models.LimitPeriodDay, violation.Violation.Period)
+		assert.Equal(t, int64(5000), violation.Violation.LimitAmount)
+		assert.Equal(t, int64(4500), violation.Violation.CurrentAmount)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should net wins against weekly loss limit", func(t *testing.T) {
+		repo := new(mocks.LimitRepository)
+		repo.On("GetUserLimits", ctx, "u1").Return(models.UserLimits{
+			UserID: "u1",
+			Limits: []models.Limit{{LimitType: models.LimitTypeLoss, Period: models.LimitPeriodWeek, Currency: "USD", Amount: 2000}},
+		}, nil).Once()
+		repo.On("TransactionExists", ctx, "bet-1").Return(false, nil).Once()
+		repo.On("GetPlayTotals", ctx, "u1", "USD", weekStart, weekEnd).
+			Return(models.PlayTotals{Bets: 10000, Wins: 9000}, nil).Once()
+
+		require.NoError(t, NewChecker(repo).Check(ctx, bet, nil))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should include pending batch transactions in totals", func(t *testing.T) {
+		repo := new(mocks.LimitRepository)
+		repo.On("GetUserLimits", ctx, "u1").Return(models.UserLimits{
+			UserID: "u1",
+			Limits: []models.Limit{{LimitType: models.LimitTypeLoss, Period: models.LimitPeriodDay, Currency: "USD", Amount: 2000}},
+		}, nil).Once()
+		repo.On("TransactionExists", ctx, "bet-1").Return(false, nil).Once()
+		repo.On("GetPlayTotals", ctx, "u1", "USD", dayStart, dayEnd).Return(models.PlayTotals{}, nil).Once()
+		pending := []models.Transaction{
+			{TransactionID: "bet-0", UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: 1500, Currency: "USD", Timestamp: ts},
+			// Другой пользователь и другая валюта не учитываются
+			{TransactionID: "bet-x", UserID: "u2", TransactionType: models.TransactionTypeBet, Amount: 1500, Currency: "USD", Timestamp: ts},
+			{TransactionID: "bet-y", UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: 1500, Currency: "EUR", Timestamp: ts},
+		}
+
+		err := NewChecker(repo).Check(ctx, bet, pending)
+
+		var violation *ViolationError
+		require.ErrorAs(t, err, &violation)
+		assert.Equal(t, models.ViolationLossLimit, violation.Violation.ViolationType)
+		assert.Equal(t, int64(1500), violation.Violation.CurrentAmount)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should read through repository of the save transaction", func(t *testing.T) {
+		own := new(mocks.LimitRepository)
+		inTx := new(mocks.LimitRepository)
+		inTx.On("GetUserLimits", ctx, "u1").Return(models.UserLimits{UserID: "u1"}, nil).Once()
+
+		require.NoError(t, NewChecker(own).CheckSave(ctx, inTx, bet, nil))
+		inTx.AssertExpectations(t)
+		own.AssertNotCalled(t, "GetUserLimits", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return repository errors", func(t *testing.T) {
+		repo := new(mocks.LimitRepository)
+		repo.On("GetUserLimits", ctx, "u1").Return(models.UserLimits{}, errors.New("database is down")).Once()
+
+		err := NewChecker(repo).Check(ctx, bet, nil)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrLimitExceeded)
+	})
+}
+
+func TestLimitPeriod_Bounds(t *testing.T) {
+	// Воскресенье, 1 июня 2025: неделя начинается в понедельник 26 мая
+	ts := time.Date(2025, 6, 1, 23, 59, 0, 0, time.UTC)
+
+	tests := []struct {
+		period     models.LimitPeriod
+		start, end time.Time
+	}{
+		{models.LimitPeriodDay, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)},
+		{models.LimitPeriodWeek, time.Date(2025, 5, 26, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)},
+		{models.LimitPeriodMonth, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.period), func(t *testing.T) {
+			start, end := tt.period.Bounds(ts)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.end, end)
+		})
+	}
+}
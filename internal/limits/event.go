@@ -0,0 +1,20 @@
+package limits
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ViolationEvent возвращает событие о нарушении для topic'а нарушений лимитов:
+// нарушение в JSON с ключом user_id, чтобы события пользователя шли по порядку.
+func ViolationEvent(violation models.LimitViolation) (kafka.Message, error) {
+	value, err := json.Marshal(violation)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("could not encode limit violation event: %w", err)
+	}
+	return kafka.Message{Key: []byte(violation.UserID), Value: value}, nil
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type requestAttrsKey struct{}
+
+// requestAttrs — атрибуты записи о запросе, которые добавляют middleware и обработчики
+// внутри Middleware. Их контексты производные, поэтому атрибуты передаются через общий указатель.
+type requestAttrs struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// AddRequestAttrs добавляет attrs к записи Middleware о завершении запроса с контекстом ctx.
+// Вне Middleware ничего не делает.
+func AddRequestAttrs(ctx context.Context, attrs ...slog.Attr) {
+	holder, ok := ctx.Value(requestAttrsKey{}).(*requestAttrs)
+	if !ok {
+		return
+	}
+	holder.mu.Lock()
+	defer holder.mu.Unlock()
+	holder.attrs = append(holder.attrs, attrs...)
+}
+
+// Middleware пишет запись о каждом обработанном HTTP-запросе. Должен стоять после
+// middleware.RequestID, чтобы запись получила request_id. Строка запроса не логируется.
+// Запись получает user_id из параметра маршрута {userID} и атрибуты из AddRequestAttrs,
+// например subject клиента.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	logger = OrDiscard(logger)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			holder := &requestAttrs{}
+			r = r.WithContext(context.WithValue(r.Context(), requestAttrsKey{}, holder))
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote_addr", r.RemoteAddr),
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					attrs = append(attrs, slog.String("route", pattern))
+				}
+				if userID := rctx.URLParam("userID"); userID != "" {
+					attrs = append(attrs, UserID(userID))
+				}
+			}
+			holder.mu.Lock()
+			attrs = append(attrs, holder.attrs...)
+			holder.mu.Unlock()
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(r.Context(), level, "request completed", attrs...)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// WithMessage возвращает ctx, записи с которым описывают положение msg в Kafka.
+func WithMessage(ctx context.Context, msg kafka.Message) context.Context {
+	return With(ctx,
+		slog.String(KeyTopic, msg.Topic),
+		slog.Int(KeyPartition, msg.Partition),
+		slog.Int64(KeyOffset, msg.Offset),
+	)
+}
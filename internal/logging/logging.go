@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Ключи атрибутов, общие для обоих сервисов.
+const (
+	KeyRequestID     = "request_id"
+	KeyUserID        = "user_id"
+	KeyTransactionID = "transaction_id"
+	KeyTopic         = "topic"
+	KeyPartition     = "partition"
+	KeyOffset        = "offset"
+	KeyError         = "error"
+	KeyTraceID       = "trace_id"
+	KeySpanID        = "span_id"
+)
+
+// New создает логгер, пишущий в w записи в формате JSON не ниже уровня level.
+// Записи, сделанные с контекстом (InfoContext и т. п.), получают атрибуты из With,
+// идентификатор запроса chi и идентификаторы трассировки.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(&contextHandler{next: slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	})})
+}
+
+// Discard возвращает логгер, который ничего не пишет. Используется по умолчанию,
+// если логгер не передан.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// OrDiscard возвращает logger или, если он nil, Discard.
+func OrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return Discard()
+	}
+	return logger
+}
+
+// ParseLevel разбирает LOG_LEVEL: debug, info, warn или error без учета регистра.
+// Пустая строка означает info.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: expected debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
+// Err описывает ошибку атрибутом error.
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+// UserID описывает пользователя атрибутом user_id.
+func UserID(userID string) slog.Attr {
+	return slog.String(KeyUserID, userID)
+}
+
+type attrsKey struct{}
+
+// With возвращает ctx, записи с которым получают атрибуты attrs
+// в дополнение к уже добавленным.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	merged = append(merged, prev...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// contextHandler дополняет записи атрибутами из контекста.
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := middleware.GetReqID(ctx); requestID != "" {
+		record.AddAttrs(slog.String(KeyRequestID, requestID))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(slog.String(KeyTraceID, sc.TraceID().String()), slog.String(KeySpanID, sc.SpanID().String()))
+	}
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		record.AddAttrs(attrs...)
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
+
+// Redacted заменяет значения чувствительных атрибутов.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys — ключи атрибутов, значения которых никогда не попадают в лог.
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"x-api-key":     true,
+	"api_key":       true,
+	"apikey":        true,
+	"cookie":        true,
+	"dsn":           true,
+	"postgres_dsn":  true,
+	"jwt":           true,
+}
+
+// sensitiveParts — части ключей, которые делают атрибут чувствительным
+// (password, db_password, access_token и т. п.).
+var sensitiveParts = []string{"password", "secret", "token"}
+
+// redact скрывает значения чувствительных атрибутов, в том числе внутри групп.
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() == slog.KindGroup {
+		return attr
+	}
+	if IsSensitive(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+	return attr
+}
+
+// IsSensitive сообщает, что значение с таким ключом (атрибута лога, заголовка,
+// параметра конфигурации) нельзя выводить.
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, part := range sensitiveParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
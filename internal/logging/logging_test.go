@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decode разбирает JSON-записи, записанные в buf.
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var record map[string]any
+		require.NoError(t, dec.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestParseLevel(t *testing.T) {
+	for input, expected := range map[string]slog.Level{
+		"":      slog.LevelInfo,
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		level, err := ParseLevel(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, level, input)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("should add context attributes", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&buf, slog.LevelInfo)
+
+		ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-1")
+		ctx = WithMessage(ctx, kafka.Message{Topic: "transactions", Partition: 3, Offset: 17})
+		ctx = With(ctx, UserID("u1"))
+		logger.InfoContext(ctx, "transaction saved")
+		logger.DebugContext(ctx, "not written")
+
+		records := decode(t, &buf)
+		require.Len(t, records, 1)
+		record := records[0]
+		assert.Equal(t, "transaction saved", record["msg"])
+		assert.Equal(t, "INFO", record["level"])
+		assert.Equal(t, "req-1", record[KeyRequestID])
+		assert.Equal(t, "transactions", record[KeyTopic])
+		assert.Equal(t, float64(3), record[KeyPartition])
+		assert.Equal(t, float64(17), record[KeyOffset])
+		assert.Equal(t, "u1", record[KeyUserID])
+	})
+
+	t.Run("should redact sensitive attributes", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&buf, slog.LevelInfo)
+
+		logger.Info("config",
+			slog.String("POSTGRES_DSN", "postgres://user:secret@db/casino"),
+			slog.Group("auth", slog.String("Authorization", "Bearer abc"), slog.String("issuer", "casino")),
+			slog.String("db_password", "secret"),
+			slog.String("api_key", "k"),
+			slog.String("kafka_topic", "transactions"),
+		)
+
+		out := buf.String()
+		assert.NotContains(t, out, "secret")
+		assert.NotContains(t, out, "Bearer abc")
+		record := decode(t, &buf)[0]
+		assert.Equal(t, Redacted, record["POSTGRES_DSN"])
+		assert.Equal(t, Redacted, record["api_key"])
+		assert.Equal(t, map[string]any{"Authorization": Redacted, "issuer": "casino"}, record["auth"])
+		assert.Equal(t, "transactions", record["kafka_topic"])
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(Middleware(New(&buf, slog.LevelInfo)))
+	r.Get("/users/{userID}/balance", func(w http.ResponseWriter, r *http.Request) {
+		AddRequestAttrs(With(r.Context(), slog.String("nested", "yes")), slog.String("subject", "agent-1"))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/u1/balance?api_key=k", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-7")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.NotContains(t, buf.String(), "api_key")
+
+	records := decode(t, &buf)
+	require.Len(t, records, 1)
+	record := records[0]
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "req-7", record[KeyRequestID])
+	assert.Equal(t, "/users/u1/balance", record["path"])
+	assert.Equal(t, "/users/{userID}/balance", record["route"])
+	assert.Equal(t, float64(http.StatusServiceUnavailable), record["status"])
+	assert.Equal(t, "u1", record[KeyUserID])
+	assert.Equal(t, "agent-1", record["subject"])
+	assert.NotContains(t, record, "nested")
+}
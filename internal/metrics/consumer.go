@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Consumer — счетчики обработки сообщений consumer'ом.
+// Методы безопасно вызывать у nil: тогда метрики не собираются.
+type Consumer struct {
+	fetched    prometheus.Counter
+	saved      prometheus.Counter
+	duplicates prometheus.Counter
+	invalid    *prometheus.CounterVec
+	failed     *prometheus.CounterVec
+	lag        *prometheus.GaugeVec
+}
+
+// NewConsumer создает метрики consumer'а и регистрирует их в reg.
+func NewConsumer(reg prometheus.Registerer) *Consumer {
+	m := &Consumer{
+		fetched: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "messages_fetched_total",
+			Help: "Messages fetched from Kafka.",
+		}),
+		saved: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "transactions_saved_total",
+			Help: "Transactions inserted into the database.",
+		}),
+		duplicates: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "transactions_duplicate_total",
+			Help: "Transactions skipped because their transaction_id was already stored.",
+		}),
+		invalid: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "messages_invalid_total",
+			Help: "Messages that could not be decoded or failed validation, by reason.",
+		}, []string{"reason"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "messages_failed_total",
+			Help: "Valid messages that could not be stored, by reason.",
+		}, []string{"reason"}),
+		lag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "lag_messages",
+			Help: "Messages behind the partition's high watermark as of the last fetched message.",
+		}, []string{"topic", "partition"}),
+	}
+	reg.MustRegister(m.fetched, m.saved, m.duplicates, m.invalid, m.failed, m.lag)
+	return m
+}
+
+// Fetched учитывает полученное сообщение. Отставание партиции считается по high watermark,
+// который Kafka возвращает вместе с сообщением, — сам reader при работе в группе
+// сообщает отставание только по последней прочитанной партиции.
+func (m *Consumer) Fetched(topic string, partition int, offset, highWaterMark int64) {
+	if m == nil {
+		return
+	}
+	m.fetched.Inc()
+	lag := highWaterMark - offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	m.lag.WithLabelValues(topic, strconv.Itoa(partition)).Set(float64(lag))
+}
+
+// Stored учитывает результат сохранения: saved новых транзакций и duplicates уже сохраненных.
+func (m *Consumer) Stored(saved, duplicates int) {
+	if m == nil {
+		return
+	}
+	m.saved.Add(float64(saved))
+	m.duplicates.Add(float64(duplicates))
+}
+
+// Invalid учитывает сообщение, не прошедшее разбор или валидацию.
+func (m *Consumer) Invalid(reason string) {
+	if m == nil {
+		return
+	}
+	m.invalid.WithLabelValues(reason).Inc()
+}
+
+// Failed учитывает корректное сообщение, которое не удалось сохранить.
+func (m *Consumer) Failed(reason string) {
+	if m == nil {
+		return
+	}
+	m.failed.WithLabelValues(reason).Inc()
+}
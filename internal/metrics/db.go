@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Queries — гистограмма длительности вызовов методов репозиториев.
+// Реализует repository.QueryObserver.
+type Queries struct {
+	duration *prometheus.HistogramVec
+}
+
+// NewQueries создает гистограмму и регистрирует ее в reg.
+func NewQueries(reg prometheus.Registerer) *Queries {
+	m := &Queries{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "db", Name: "query_duration_seconds",
+			Help:    "Duration of repository method calls, including transaction overhead.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"repository", "method", "outcome"}),
+	}
+	reg.MustRegister(m.duration)
+	return m
+}
+
+func (m *Queries) ObserveQuery(repository, method string, duration time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	m.duration.WithLabelValues(repository, method, outcome).Observe(duration.Seconds())
+}
+
+// PoolCollector отдает статистику пула соединений pgxpool.
+type PoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired, idle, constructing, total, max           *prometheus.Desc
+	acquires, emptyAcquires, canceledAcquires, waitSec *prometheus.Desc
+}
+
+// NewPoolCollector создает коллектор статистики pool для регистрации в реестре.
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &PoolCollector{
+		pool:             pool,
+		acquired:         desc("acquired_connections", "Connections currently in use."),
+		idle:             desc("idle_connections", "Idle connections in the pool."),
+		constructing:     desc("constructing_connections", "Connections being established."),
+		total:            desc("total_connections", "Connections in the pool."),
+		max:              desc("max_connections", "Maximum size of the pool."),
+		acquires:         desc("acquires_total", "Successful connection acquisitions."),
+		emptyAcquires:    desc("empty_acquires_total", "Acquisitions that had to wait because the pool was empty."),
+		canceledAcquires: desc("canceled_acquires_total", "Acquisitions canceled by their context."),
+		waitSec:          desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.acquired, c.idle, c.constructing, c.total, c.max,
+		c.acquires, c.emptyAcquires, c.canceledAcquires, c.waitSec,
+	} {
+		ch <- d
+	}
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+	gauge(c.acquired, float64(stat.AcquiredConns()))
+	gauge(c.idle, float64(stat.IdleConns()))
+	gauge(c.constructing, float64(stat.ConstructingConns()))
+	gauge(c.total, float64(stat.TotalConns()))
+	gauge(c.max, float64(stat.MaxConns()))
+	counter(c.acquires, float64(stat.AcquireCount()))
+	counter(c.emptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(c.canceledAcquires, float64(stat.CanceledAcquireCount()))
+	counter(c.waitSec, stat.AcquireDuration().Seconds())
+}
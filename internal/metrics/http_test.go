@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape возвращает метрики реестра так, как их получит Prometheus.
+func scrape(t *testing.T, reg *prometheus.Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewHTTP(reg)
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/users/{userID}/transactions", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, path := range []string{"/users/u1/transactions", "/users/u2/transactions", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Идентификатор пользователя из пути не должен попадать в метки.
+	assert.Equal(t, 2, testutil.CollectAndCount(m.duration))
+	body := scrape(t, reg)
+	assert.Contains(t, body, `casino_http_request_duration_seconds_count{method="GET",route="/users/{userID}/transactions",status="200"} 2`)
+	assert.Contains(t, body, `casino_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+)
+
+// ReaderStatser — источник статистики kafka.Reader.
+type ReaderStatser interface {
+	Stats() kafka.ReaderStats
+}
+
+// ReaderCollector отдает статистику kafka.Reader. Reader обнуляет свои счетчики
+// при каждом вызове Stats, поэтому коллектор накапливает их сам.
+type ReaderCollector struct {
+	reader ReaderStatser
+
+	mu     sync.Mutex
+	totals map[*prometheus.Desc]float64
+
+	messages, bytes, errors, rebalances, timeouts *prometheus.Desc
+	lag, queueLength                              *prometheus.Desc
+}
+
+// NewReaderCollector создает коллектор статистики reader'а для регистрации в реестре.
+func NewReaderCollector(reader ReaderStatser) *ReaderCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "kafka_reader", name), help, []string{"topic"}, nil)
+	}
+	return &ReaderCollector{
+		reader:      reader,
+		totals:      make(map[*prometheus.Desc]float64),
+		messages:    desc("messages_total", "Messages read by the Kafka reader."),
+		bytes:       desc("bytes_total", "Bytes read by the Kafka reader."),
+		errors:      desc("errors_total", "Errors reported by the Kafka reader."),
+		rebalances:  desc("rebalances_total", "Consumer group rebalances."),
+		timeouts:    desc("timeouts_total", "Fetch timeouts of the Kafka reader."),
+		lag:         desc("lag_messages", "Lag reported by the Kafka reader for the partition it read last."),
+		queueLength: desc("queue_length", "Messages fetched by the Kafka reader but not yet consumed."),
+	}
+}
+
+func (c *ReaderCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.messages, c.bytes, c.errors, c.rebalances, c.timeouts, c.lag, c.queueLength} {
+		ch <- d
+	}
+}
+
+func (c *ReaderCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.reader.Stats()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for d, delta := range map[*prometheus.Desc]int64{
+		c.messages:   stats.Messages,
+		c.bytes:      stats.Bytes,
+		c.errors:     stats.Errors,
+		c.rebalances: stats.Rebalances,
+		c.timeouts:   stats.Timeouts,
+	} {
+		c.totals[d] += float64(delta)
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, c.totals[d], stats.Topic)
+	}
+	ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, float64(stats.Lag), stats.Topic)
+	ch <- prometheus.MustNewConstMetric(c.queueLength, prometheus.GaugeValue, float64(stats.QueueLength), stats.Topic)
+}
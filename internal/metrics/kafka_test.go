@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeReader struct {
+	stats []kafka.ReaderStats
+}
+
+func (r *fakeReader) Stats() kafka.ReaderStats {
+	s := r.stats[0]
+	r.stats = r.stats[1:]
+	return s
+}
+
+func TestReaderCollector(t *testing.T) {
+	// kafka.Reader обнуляет счетчики при каждом вызове Stats, поэтому второй снимок — приращение.
+	reader := &fakeReader{stats: []kafka.ReaderStats{
+		{Topic: "transactions", Messages: 5, Errors: 1, Lag: 7},
+		{Topic: "transactions", Messages: 3, Lag: 2},
+	}}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewReaderCollector(reader))
+
+	_, err := reg.Gather()
+	assert.NoError(t, err)
+
+	expected := `
+# HELP casino_kafka_reader_messages_total Messages read by the Kafka reader.
+# TYPE casino_kafka_reader_messages_total counter
+casino_kafka_reader_messages_total{topic="transactions"} 8
+# HELP casino_kafka_reader_errors_total Errors reported by the Kafka reader.
+# TYPE casino_kafka_reader_errors_total counter
+casino_kafka_reader_errors_total{topic="transactions"} 1
+# HELP casino_kafka_reader_lag_messages Lag reported by the Kafka reader for the partition it read last.
+# TYPE casino_kafka_reader_lag_messages gauge
+casino_kafka_reader_lag_messages{topic="transactions"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"casino_kafka_reader_messages_total", "casino_kafka_reader_errors_total", "casino_kafka_reader_lag_messages"))
+}
@@ -0,0 +1,329 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockID — ключ advisory lock, под которым выполняются миграции. Реплики,
+// запущенные одновременно, ждут друг друга, а не применяют одну миграцию дважды.
+const lockID int64 = 0x63_61_73_69_6e_6f // "casino"
+
+const createTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    version    BIGINT PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    checksum   CHAR(64)    NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+// ErrChecksumMismatch возвращается, если примененная миграция была изменена после применения.
+var ErrChecksumMismatch = errors.New("applied migration was modified")
+
+// State — состояние миграции в Status.
+type State string
+
+const (
+	StateApplied State = "applied"
+	StatePending State = "pending"
+	// StateModified — миграция применена, но ее файл с тех пор изменился.
+	StateModified State = "modified"
+	// StateUnknown — версия применена, но ее файла нет: БД обновлена более новой версией сервиса.
+	StateUnknown State = "unknown"
+)
+
+// Status — состояние одной версии схемы.
+type Status struct {
+	Version   int64
+	Name      string
+	State     State
+	AppliedAt time.Time // нулевое для StatePending
+}
+
+// applied — запись schema_migrations.
+type applied struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+// New создает Migrator для миграций из fsys (см. Load).
+func New(db *pgxpool.Pool, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, logger: logging.OrDiscard(logger)}, nil
+}
+
+// Latest возвращает версию последней известной миграции или 0, если миграций нет.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up применяет все непримененные миграции. Версии, примененные более новым
+// сервисом и неизвестные этому, не трогает: при поэтапном обновлении старые реплики
+// не должны откатывать схему.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn, done map[int64]applied) error {
+		if err := m.verify(done); err != nil {
+			return err
+		}
+		return m.applyPending(ctx, conn, done, m.Latest())
+	})
+}
+
+// Down откатывает steps последних примененных миграций.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn, done map[int64]applied) error {
+		versions := appliedVersions(done)
+		for i := len(versions) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+			if err := m.revert(ctx, conn, versions[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// To приводит схему к версии version: применяет непримененные миграции до нее включительно
+// и откатывает примененные после нее, начиная с последней. To(ctx, 0) откатывает все.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	return m.withLock(ctx, func(conn *pgxpool.Conn, done map[int64]applied) error {
+		if err := m.verify(done); err != nil {
+			return err
+		}
+		versions := appliedVersions(done)
+		for i := len(versions) - 1; i >= 0 && versions[i] > version; i-- {
+			if err := m.revert(ctx, conn, versions[i]); err != nil {
+				return err
+			}
+		}
+		return m.applyPending(ctx, conn, done, version)
+	})
+}
+
+// Baseline отмечает миграции до version включительно как примененные, не выполняя их.
+// Нужен для БД, схема которой создана до появления schema_migrations.
+// Допустим только для БД без примененных миграций.
+func (m *Migrator) Baseline(ctx context.Context, version int64) error {
+	if m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	return m.withLock(ctx, func(conn *pgxpool.Conn, done map[int64]applied) error {
+		if len(done) > 0 {
+			return errors.New("baseline requires a database without applied migrations")
+		}
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, err := conn.Exec(ctx,
+				"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+				migration.Version, migration.Name, migration.Checksum); err != nil {
+				return fmt.Errorf("could not record migration %d: %w", migration.Version, err)
+			}
+		}
+		m.logger.InfoContext(ctx, "baselined schema", slog.Int64("version", version))
+		return nil
+	})
+}
+
+// Status возвращает состояние всех известных и всех примененных версий по возрастанию.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(_ *pgxpool.Conn, done map[int64]applied) error {
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name, State: StatePending}
+			if a, ok := done[migration.Version]; ok {
+				status.State, status.AppliedAt = StateApplied, a.appliedAt
+				if a.checksum != migration.Checksum {
+					status.State = StateModified
+				}
+			}
+			statuses = append(statuses, status)
+		}
+		for _, version := range appliedVersions(done) {
+			if m.find(version) == nil {
+				a := done[version]
+				statuses = append(statuses, Status{Version: version, Name: a.name, State: StateUnknown, AppliedAt: a.appliedAt})
+			}
+		}
+		return nil
+	})
+	sortStatuses(statuses)
+	return statuses, err
+}
+
+// withLock выполняет fn на отдельном соединении под advisory lock, передавая
+// примененные версии. Session-level lock снимается на том же соединении.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn, done map[int64]applied) error) (err error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("could not acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("could not take migration lock: %w", err)
+	}
+	defer func() {
+		// Снимаем lock даже при отмененном ctx; если это не удалось, закрываем
+		// соединение, чтобы lock не остался в пуле
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if _, unlockErr := conn.Exec(unlockCtx, "SELECT pg_advisory_unlock($1)", lockID); unlockErr != nil {
+			_ = conn.Conn().Close(unlockCtx)
+			err = errors.Join(err, fmt.Errorf("could not release migration lock: %w", unlockErr))
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createTable); err != nil {
+		return fmt.Errorf("could not create schema_migrations: %w", err)
+	}
+	done, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, done)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int64]applied, error) {
+	rows, err := conn.Query(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("could not read schema_migrations: %w", err)
+	}
+	done := make(map[int64]applied)
+	var (
+		version int64
+		a       applied
+	)
+	_, err = pgx.ForEachRow(rows, []any{&version, &a.name, &a.checksum, &a.appliedAt}, func() error {
+		done[version] = a
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read schema_migrations: %w", err)
+	}
+	return done, nil
+}
+
+// verify проверяет, что примененные миграции не менялись.
+func (m *Migrator) verify(done map[int64]applied) error {
+	for _, migration := range m.migrations {
+		if a, ok := done[migration.Version]; ok && a.checksum != migration.Checksum {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrChecksumMismatch)
+		}
+	}
+	return nil
+}
+
+// applyPending применяет непримененные миграции до version включительно.
+func (m *Migrator) applyPending(ctx context.Context, conn *pgxpool.Conn, done map[int64]applied, version int64) error {
+	for _, migration := range m.migrations {
+		if _, ok := done[migration.Version]; ok || migration.Version > version {
+			continue
+		}
+		if err := m.apply(ctx, conn, migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply выполняет миграцию и запись о ней в одной транзакции.
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, migration Migration) error {
+	start := time.Now()
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, migration.Up); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx,
+			"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+			migration.Version, migration.Name, migration.Checksum)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	m.logger.InfoContext(ctx, "applied migration",
+		slog.Int64("version", migration.Version),
+		slog.String("name", migration.Name),
+		slog.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// revert откатывает миграцию и удаляет запись о ней в одной транзакции.
+func (m *Migrator) revert(ctx context.Context, conn *pgxpool.Conn, version int64) error {
+	migration := m.find(version)
+	if migration == nil {
+		return fmt.Errorf("cannot revert migration %d: no migration file", version)
+	}
+	if migration.Down == "" {
+		return fmt.Errorf("cannot revert migration %d_%s: no down file", version, migration.Name)
+	}
+
+	start := time.Now()
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("revert migration %d_%s: %w", version, migration.Name, err)
+	}
+	m.logger.InfoContext(ctx, "reverted migration",
+		slog.Int64("version", version),
+		slog.String("name", migration.Name),
+		slog.Duration("duration", time.Since(start)))
+	return nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// appliedVersions возвращает примененные версии по возрастанию.
+func appliedVersions(done map[int64]applied) []int64 {
+	versions := make([]int64, 0, len(done))
+	for version := range done {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+func sortStatuses(statuses []Status) {
+	slices.SortFunc(statuses, func(a, b Status) int { return cmp.Compare(a.Version, b.Version) })
+}
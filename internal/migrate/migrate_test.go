@@ -0,0 +1,150 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/migrations"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"golang.org/x/sync/errgroup"
+)
+
+func setupTestDB(ctx context.Context, t *testing.T) *pgxpool.Pool {
+	pgContainer, err := postgres.Run(ctx, "postgres:14-alpine",
+		postgres.WithDatabase("test-db"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second),
+		),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = pgContainer.Terminate(ctx) })
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	dbpool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	t.Cleanup(dbpool.Close)
+	return dbpool
+}
+
+// states возвращает состояние каждой версии.
+func states(ctx context.Context, t *testing.T, m *Migrator) map[int64]State {
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	result := make(map[int64]State, len(statuses))
+	for _, s := range statuses {
+		result[s.Version] = s.State
+	}
+	return result
+}
+
+func tableExists(ctx context.Context, t *testing.T, dbpool *pgxpool.Pool, table string) bool {
+	var exists bool
+	require.NoError(t, dbpool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists))
+	return exists
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	dbpool := setupTestDB(ctx, t)
+
+	migrator, err := New(dbpool, migrations.FS, nil)
+	require.NoError(t, err)
+	latest := migrator.Latest()
+
+	// Реплики, стартующие одновременно, применяют каждую миграцию один раз
+	g, gctx := errgroup.WithContext(ctx)
+	for range 3 {
+		g.Go(func() error {
+			replica, err := New(dbpool, migrations.FS, nil)
+			if err != nil {
+				return err
+			}
+			return replica.Up(gctx)
+		})
+	}
+	require.NoError(t, g.Wait())
+
+	for version, state := range states(ctx, t, migrator) {
+		assert.Equal(t, StateApplied, state, "version %d", version)
+	}
+	assert.True(t, tableExists(ctx, t, dbpool, "access_denials"))
+	require.NoError(t, migrator.Up(ctx), "up is idempotent")
+
+	t.Run("down and to", func(t *testing.T) {
+		require.NoError(t, migrator.Down(ctx, 1))
+		assert.Equal(t, StatePending, states(ctx, t, migrator)[latest])
+		assert.False(t, tableExists(ctx, t, dbpool, "access_denials"))
+
+		require.NoError(t, migrator.To(ctx, 8))
+		current := states(ctx, t, migrator)
+		assert.Equal(t, StateApplied, current[8])
+		assert.Equal(t, StatePending, current[9])
+		assert.False(t, tableExists(ctx, t, dbpool, "ggr_hourly"))
+		assert.True(t, tableExists(ctx, t, dbpool, "user_limits"))
+
+		// Все откаты обратимы: схема собирается заново
+		require.NoError(t, migrator.To(ctx, 0))
+		assert.False(t, tableExists(ctx, t, dbpool, "transactions"))
+		require.NoError(t, migrator.To(ctx, latest))
+		assert.True(t, tableExists(ctx, t, dbpool, "access_denials"))
+
+		assert.ErrorContains(t, migrator.To(ctx, latest+1), "unknown migration version")
+	})
+
+	t.Run("modified migration", func(t *testing.T) {
+		files := fstest.MapFS{}
+		for _, m := range migrator.migrations {
+			name := func(suffix string) string { return formatName(m) + suffix }
+			files[name(".up.sql")] = &fstest.MapFile{Data: []byte(m.Up)}
+			files[name(".down.sql")] = &fstest.MapFile{Data: []byte(m.Down)}
+		}
+		files[formatName(migrator.migrations[0])+".up.sql"].Data = []byte(migrator.migrations[0].Up + "\n-- edited")
+
+		modified, err := New(dbpool, files, nil)
+		require.NoError(t, err)
+		assert.ErrorIs(t, modified.Up(ctx), ErrChecksumMismatch)
+		assert.Equal(t, StateModified, states(ctx, t, modified)[1])
+	})
+
+	t.Run("unknown applied version", func(t *testing.T) {
+		_, err := dbpool.Exec(ctx, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, 'from_newer_release', $2)",
+			latest+1, migrator.migrations[0].Checksum)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_, _ = dbpool.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", latest+1)
+		})
+
+		require.NoError(t, migrator.Up(ctx), "an older release does not revert newer migrations")
+		assert.Equal(t, StateUnknown, states(ctx, t, migrator)[latest+1])
+		assert.ErrorContains(t, migrator.Down(ctx, 1), "no migration file")
+	})
+
+	t.Run("baseline", func(t *testing.T) {
+		assert.ErrorContains(t, migrator.Baseline(ctx, latest), "without applied migrations")
+
+		// Схема, созданная до schema_migrations, отмечается как примененная без выполнения миграций
+		_, err := dbpool.Exec(ctx, "DROP TABLE schema_migrations")
+		require.NoError(t, err)
+		require.NoError(t, migrator.Baseline(ctx, latest))
+		for version, state := range states(ctx, t, migrator) {
+			assert.Equal(t, StateApplied, state, "version %d", version)
+		}
+	})
+}
+
+func formatName(m Migration) string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
@@ -0,0 +1,79 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/OlgaPie/casino-transaction-system/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("pairs up and down files by version", func(t *testing.T) {
+		loaded, err := Load(fstest.MapFS{
+			"010_add_b.up.sql":        {Data: []byte("CREATE TABLE b ();")},
+			"002_add_a.up.sql":        {Data: []byte("CREATE TABLE a ();")},
+			"002_add_a.down.sql":      {Data: []byte("DROP TABLE a;")},
+			"migrations.go":           {Data: []byte("package migrations")},
+			"nested/001_x.up.sql":     {Data: []byte("ignored")},
+			"nested/001_x.README.sql": {Data: []byte("ignored")},
+		})
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+
+		assert.Equal(t, int64(2), loaded[0].Version)
+		assert.Equal(t, "add_a", loaded[0].Name)
+		assert.Equal(t, "CREATE TABLE a ();", loaded[0].Up)
+		assert.Equal(t, "DROP TABLE a;", loaded[0].Down)
+		// sha256 содержимого up-файла
+		assert.Equal(t, "902f5c947b00294d6b0cdb41666553a31c51672ed6e65af6209e622790dce7b1", loaded[0].Checksum)
+
+		assert.Equal(t, int64(10), loaded[1].Version)
+		assert.Empty(t, loaded[1].Down, "down file is optional")
+		assert.NotEqual(t, loaded[0].Checksum, loaded[1].Checksum)
+	})
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name:    "misnamed file",
+			files:   fstest.MapFS{"003_add_c.sql": {}},
+			wantErr: "must be named NNN_name.up.sql",
+		},
+		{
+			name:    "zero version",
+			files:   fstest.MapFS{"000_init.up.sql": {}},
+			wantErr: "invalid version",
+		},
+		{
+			name:    "duplicate version",
+			files:   fstest.MapFS{"001_a.up.sql": {}, "001_b.up.sql": {}},
+			wantErr: "version 1 is already used",
+		},
+		{
+			name:    "down without up",
+			files:   fstest.MapFS{"001_a.down.sql": {}},
+			wantErr: "has no up file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.files)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("embedded migrations", func(t *testing.T) {
+		loaded, err := Load(migrations.FS)
+		require.NoError(t, err)
+		require.NotEmpty(t, loaded)
+		for i, m := range loaded {
+			assert.Equal(t, int64(i+1), m.Version, "versions are consecutive")
+			assert.NotEmpty(t, m.Down, "%d_%s has a down file", m.Version, m.Name)
+		}
+	})
+}
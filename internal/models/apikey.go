@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// APIKey — статический ключ доступа к API. Сам ключ не хранится, только его хеш.
+type APIKey struct {
+	ID int64 `db:"id"`
+	// Name идентифицирует владельца ключа и используется как subject в проверках доступа.
+	Name      string     `db:"name"`
+	KeyHash   string     `db:"key_hash"`
+	Scopes    []string   `db:"scopes"`
+	Roles     []string   `db:"roles"`
+	CreatedAt time.Time  `db:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+// AccessDenial — запись журнала аудита об отказе в доступе к endpoint'у.
+type AccessDenial struct {
+	ID         int64    `json:"id" db:"id"`
+	Subject    string   `json:"subject" db:"subject"`
+	AuthMethod string   `json:"auth_method" db:"auth_method"`
+	Roles      []string `json:"roles" db:"roles"`
+	// Permission — разрешение, которого не хватило.
+	Permission string    `json:"permission" db:"permission"`
+	HTTPMethod string    `json:"http_method" db:"http_method"`
+	Path       string    `json:"path" db:"path"`
+	RequestID  string    `json:"request_id" db:"request_id"`
+	OccurredAt time.Time `json:"occurred_at" db:"occurred_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type Balance struct {
+	UserID   string `json:"user_id" db:"user_id"`
+	Currency string `json:"currency" db:"currency"`
+	Balance  int64  `json:"balance" db:"balance"`
+	// UpdatedAt пуст, если по пользователю еще не было движений.
+	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+type Currency struct {
+	// Code — трехбуквенный код ISO 4217, например "EUR".
+	Code string `json:"code" db:"code"`
+	// MinorUnits — число знаков дробной части: суммы хранятся в 10^-MinorUnits долях валюты.
+	MinorUnits int `json:"minor_units" db:"minor_units"`
+}
+
+// IsCurrencyCode сообщает, похожа ли строка на код ISO 4217 (три заглавные латинские буквы).
+// Существование валюты проверяется по таблице currencies.
+func IsCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package models
+
+import "time"
+
+type LimitType string
+
+const (
+	// LimitTypeLoss ограничивает проигрыш: ставки минус выигрыши, возвраты и отмены ставок.
+	LimitTypeLoss LimitType = "loss"
+	// LimitTypeWager ограничивает сумму ставок за вычетом возвратов и отмен.
+	LimitTypeWager LimitType = "wager"
+)
+
+// Valid сообщает, является ли t поддерживаемым типом лимита.
+func (t LimitType) Valid() bool {
+	return t == LimitTypeLoss || t == LimitTypeWager
+}
+
+// LimitPeriod — календарный период лимита в UTC.
+type LimitPeriod string
+
+const (
+	LimitPeriodDay   LimitPeriod = "day"
+	LimitPeriodWeek  LimitPeriod = "week"
+	LimitPeriodMonth LimitPeriod = "month"
+)
+
+// Valid сообщает, является ли p поддерживаемым периодом лимита.
+func (p LimitPeriod) Valid() bool {
+	return p == LimitPeriodDay || p == LimitPeriodWeek || p == LimitPeriodMonth
+}
+
+// Bounds возвращает начало (включительно) и конец (не включительно) периода, содержащего t.
+// Неделя начинается в понедельник.
+func (p LimitPeriod) Bounds(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case LimitPeriodWeek:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case LimitPeriodMonth:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return day, day.AddDate(0, 0, 1)
+	}
+}
+
+type Limit struct {
+	LimitType LimitType   `json:"limit_type" db:"limit_type"`
+	Period    LimitPeriod `json:"period" db:"period"`
+	Currency  string      `json:"currency" db:"currency"`
+	// Amount задается в минимальных единицах валюты.
+	Amount    int64      `json:"amount" db:"amount"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+type SelfExclusion struct {
+	ExcludedUntil time.Time `json:"excluded_until" db:"excluded_until"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// UserLimits — все ограничения ответственной игры, установленные пользователю.
+type UserLimits struct {
+	UserID string  `json:"user_id"`
+	Limits []Limit `json:"limits"`
+	// SelfExclusion пуст, если пользователь никогда не исключал себя.
+	SelfExclusion *SelfExclusion `json:"self_exclusion,omitempty"`
+}
+
+// ExcludedAt сообщает, действует ли самоисключение в момент t.
+func (l UserLimits) ExcludedAt(t time.Time) bool {
+	return l.SelfExclusion != nil && t.Before(l.SelfExclusion.ExcludedUntil)
+}
+
+// PlayTotals — суммы игровых транзакций пользователя в одной валюте за период.
+type PlayTotals struct {
+	Bets      int64 `db:"bets"`
+	Wins      int64 `db:"wins"`
+	Reversals int64 `db:"reversals"`
+}
+
+// Wagered возвращает сумму ставок за вычетом возвратов и отмен.
+func (t PlayTotals) Wagered() int64 {
+	return t.Bets - t.Reversals
+}
+
+// Loss возвращает проигрыш; отрицательное значение означает, что пользователь в плюсе.
+func (t PlayTotals) Loss() int64 {
+	return t.Bets - t.Wins - t.Reversals
+}
+
+// Add учитывает транзакцию tx в суммах.
+func (t *PlayTotals) Add(tx Transaction) {
+	switch {
+	case tx.TransactionType == TransactionTypeBet:
+		t.Bets += tx.Amount
+	case tx.TransactionType == TransactionTypeWin:
+		t.Wins += tx.Amount
+	case tx.TransactionType.RequiresReference():
+		t.Reversals += tx.Amount
+	}
+}
+
+type ViolationType string
+
+const (
+	ViolationSelfExclusion ViolationType = "self_exclusion"
+	ViolationLossLimit     ViolationType = "loss_limit"
+	ViolationWagerLimit    ViolationType = "wager_limit"
+)
+
+// LimitViolation описывает транзакцию, отклоненную из-за лимита или самоисключения.
+// Для самоисключения Period, LimitAmount и CurrentAmount остаются нулевыми.
+type LimitViolation struct {
+	ID              int64         `json:"id" db:"id"`
+	UserID          string        `json:"user_id" db:"user_id"`
+	TransactionID   string        `json:"transaction_id" db:"transaction_id"`
+	ViolationType   ViolationType `json:"violation_type" db:"violation_type"`
+	Period          LimitPeriod   `json:"period,omitempty" db:"period"`
+	Currency        string        `json:"currency" db:"currency"`
+	LimitAmount     int64         `json:"limit_amount" db:"limit_amount"`
+	CurrentAmount   int64         `json:"current_amount" db:"current_amount"`
+	AttemptedAmount int64         `json:"attempted_amount" db:"attempted_amount"`
+	OccurredAt      time.Time     `json:"occurred_at" db:"occurred_at"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// Granularity — шаг отчета: час или календарный день в UTC.
+type Granularity string
+
+const (
+	GranularityHour Granularity = "hour"
+	GranularityDay  Granularity = "day"
+)
+
+// Valid сообщает, является ли g поддерживаемым шагом отчета.
+func (g Granularity) Valid() bool {
+	return g == GranularityHour || g == GranularityDay
+}
+
+// Truncate возвращает начало периода, содержащего t.
+func (g Granularity) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	if g == GranularityHour {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Next возвращает начало периода, следующего за периодом, который начинается в start.
+func (g Granularity) Next(start time.Time) time.Time {
+	if g == GranularityHour {
+		return start.Add(time.Hour)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// GGRAmounts — суммы игровых транзакций в минимальных единицах валюты.
+type GGRAmounts struct {
+	// TotalWagered — сумма ставок, TotalReversed — сумма возвратов и отмен ставок.
+	TotalWagered  int64 `json:"total_wagered" db:"total_wagered"`
+	TotalWon      int64 `json:"total_won" db:"total_won"`
+	TotalReversed int64 `json:"total_reversed" db:"total_reversed"`
+	// GGR — ставки минус выигрыши, возвраты и отмены ставок.
+	GGR      int64 `json:"ggr" db:"ggr"`
+	BetCount int64 `json:"bet_count" db:"bet_count"`
+	// WinCount не учитывает нулевые выигрыши, которыми закрываются проигранные раунды.
+	WinCount int64 `json:"win_count" db:"win_count"`
+}
+
+// Add прибавляет a к суммам.
+func (s *GGRAmounts) Add(a GGRAmounts) {
+	s.TotalWagered += a.TotalWagered
+	s.TotalWon += a.TotalWon
+	s.TotalReversed += a.TotalReversed
+	s.GGR += a.GGR
+	s.BetCount += a.BetCount
+	s.WinCount += a.WinCount
+}
+
+// GGRRow — суммы за один период в одной валюте.
+type GGRRow struct {
+	PeriodStart time.Time `json:"period_start" db:"period_start"`
+	Currency    string    `json:"currency" db:"currency"`
+	GGRAmounts
+}
+
+// GGRTotal — суммы за весь отчет в одной валюте.
+type GGRTotal struct {
+	Currency string `json:"currency"`
+	GGRAmounts
+}
+
+// GGRReport — отчет о GGR за период [From, To).
+// Периоды без игровых транзакций в отчет не попадают.
+type GGRReport struct {
+	From        time.Time   `json:"from"`
+	To          time.Time   `json:"to"`
+	Granularity Granularity `json:"granularity"`
+	// Rows упорядочены по началу периода и коду валюты.
+	Rows   []GGRRow   `json:"rows"`
+	Totals []GGRTotal `json:"totals"`
+}
@@ -0,0 +1,30 @@
+package models
+
+type RoundStatus string
+
+const (
+	// RoundStatusOpen — по раунду есть ставки, но он еще не рассчитан.
+	RoundStatusOpen RoundStatus = "open"
+	// RoundStatusClosed — по раунду пришел выигрыш (в том числе нулевой)
+	// либо все ставки полностью возвращены.
+	RoundStatusClosed RoundStatus = "closed"
+)
+
+// Round — игровой раунд (спин, раздача) со всеми его транзакциями.
+// Все транзакции раунда принадлежат одному пользователю и одной валюте.
+type Round struct {
+	RoundID    string      `json:"round_id"`
+	UserID     string      `json:"user_id"`
+	Currency   string      `json:"currency"`
+	GameID     string      `json:"game_id,omitempty"`
+	ProviderID string      `json:"provider_id,omitempty"`
+	Status     RoundStatus `json:"status"`
+	// Суммы в минимальных единицах валюты: ставки, выигрыши, возвраты и отмены ставок.
+	TotalBet      int64 `json:"total_bet"`
+	TotalWin      int64 `json:"total_win"`
+	TotalReversed int64 `json:"total_reversed"`
+	// NetResult — результат раунда для игрока: выигрыши и возвраты минус ставки.
+	NetResult int64 `json:"net_result"`
+	// Transactions упорядочены по времени совершения.
+	Transactions []Transaction `json:"transactions"`
+}
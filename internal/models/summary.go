@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// UserSummary — агрегированная статистика пользователя за период.
+// Суммы в разных валютах не складываются, поэтому статистика ведется по каждой валюте отдельно.
+type UserSummary struct {
+	UserID string `json:"user_id"`
+	// From включительно, To не включительно; пустые границы не ограничивают период.
+	From       *time.Time        `json:"from,omitempty"`
+	To         *time.Time        `json:"to,omitempty"`
+	Currencies []CurrencySummary `json:"currencies"`
+}
+
+// CurrencySummary — статистика пользователя в одной валюте.
+// Суммы в минимальных единицах валюты.
+type CurrencySummary struct {
+	Currency string `json:"currency" db:"currency"`
+	// TotalWagered — сумма ставок, TotalReversed — сумма их возвратов и отмен.
+	TotalWagered  int64 `json:"total_wagered" db:"total_wagered"`
+	TotalWon      int64 `json:"total_won" db:"total_won"`
+	TotalReversed int64 `json:"total_reversed" db:"total_reversed"`
+	// NetResult — вклад пользователя в GGR: ставки минус выигрыши, возвраты и отмены.
+	// Отрицательное значение означает, что пользователь в плюсе.
+	NetResult int64 `json:"net_result" db:"net_result"`
+	BetCount  int64 `json:"bet_count" db:"bet_count"`
+	// WinCount не учитывает нулевые выигрыши, которыми закрываются проигранные раунды.
+	WinCount   int64 `json:"win_count" db:"win_count"`
+	LargestWin int64 `json:"largest_win" db:"largest_win"`
+	// Первая и последняя транзакция любого типа, включая пополнения и выводы.
+	FirstActivity time.Time `json:"first_activity" db:"first_activity"`
+	LastActivity  time.Time `json:"last_activity" db:"last_activity"`
+}
@@ -1,19 +1,101 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type TransactionType string
 
 const (
 	TransactionTypeBet TransactionType = "bet"
 	TransactionTypeWin TransactionType = "win"
+	// TransactionTypeRefund возвращает часть или всю сумму ставки.
+	TransactionTypeRefund TransactionType = "refund"
+	// TransactionTypeRollback полностью отменяет ставку.
+	TransactionTypeRollback   TransactionType = "rollback"
+	TransactionTypeDeposit    TransactionType = "deposit"
+	TransactionTypeWithdrawal TransactionType = "withdrawal"
 )
 
+// TransactionTypes перечисляет все поддерживаемые типы транзакций.
+var TransactionTypes = []TransactionType{
+	TransactionTypeBet,
+	TransactionTypeWin,
+	TransactionTypeRefund,
+	TransactionTypeRollback,
+	TransactionTypeDeposit,
+	TransactionTypeWithdrawal,
+}
+
+// Valid сообщает, является ли t поддерживаемым типом транзакции.
+func (t TransactionType) Valid() bool {
+	for _, known := range TransactionTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDebit сообщает, списывает ли транзакция этого типа средства с баланса.
+func (t TransactionType) IsDebit() bool {
+	return t == TransactionTypeBet || t == TransactionTypeWithdrawal
+}
+
+// RequiresReference сообщает, должна ли транзакция ссылаться на исходную ставку
+// через ReferenceTransactionID.
+func (t TransactionType) RequiresReference() bool {
+	return t == TransactionTypeRefund || t == TransactionTypeRollback
+}
+
+// IsGameplay сообщает, может ли транзакция этого типа относиться к игровому раунду.
+// Депозиты и выводы средств вне игры.
+func (t TransactionType) IsGameplay() bool {
+	return t == TransactionTypeBet || t == TransactionTypeWin || t.RequiresReference()
+}
+
+// MaxUserIDLength — наибольшая длина user_id, как у колонки user_id в БД.
+const MaxUserIDLength = 255
+
+// IsUserID сообщает, похожа ли строка на user_id: от 1 до MaxUserIDLength латинских букв,
+// цифр и символов "-", "_", ".", ":" или "@".
+func IsUserID(id string) bool {
+	if id == "" || len(id) > MaxUserIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("-_.:@", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 type Transaction struct {
 	ID              int64           `json:"id" db:"id"`
 	TransactionID   string          `json:"transaction_id" db:"transaction_id"`
 	UserID          string          `json:"user_id" db:"user_id"`
 	TransactionType TransactionType `json:"transaction_type" db:"transaction_type"`
 	Amount          int64           `json:"amount" db:"amount"`
-	Timestamp       time.Time       `json:"timestamp" db:"timestamp"`
+	// Currency — код валюты ISO 4217; Amount задается в ее минимальных единицах.
+	Currency  string    `json:"currency" db:"currency"`
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	// ReferenceTransactionID — transaction_id ставки, которую отменяет rollback или возвращает refund.
+	ReferenceTransactionID string `json:"reference_transaction_id,omitempty" db:"reference_transaction_id"`
+	// Игровой контекст: раунд, игра, провайдер и игровая сессия. Необязательны.
+	RoundID    string `json:"round_id,omitempty" db:"round_id"`
+	GameID     string `json:"game_id,omitempty" db:"game_id"`
+	ProviderID string `json:"provider_id,omitempty" db:"provider_id"`
+	SessionID  string `json:"session_id,omitempty" db:"session_id"`
+}
+
+// TransactionDetails — транзакция вместе со связанными с ней транзакциями.
+type TransactionDetails struct {
+	Transaction
+	// Reversals — возвраты и отмена ставки в порядке совершения; пуст для остальных типов.
+	Reversals []Transaction `json:"reversals"`
 }
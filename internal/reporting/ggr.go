@@ -0,0 +1,74 @@
+package reporting
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
+)
+
+var (
+	ErrInvalidGranularity = errors.New("granularity must be day or hour")
+	ErrInvalidRange       = errors.New("from must be before to")
+)
+
+// Reporter строит отчеты по агрегатам, которые обновляются при сохранении транзакций,
+// не обращаясь к таблице transactions.
+type Reporter struct {
+	repo repository.ReportRepository
+}
+
+func NewReporter(repo repository.ReportRepository) *Reporter {
+	return &Reporter{repo: repo}
+}
+
+// GGR возвращает отчет о GGR за [from, to) с шагом granularity.
+// Границы расширяются до целых периодов: from округляется вниз, to — вверх.
+func (r *Reporter) GGR(ctx context.Context, from, to time.Time, granularity models.Granularity) (models.GGRReport, error) {
+	if !granularity.Valid() {
+		return models.GGRReport{}, ErrInvalidGranularity
+	}
+	if !from.Before(to) {
+		return models.GGRReport{}, ErrInvalidRange
+	}
+
+	from = granularity.Truncate(from)
+	if start := granularity.Truncate(to); start.Before(to) {
+		to = granularity.Next(start)
+	} else {
+		to = start
+	}
+
+	rows, err := r.repo.GetGGR(ctx, granularity, from, to)
+	if err != nil {
+		return models.GGRReport{}, err
+	}
+
+	return models.GGRReport{
+		From:        from,
+		To:          to,
+		Granularity: granularity,
+		Rows:        rows,
+		Totals:      totals(rows),
+	}, nil
+}
+
+// totals суммирует строки отчета по валютам, упорядочивая итоги по коду валюты.
+func totals(rows []models.GGRRow) []models.GGRTotal {
+	result := make([]models.GGRTotal, 0)
+	index := make(map[string]int)
+	for _, row := range rows {
+		i, ok := index[row.Currency]
+		if !ok {
+			i = len(result)
+			index[row.Currency] = i
+			result = append(result, models.GGRTotal{Currency: row.Currency})
+		}
+		result[i].Add(row.GGRAmounts)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Currency < result[j].Currency })
+	return result
+}
@@ -0,0 +1,73 @@
+package reporting
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReporter_GGR(t *testing.T) {
+	ctx := context.Background()
+	day1 := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	t.Run("should align range to whole periods and sum totals per currency", func(t *testing.T) {
+		repo := new(mocks.ReportRepository)
+		rows := []models.GGRRow{
+			{PeriodStart: day1, Currency: "USD", GGRAmounts: models.GGRAmounts{TotalWagered: 1000, TotalWon: 300, GGR: 700, BetCount: 2, WinCount: 1}},
+			{PeriodStart: day2, Currency: "EUR", GGRAmounts: models.GGRAmounts{TotalWagered: 500, TotalReversed: 500, BetCount: 1}},
+			{PeriodStart: day2, Currency: "USD", GGRAmounts: models.GGRAmounts{TotalWagered: 200, TotalWon: 900, GGR: -700, BetCount: 1, WinCount: 1}},
+		}
+		repo.On("GetGGR", ctx, models.GranularityDay, day1, day2.AddDate(0, 0, 1)).Return(rows, nil).Once()
+
+		report, err := NewReporter(repo).GGR(ctx, day1.Add(5*time.Hour), day2.Add(time.Minute), models.GranularityDay)
+		require.NoError(t, err)
+		assert.Equal(t, day1, report.From)
+		assert.Equal(t, day2.AddDate(0, 0, 1), report.To)
+		assert.Len(t, report.Rows, 3)
+		require.Len(t, report.Totals, 2)
+		assert.Equal(t, "EUR", report.Totals[0].Currency)
+		assert.Equal(t, "USD", report.Totals[1].Currency)
+		assert.Equal(t, int64(1200), report.Totals[1].TotalWagered)
+		assert.Equal(t, int64(0), report.Totals[1].GGR)
+		assert.Equal(t, int64(3), report.Totals[1].BetCount)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should keep aligned hourly range", func(t *testing.T) {
+		repo := new(mocks.ReportRepository)
+		repo.On("GetGGR", ctx, models.GranularityHour, day1, day1.Add(2*time.Hour)).Return([]models.GGRRow{}, nil).Once()
+
+		report, err := NewReporter(repo).GGR(ctx, day1, day1.Add(2*time.Hour), models.GranularityHour)
+		require.NoError(t, err)
+		assert.Empty(t, report.Rows)
+		assert.Empty(t, report.Totals)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should reject invalid parameters", func(t *testing.T) {
+		repo := new(mocks.ReportRepository)
+		reporter := NewReporter(repo)
+
+		_, err := reporter.GGR(ctx, day1, day2, models.Granularity("week"))
+		assert.ErrorIs(t, err, ErrInvalidGranularity)
+		_, err = reporter.GGR(ctx, day2, day1, models.GranularityDay)
+		assert.ErrorIs(t, err, ErrInvalidRange)
+		repo.AssertNotCalled(t, "GetGGR", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return repository error", func(t *testing.T) {
+		repo := new(mocks.ReportRepository)
+		repo.On("GetGGR", ctx, models.GranularityDay, day1, day2).Return([]models.GGRRow(nil), errors.New("database is down")).Once()
+
+		_, err := NewReporter(repo).GGR(ctx, day1, day2, models.GranularityDay)
+		assert.Error(t, err)
+	})
+}
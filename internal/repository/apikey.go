@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrAPIKeyNotFound возвращается, если действующего ключа с таким хешем нет.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepository interface {
+	// GetAPIKey возвращает неотозванный ключ по SHA-256 в hex.
+	GetAPIKey(ctx context.Context, keyHash string) (models.APIKey, error)
+}
+
+type postgresAPIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresAPIKeyRepository(db *pgxpool.Pool) APIKeyRepository {
+	return &postgresAPIKeyRepository{db: db}
+}
+
+func (r *postgresAPIKeyRepository) GetAPIKey(ctx context.Context, keyHash string) (models.APIKey, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, name, key_hash, scopes, roles, created_at, revoked_at FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL
+	`, keyHash)
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("could not query api key: %w", err)
+	}
+	key, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.APIKey])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("could not scan api key: %w", err)
+	}
+	return key, nil
+}
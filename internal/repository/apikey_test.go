@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresAPIKeyRepository(t *testing.T) {
+	ctx := context.Background()
+	dbpool, cleanup := setupTestDB(ctx)
+	defer cleanup()
+
+	repo := NewPostgresAPIKeyRepository(dbpool)
+	const activeHash = "a3f1c2d4e5b6a7980112233445566778899aabbccddeeff00112233445566778"
+	const revokedHash = "0000000000000000000000000000000000000000000000000000000000000001"
+
+	_, err := dbpool.Exec(ctx, `
+		INSERT INTO api_keys (name, key_hash, scopes, roles, revoked_at) VALUES
+			('backoffice', $1, '{transactions:read}', '{support,finance}', NULL),
+			('old-backoffice', $2, '{transactions:read}', '{}', now())
+	`, activeHash, revokedHash)
+	require.NoError(t, err)
+
+	t.Run("should return active key by hash", func(t *testing.T) {
+		key, err := repo.GetAPIKey(ctx, activeHash)
+		require.NoError(t, err)
+		assert.Equal(t, "backoffice", key.Name)
+		assert.Equal(t, []string{"transactions:read"}, key.Scopes)
+		assert.Equal(t, []string{"support", "finance"}, key.Roles)
+		assert.Nil(t, key.RevokedAt)
+	})
+
+	t.Run("should not return revoked or unknown keys", func(t *testing.T) {
+		_, err := repo.GetAPIKey(ctx, revokedHash)
+		assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+		_, err = repo.GetAPIKey(ctx, "ffff")
+		assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	})
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditRepository interface {
+	// SaveAccessDenial записывает отказ в доступе в журнал аудита.
+	SaveAccessDenial(ctx context.Context, denial models.AccessDenial) error
+}
+
+type postgresAuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresAuditRepository(db *pgxpool.Pool) AuditRepository {
+	return &postgresAuditRepository{db: db}
+}
+
+func (r *postgresAuditRepository) SaveAccessDenial(ctx context.Context, d models.AccessDenial) error {
+	roles := d.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO access_denials (subject, auth_method, roles, permission, http_method, path, request_id, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, d.Subject, d.AuthMethod, roles, d.Permission, d.HTTPMethod, d.Path, d.RequestID, d.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("could not save access denial: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresAuditRepository(t *testing.T) {
+	ctx := context.Background()
+	dbpool, cleanup := setupTestDB(ctx)
+	defer cleanup()
+
+	repo := NewPostgresAuditRepository(dbpool)
+	occurredAt := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repo.SaveAccessDenial(ctx, models.AccessDenial{
+		Subject: "agent-1", AuthMethod: "api_key", Roles: []string{"support"}, Permission: "transactions:read",
+		HTTPMethod: "GET", Path: "/transactions", RequestID: "req-1", OccurredAt: occurredAt,
+	}))
+	require.NoError(t, repo.SaveAccessDenial(ctx, models.AccessDenial{
+		Subject: "user-42", AuthMethod: "jwt", Permission: "users:read",
+		HTTPMethod: "GET", Path: "/users/user-43/balance", OccurredAt: occurredAt,
+	}))
+
+	rows, err := dbpool.Query(ctx, `SELECT * FROM access_denials ORDER BY id`)
+	require.NoError(t, err)
+	denials, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.AccessDenial])
+	require.NoError(t, err)
+
+	require.Len(t, denials, 2)
+	assert.Equal(t, "agent-1", denials[0].Subject)
+	assert.Equal(t, []string{"support"}, denials[0].Roles)
+	assert.Equal(t, "req-1", denials[0].RequestID)
+	assert.True(t, occurredAt.Equal(denials[0].OccurredAt))
+	assert.Empty(t, denials[1].Roles)
+	assert.Equal(t, "/users/user-43/balance", denials[1].Path)
+}
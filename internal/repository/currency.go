@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrUnknownCurrency возвращается, если валюты транзакции нет в таблице currencies.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+const (
+	pgCodeForeignKeyViolation = "23503"
+	// Внешние ключи, связывающие валюту транзакции и лимита с таблицей currencies.
+	transactionsCurrencyFK = "transactions_currency_fkey"
+	userLimitsCurrencyFK   = "user_limits_currency_fkey"
+)
+
+type CurrencyRepository interface {
+	// GetCurrencies возвращает поддерживаемые валюты, упорядоченные по коду.
+	GetCurrencies(ctx context.Context) ([]models.Currency, error)
+}
+
+type postgresCurrencyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresCurrencyRepository(db *pgxpool.Pool) CurrencyRepository {
+	return &postgresCurrencyRepository{db: db}
+}
+
+func (r *postgresCurrencyRepository) GetCurrencies(ctx context.Context) ([]models.Currency, error) {
+	rows, err := r.db.Query(ctx, `SELECT code, minor_units FROM currencies ORDER BY code`)
+	if err != nil {
+		return nil, fmt.Errorf("could not query currencies: %w", err)
+	}
+	currencies, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Currency])
+	if err != nil {
+		return nil, fmt.Errorf("could not scan currencies: %w", err)
+	}
+	return currencies, nil
+}
+
+// asCurrencyError превращает нарушение внешнего ключа на валюту в ErrUnknownCurrency.
+func asCurrencyError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgCodeForeignKeyViolation &&
+		(pgErr.ConstraintName == transactionsCurrencyFK || pgErr.ConstraintName == userLimitsCurrencyFK) {
+		return fmt.Errorf("%w: %s", ErrUnknownCurrency, pgErr.Detail)
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Коды ошибок PostgreSQL, после которых операцию имеет смысл повторить.
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgCodeSerializationFailure = "40001"
+	pgCodeDeadlockDetected     = "40P01"
+	pgCodeLockNotAvailable     = "55P03"
+	pgCodeTooManyConnections   = "53300"
+	pgCodeAdminShutdown        = "57P01"
+	pgCodeCrashShutdown        = "57P02"
+	pgCodeCannotConnectNow     = "57P03"
+	// Класс 08 — ошибки соединения.
+	pgClassConnectionException = "08"
+)
+
+// IsTransient сообщает, может ли повтор операции завершиться успешно:
+// потеря соединения, таймаут, конфликт сериализации, взаимоблокировка.
+// Нарушения ограничений (например, CHECK), некорректные данные,
+// а также ошибки, для которых IsRejected возвращает true, считаются постоянными.
+func IsTransient(err error) bool {
+	if err == nil || IsRejected(err) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgCodeSerializationFailure, pgCodeDeadlockDetected, pgCodeLockNotAvailable,
+			pgCodeTooManyConnections, pgCodeAdminShutdown, pgCodeCrashShutdown, pgCodeCannotConnectNow:
+			return true
+		}
+		return strings.HasPrefix(pgErr.Code, pgClassConnectionException)
+	}
+
+	if pgconn.Timeout(err) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// IsRejected сообщает, что транзакция отклонена бизнес-правилами
+// и повторная попытка сохранения не изменит результат.
+func IsRejected(err error) bool {
+	return errors.Is(err, ErrInsufficientFunds) || errors.Is(err, ErrInvalidReference) ||
+		errors.Is(err, ErrUnknownCurrency) || errors.Is(err, ErrInvalidRound)
+}
+
+// asRejectedError превращает нарушения ограничений БД, означающие отказ
+// по бизнес-правилам, в соответствующие ошибки репозитория.
+func asRejectedError(err error) error {
+	return asCurrencyError(asReferenceError(err))
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, true},
+		{"wrapped serialization failure", fmt.Errorf("could not save transaction: %w", &pgconn.PgError{Code: "40001"}), true},
+		{"check violation", &pgconn.PgError{Code: "23514"}, false},
+		{"not null violation", &pgconn.PgError{Code: "23502"}, false},
+		{"invalid text representation", &pgconn.PgError{Code: "22P02"}, false},
+		{"insufficient funds", fmt.Errorf("could not save transaction: %w", ErrInsufficientFunds), false},
+		{"invalid reference", fmt.Errorf("could not save transaction: %w", ErrInvalidReference), false},
+		{"unknown currency", fmt.Errorf("could not save transaction: %w", ErrUnknownCurrency), false},
+		{"invalid round", fmt.Errorf("could not save transaction: %w", ErrInvalidRound), false},
+		{"network error", &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, true},
+		{"unexpected eof", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"context canceled", context.Canceled, false},
+		{"unknown error", errors.New("something else"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsTransient(tt.err))
+		})
+	}
+}
+
+func TestAsRejectedError(t *testing.T) {
+	currencyErr := asRejectedError(&pgconn.PgError{Code: "23503", ConstraintName: "transactions_currency_fkey"})
+	assert.ErrorIs(t, currencyErr, ErrUnknownCurrency)
+	assert.True(t, IsRejected(currencyErr))
+
+	rollbackErr := asRejectedError(&pgconn.PgError{Code: "23505", ConstraintName: "idx_transactions_rollback_reference"})
+	assert.ErrorIs(t, rollbackErr, ErrInvalidReference)
+
+	other := &pgconn.PgError{Code: "23503", ConstraintName: "some_other_fkey"}
+	assert.Same(t, other, asRejectedError(other))
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+)
+
+// TransactionFilter описывает условия выборки транзакций.
+// Пустые поля не ограничивают выборку.
+type TransactionFilter struct {
+	Types []models.TransactionType
+	// Currencies — коды валют ISO 4217.
+	Currencies []string
+	// From включительно, To не включительно.
+	From *time.Time
+	To   *time.Time
+	// Границы суммы (в минимальных единицах валюты), обе включительно.
+	MinAmount *int64
+	MaxAmount *int64
+	// TransactionIDPrefix ищет транзакции, transaction_id которых начинается с этой строки.
+	TransactionIDPrefix string
+	// Точное совпадение игрового контекста.
+	RoundID    string
+	GameID     string
+	ProviderID string
+	SessionID  string
+}
+
+func (f TransactionFilter) apply(q *queryBuilder) {
+	if len(f.Types) > 0 {
+		types := make([]string, len(f.Types))
+		for i, t := range f.Types {
+			types[i] = string(t)
+		}
+		q.where("transaction_type = ANY(?)", types)
+	}
+	if len(f.Currencies) > 0 {
+		q.where("currency = ANY(?)", f.Currencies)
+	}
+	if f.From != nil {
+		q.where(`"timestamp" >= ?`, *f.From)
+	}
+	if f.To != nil {
+		q.where(`"timestamp" < ?`, *f.To)
+	}
+	if f.MinAmount != nil {
+		q.where("amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		q.where("amount <= ?", *f.MaxAmount)
+	}
+	if f.TransactionIDPrefix != "" {
+		q.where(`transaction_id LIKE ? ESCAPE '\'`, escapeLike(f.TransactionIDPrefix)+"%")
+	}
+	if f.RoundID != "" {
+		q.where("round_id = ?", f.RoundID)
+	}
+	if f.GameID != "" {
+		q.where("game_id = ?", f.GameID)
+	}
+	if f.ProviderID != "" {
+		q.where("provider_id = ?", f.ProviderID)
+	}
+	if f.SessionID != "" {
+		q.where("session_id = ?", f.SessionID)
+	}
+}
+
+// queryBuilder собирает WHERE-условия и нумерует плейсхолдеры ($1, $2, ...).
+// В условиях вместо плейсхолдеров используется "?", значения передаются только как параметры.
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+func (q *queryBuilder) where(condition string, args ...any) {
+	var sb strings.Builder
+	for _, part := range strings.SplitAfter(condition, "?") {
+		if !strings.HasSuffix(part, "?") {
+			sb.WriteString(part)
+			continue
+		}
+		q.args = append(q.args, args[0])
+		args = args[1:]
+		sb.WriteString(strings.TrimSuffix(part, "?"))
+		sb.WriteString("$" + strconv.Itoa(len(q.args)))
+	}
+	q.conditions = append(q.conditions, sb.String())
+}
+
+// arg добавляет параметр без условия и возвращает его плейсхолдер.
+func (q *queryBuilder) arg(value any) string {
+	q.args = append(q.args, value)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// whereClause возвращает " WHERE ..." или пустую строку, если условий нет.
+func (q *queryBuilder) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы префикс сравнивался буквально.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionFilter_Apply(t *testing.T) {
+	t.Run("should produce no conditions for empty filter", func(t *testing.T) {
+		var q queryBuilder
+		TransactionFilter{}.apply(&q)
+
+		assert.Empty(t, q.whereClause())
+		assert.Empty(t, q.args)
+	})
+
+	t.Run("should number placeholders in order", func(t *testing.T) {
+		from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := from.Add(24 * time.Hour)
+		minAmount, maxAmount := int64(10), int64(20)
+
+		var q queryBuilder
+		q.where("user_id = ?", "user-1")
+		TransactionFilter{
+			Types:               []models.TransactionType{models.TransactionTypeBet},
+			Currencies:          []string{"EUR", "USD"},
+			From:                &from,
+			To:                  &to,
+			MinAmount:           &minAmount,
+			MaxAmount:           &maxAmount,
+			TransactionIDPrefix: "tx_50%",
+		}.apply(&q)
+
+		assert.Equal(t,
+			` WHERE user_id = $1 AND transaction_type = ANY($2) AND currency = ANY($3) AND "timestamp" >= $4`+
+				` AND "timestamp" < $5 AND amount >= $6 AND amount <= $7 AND transaction_id LIKE $8 ESCAPE '\'`,
+			q.whereClause())
+		assert.Equal(t, []any{"user-1", []string{"bet"}, []string{"EUR", "USD"}, from, to, int64(10), int64(20), `tx\_50\%%`}, q.args)
+		assert.Equal(t, "$9", q.arg(51))
+	})
+
+	t.Run("should match game context exactly", func(t *testing.T) {
+		var q queryBuilder
+		TransactionFilter{RoundID: "r-1", GameID: "g-1", ProviderID: "p-1", SessionID: "s-1"}.apply(&q)
+
+		assert.Equal(t, " WHERE round_id = $1 AND game_id = $2 AND provider_id = $3 AND session_id = $4", q.whereClause())
+		assert.Equal(t, []any{"r-1", "g-1", "p-1", "s-1"}, q.args)
+	})
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+)
+
+// QueryObserver получает длительность и результат каждого вызова метода репозитория.
+type QueryObserver interface {
+	ObserveQuery(repository, method string, duration time.Duration, err error)
+}
+
+// observe замеряет вызов, начатый в start; вызывается через defer с указателем на ошибку.
+func observe(o QueryObserver, repository, method string, start time.Time, err *error) {
+	o.ObserveQuery(repository, method, time.Since(start), *err)
+}
+
+type instrumentedTransactionRepository struct {
+	next TransactionRepository
+	obs  QueryObserver
+}
+
+// InstrumentTransactionRepository сообщает obs о каждом вызове методов repo.
+func InstrumentTransactionRepository(repo TransactionRepository, obs QueryObserver) TransactionRepository {
+	return &instrumentedTransactionRepository{next: repo, obs: obs}
+}
+
+func (r *instrumentedTransactionRepository) SaveTransaction(ctx context.Context, tx models.Transaction) (err error) {
+	defer observe(r.obs, "transaction", "SaveTransaction", time.Now(), &err)
+	return r.next.SaveTransaction(ctx, tx)
+}
+
+func (r *instrumentedTransactionRepository) CreateTransaction(ctx context.Context, tx models.Transaction) (_ bool, err error) {
+	defer observe(r.obs, "transaction", "CreateTransaction", time.Now(), &err)
+	return r.next.CreateTransaction(ctx, tx)
+}
+
+func (r *instrumentedTransactionRepository) SaveTransactions(ctx context.Context, txs []models.Transaction) (err error) {
+	defer observe(r.obs, "transaction", "SaveTransactions", time.Now(), &err)
+	return r.next.SaveTransactions(ctx, txs)
+}
+
+func (r *instrumentedTransactionRepository) CreateTransactions(ctx context.Context, txs []models.Transaction) (_ []string, err error) {
+	defer observe(r.obs, "transaction", "CreateTransactions", time.Now(), &err)
+	return r.next.CreateTransactions(ctx, txs)
+}
+
+func (r *instrumentedTransactionRepository) GetTransactionsByUserID(ctx context.Context, userID string, filter TransactionFilter, page PageRequest) (_ TransactionPage, err error) {
+	defer observe(r.obs, "transaction", "GetTransactionsByUserID", time.Now(), &err)
+	return r.next.GetTransactionsByUserID(ctx, userID, filter, page)
+}
+
+func (r *instrumentedTransactionRepository) GetAllTransactions(ctx context.Context, filter TransactionFilter, page PageRequest) (_ TransactionPage, err error) {
+	defer observe(r.obs, "transaction", "GetAllTransactions", time.Now(), &err)
+	return r.next.GetAllTransactions(ctx, filter, page)
+}
+
+func (r *instrumentedTransactionRepository) StreamTransactions(ctx context.Context, userID string, filter TransactionFilter, fn func(models.Transaction) error) (err error) {
+	defer observe(r.obs, "transaction", "StreamTransactions", time.Now(), &err)
+	return r.next.StreamTransactions(ctx, userID, filter, fn)
+}
+
+func (r *instrumentedTransactionRepository) GetTransactionByID(ctx context.Context, transactionID string) (_ models.TransactionDetails, err error) {
+	defer observe(r.obs, "transaction", "GetTransactionByID", time.Now(), &err)
+	return r.next.GetTransactionByID(ctx, transactionID)
+}
+
+func (r *instrumentedTransactionRepository) GetRound(ctx context.Context, roundID string) (_ models.Round, err error) {
+	defer observe(r.obs, "transaction", "GetRound", time.Now(), &err)
+	return r.next.GetRound(ctx, roundID)
+}
+
+func (r *instrumentedTransactionRepository) GetUserSummary(ctx context.Context, userID string, from, to *time.Time) (_ models.UserSummary, err error) {
+	defer observe(r.obs, "transaction", "GetUserSummary", time.Now(), &err)
+	return r.next.GetUserSummary(ctx, userID, from, to)
+}
+
+type instrumentedWalletRepository struct {
+	next WalletRepository
+	obs  QueryObserver
+}
+
+// InstrumentWalletRepository сообщает obs о каждом вызове методов repo.
+func InstrumentWalletRepository(repo WalletRepository, obs QueryObserver) WalletRepository {
+	return &instrumentedWalletRepository{next: repo, obs: obs}
+}
+
+func (r *instrumentedWalletRepository) GetBalances(ctx context.Context, userID string) (_ []models.Balance, err error) {
+	defer observe(r.obs, "wallet", "GetBalances", time.Now(), &err)
+	return r.next.GetBalances(ctx, userID)
+}
+
+type instrumentedLimitRepository struct {
+	next LimitRepository
+	obs  QueryObserver
+}
+
+// InstrumentLimitRepository сообщает obs о каждом вызове методов repo.
+func InstrumentLimitRepository(repo LimitRepository, obs QueryObserver) LimitRepository {
+	return &instrumentedLimitRepository{next: repo, obs: obs}
+}
+
+func (r *instrumentedLimitRepository) GetUserLimits(ctx context.Context, userID string) (_ models.UserLimits, err error) {
+	defer observe(r.obs, "limit", "GetUserLimits", time.Now(), &err)
+	return r.next.GetUserLimits(ctx, userID)
+}
+
+func (r *instrumentedLimitRepository) SetLimits(ctx context.Context, userID string, limits []models.Limit) (err error) {
+	defer observe(r.obs, "limit", "SetLimits", time.Now(), &err)
+	return r.next.SetLimits(ctx, userID, limits)
+}
+
+func (r *instrumentedLimitRepository) SetSelfExclusion(ctx context.Context, userID string, excludedUntil time.Time) (err error) {
+	defer observe(r.obs, "limit", "SetSelfExclusion", time.Now(), &err)
+	return r.next.SetSelfExclusion(ctx, userID, excludedUntil)
+}
+
+func (r *instrumentedLimitRepository) GetPlayTotals(ctx context.Context, userID, currency string, from, to time.Time) (_ models.PlayTotals, err error) {
+	defer observe(r.obs, "limit", "GetPlayTotals", time.Now(), &err)
+	return r.next.GetPlayTotals(ctx, userID, currency, from, to)
+}
+
+func (r *instrumentedLimitRepository) TransactionExists(ctx context.Context, transactionID string) (_ bool, err error) {
+	defer observe(r.obs, "limit", "TransactionExists", time.Now(), &err)
+	return r.next.TransactionExists(ctx, transactionID)
+}
+
+func (r *instrumentedLimitRepository) SaveLimitViolation(ctx context.Context, violation models.LimitViolation) (err error) {
+	defer observe(r.obs, "limit", "SaveLimitViolation", time.Now(), &err)
+	return r.next.SaveLimitViolation(ctx, violation)
+}
+
+type instrumentedCurrencyRepository struct {
+	next CurrencyRepository
+	obs  QueryObserver
+}
+
+// InstrumentCurrencyRepository сообщает obs о каждом вызове методов repo.
+func InstrumentCurrencyRepository(repo CurrencyRepository, obs QueryObserver) CurrencyRepository {
+	return &instrumentedCurrencyRepository{next: repo, obs: obs}
+}
+
+func (r *instrumentedCurrencyRepository) GetCurrencies(ctx context.Context) (_ []models.Currency, err error) {
+	defer observe(r.obs, "currency", "GetCurrencies", time.Now(), &err)
+	return r.next.GetCurrencies(ctx)
+}
+
+type instrumentedReportRepository struct {
+	next ReportRepository
+	obs  QueryObserver
+}
+
+// InstrumentReportRepository сообщает obs о каждом вызове методов repo.
+func InstrumentReportRepository(repo ReportRepository, obs QueryObserver) ReportRepository {
+	return &instrumentedReportRepository{next: repo, obs: obs}
+}
+
+func (r *instrumentedReportRepository) GetGGR(ctx context.Context, granularity models.Granularity, from, to time.Time) (_ []models.GGRRow, err error) {
+	defer observe(r.obs, "report", "GetGGR", time.Now(), &err)
+	return r.next.GetGGR(ctx, granularity, from, to)
+}
+
+type instrumentedAPIKeyRepository struct {
+	next APIKeyRepository
+	obs  QueryObserver
+}
+
+// InstrumentAPIKeyRepository сообщает obs о каждом вызове методов repo.
+func InstrumentAPIKeyRepository(repo APIKeyRepository, obs QueryObserver) APIKeyRepository {
+	return &instrumentedAPIKeyRepository{next: repo, obs: obs}
+}
+
+func (r *instrumentedAPIKeyRepository) GetAPIKey(ctx context.Context, keyHash string) (_ models.APIKey, err error) {
+	defer observe(r.obs, "api_key", "GetAPIKey", time.Now(), &err)
+	return r.next.GetAPIKey(ctx, keyHash)
+}
+
+type instrumentedAuditRepository struct {
+	next AuditRepository
+	obs  QueryObserver
+}
+
+// InstrumentAuditRepository сообщает obs о каждом вызове методов repo.
+func InstrumentAuditRepository(repo AuditRepository, obs QueryObserver) AuditRepository {
+	return &instrumentedAuditRepository{next: repo, obs: obs}
+}
+
+func (r *instrumentedAuditRepository) SaveAccessDenial(ctx context.Context, denial models.AccessDenial) (err error) {
+	defer observe(r.obs, "audit", "SaveAccessDenial", time.Now(), &err)
+	return r.next.SaveAccessDenial(ctx, denial)
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LimitRepository interface {
+	// GetUserLimits возвращает лимиты и самоисключение пользователя.
+	// У пользователя без ограничений список лимитов пуст.
+	GetUserLimits(ctx context.Context, userID string) (models.UserLimits, error)
+	// SetLimits заменяет все лимиты пользователя на limits.
+	SetLimits(ctx context.Context, userID string, limits []models.Limit) error
+	// SetSelfExclusion исключает пользователя из игры до excludedUntil.
+	SetSelfExclusion(ctx context.Context, userID string, excludedUntil time.Time) error
+	// GetPlayTotals суммирует игровые транзакции пользователя в валюте currency
+	// за период [from, to).
+	GetPlayTotals(ctx context.Context, userID, currency string, from, to time.Time) (models.PlayTotals, error)
+	// TransactionExists сообщает, сохранена ли уже транзакция с таким transaction_id.
+	TransactionExists(ctx context.Context, transactionID string) (bool, error)
+	SaveLimitViolation(ctx context.Context, violation models.LimitViolation) error
+}
+
+type postgresLimitRepository struct {
+	db querier
+}
+
+// querier — общие методы *pgxpool.Pool и pgx.Tx. Через pgx.Tx запросы репозитория
+// выполняются в уже открытой транзакции БД.
+type querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func NewPostgresLimitRepository(db *pgxpool.Pool) LimitRepository {
+	return &postgresLimitRepository{db: db}
+}
+
+func (r *postgresLimitRepository) GetUserLimits(ctx context.Context, userID string) (models.UserLimits, error) {
+	result := models.UserLimits{UserID: userID}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT limit_type, period, currency, amount, updated_at FROM user_limits
+		WHERE user_id = $1
+		ORDER BY currency, limit_type, period
+	`, userID)
+	if err != nil {
+		return models.UserLimits{}, fmt.Errorf("could not query limits: %w", err)
+	}
+	result.Limits, err = pgx.CollectRows(rows, pgx.RowToStructByName[models.Limit])
+	if err != nil {
+		return models.UserLimits{}, fmt.Errorf("could not scan limits: %w", err)
+	}
+
+	var exclusion models.SelfExclusion
+	err = r.db.QueryRow(ctx, `SELECT excluded_until, created_at FROM self_exclusions WHERE user_id = $1`, userID).
+		Scan(&exclusion.ExcludedUntil, &exclusion.CreatedAt)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return models.UserLimits{}, fmt.Errorf("could not query self-exclusion: %w", err)
+	default:
+		result.SelfExclusion = &exclusion
+	}
+	return result, nil
+}
+
+func (r *postgresLimitRepository) SetLimits(ctx context.Context, userID string, limits []models.Limit) error {
+	err := pgx.BeginFunc(ctx, r.db, func(dbTx pgx.Tx) error {
+		if _, err := dbTx.Exec(ctx, `DELETE FROM user_limits WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		if len(limits) == 0 {
+			return nil
+		}
+		batch := &pgx.Batch{}
+		for _, l := range limits {
+			batch.Queue(`
+				INSERT INTO user_limits (user_id, limit_type, period, currency, amount)
+				VALUES ($1, $2, $3, $4, $5)
+			`, userID, l.LimitType, l.Period, l.Currency, l.Amount)
+		}
+		return dbTx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		return fmt.Errorf("could not set limits: %w", asRejectedError(err))
+	}
+	return nil
+}
+
+func (r *postgresLimitRepository) SetSelfExclusion(ctx context.Context, userID string, excludedUntil time.Time) error {
+	// Действующее самоисключение можно только продлить, но не сократить.
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO self_exclusions (user_id, excluded_until) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET excluded_until = CASE
+				WHEN self_exclusions.excluded_until > now()
+					THEN GREATEST(self_exclusions.excluded_until, EXCLUDED.excluded_until)
+				ELSE EXCLUDED.excluded_until
+			END,
+			created_at = CASE
+				WHEN self_exclusions.excluded_until > now() THEN self_exclusions.created_at
+				ELSE now()
+			END
+	`, userID, excludedUntil)
+	if err != nil {
+		return fmt.Errorf("could not set self-exclusion: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresLimitRepository) GetPlayTotals(ctx context.Context, userID, currency string, from, to time.Time) (models.PlayTotals, error) {
+	var totals models.PlayTotals
+	err := r.db.QueryRow(ctx, `
+		SELECT
+			COALESCE(SUM(amount) FILTER (WHERE transaction_type = 'bet'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE transaction_type = 'win'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE transaction_type IN ('refund', 'rollback')), 0)
+		FROM transactions
+		WHERE user_id = $1 AND currency = $2 AND "timestamp" >= $3 AND "timestamp" < $4
+	`, userID, currency, from, to).Scan(&totals.Bets, &totals.Wins, &totals.Reversals)
+	if err != nil {
+		return models.PlayTotals{}, fmt.Errorf("could not sum play totals: %w", err)
+	}
+	return totals, nil
+}
+
+func (r *postgresLimitRepository) TransactionExists(ctx context.Context, transactionID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM transactions WHERE transaction_id = $1)`, transactionID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("could not check transaction: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *postgresLimitRepository) SaveLimitViolation(ctx context.Context, v models.LimitViolation) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO limit_violations (user_id, transaction_id, violation_type, period, currency,
+		                              limit_amount, current_amount, attempted_amount, occurred_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9)
+		ON CONFLICT (transaction_id, violation_type) DO NOTHING
+	`, v.UserID, v.TransactionID, v.ViolationType, v.Period, v.Currency,
+		v.LimitAmount, v.CurrentAmount, v.AttemptedAmount, v.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("could not save limit violation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLimitRepository(t *testing.T) {
+	ctx := context.Background()
+	dbpool, cleanup := setupTestDB(ctx)
+	defer cleanup()
+
+	txRepo := NewPostgresRepository(dbpool)
+	limitRepo := NewPostgresLimitRepository(dbpool)
+
+	t.Run("should return empty limits for unknown user", func(t *testing.T) {
+		limits, err := limitRepo.GetUserLimits(ctx, "limits-unknown")
+		require.NoError(t, err)
+		assert.Empty(t, limits.Limits)
+		assert.Nil(t, limits.SelfExclusion)
+	})
+
+	t.Run("should replace limits", func(t *testing.T) {
+		require.NoError(t, limitRepo.SetLimits(ctx, "limits-user", []models.Limit{
+			{LimitType: models.LimitTypeLoss, Period: models.LimitPeriodDay, Currency: "USD", Amount: 1000},
+			{LimitType: models.LimitTypeWager, Period: models.LimitPeriodMonth, Currency: "EUR", Amount: 50000},
+		}))
+		require.NoError(t, limitRepo.SetLimits(ctx, "limits-user", []models.Limit{
+			{LimitType: models.LimitTypeWager, Period: models.LimitPeriodWeek, Currency: "USD", Amount: 2000},
+		}))
+
+		limits, err := limitRepo.GetUserLimits(ctx, "limits-user")
+		require.NoError(t, err)
+		require.Len(t, limits.Limits, 1)
+		assert.Equal(t, models.LimitTypeWager, limits.Limits[0].LimitType)
+		assert.Equal(t, int64(2000), limits.Limits[0].Amount)
+		assert.NotNil(t, limits.Limits[0].UpdatedAt)
+	})
+
+	t.Run("should reject limit in unknown currency", func(t *testing.T) {
+		err := limitRepo.SetLimits(ctx, "limits-user", []models.Limit{
+			{LimitType: models.LimitTypeLoss, Period: models.LimitPeriodDay, Currency: "XXX", Amount: 1000},
+		})
+		require.ErrorIs(t, err, ErrUnknownCurrency)
+	})
+
+	t.Run("should only extend active self-exclusion", func(t *testing.T) {
+		long := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Microsecond)
+		require.NoError(t, limitRepo.SetSelfExclusion(ctx, "limits-user", long))
+		require.NoError(t, limitRepo.SetSelfExclusion(ctx, "limits-user", time.Now().Add(time.Hour)))
+
+		limits, err := limitRepo.GetUserLimits(ctx, "limits-user")
+		require.NoError(t, err)
+		require.NotNil(t, limits.SelfExclusion)
+		assert.True(t, long.Equal(limits.SelfExclusion.ExcludedUntil))
+	})
+
+	t.Run("should sum play totals within period and currency", func(t *testing.T) {
+		base := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+		txs := []models.Transaction{
+			{TransactionID: "limits-dep-1", UserID: "limits-player", TransactionType: models.TransactionTypeDeposit, Amount: 10000, Currency: "USD", Timestamp: base},
+			{TransactionID: "limits-bet-1", UserID: "limits-player", TransactionType: models.TransactionTypeBet, Amount: 700, Currency: "USD", Timestamp: base},
+			{TransactionID: "limits-win-1", UserID: "limits-player", TransactionType: models.TransactionTypeWin, Amount: 200, Currency: "USD", Timestamp: base},
+			{TransactionID: "limits-ref-1", UserID: "limits-player", TransactionType: models.TransactionTypeRefund, Amount: 100, Currency: "USD", Timestamp: base, ReferenceTransactionID: "limits-bet-1"},
+			// Вне периода
+			{TransactionID: "limits-bet-2", UserID: "limits-player", TransactionType: models.TransactionTypeBet, Amount: 300, Currency: "USD", Timestamp: base.Add(24 * time.Hour)},
+		}
+		require.NoError(t, txRepo.SaveTransactions(ctx, txs))
+
+		totals, err := limitRepo.GetPlayTotals(ctx, "limits-player", "USD", base.Add(-time.Hour), base.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, models.PlayTotals{Bets: 700, Wins: 200, Reversals: 100}, totals)
+		assert.Equal(t, int64(400), totals.Loss())
+
+		exists, err := limitRepo.TransactionExists(ctx, "limits-bet-1")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("should record violation once", func(t *testing.T) {
+		violation := models.LimitViolation{
+			UserID:          "limits-player",
+			TransactionID:   "limits-bet-3",
+			ViolationType:   models.ViolationLossLimit,
+			Period:          models.LimitPeriodDay,
+			Currency:        "USD",
+			LimitAmount:     500,
+			CurrentAmount:   400,
+			AttemptedAmount: 200,
+			OccurredAt:      time.Now(),
+		}
+		require.NoError(t, limitRepo.SaveLimitViolation(ctx, violation))
+		require.NoError(t, limitRepo.SaveLimitViolation(ctx, violation))
+
+		var count int
+		require.NoError(t, dbpool.QueryRow(ctx, "SELECT count(*) FROM limit_violations WHERE transaction_id = 'limits-bet-3'").Scan(&count))
+		assert.Equal(t, 1, count)
+	})
+	t.Run("should check concurrent saves of a user one at a time", func(t *testing.T) {
+		base := time.Now().UTC().Truncate(time.Microsecond)
+		require.NoError(t, txRepo.SaveTransaction(ctx, models.Transaction{
+			TransactionID: "limits-racer-dep", UserID: "limits-racer", TransactionType: models.TransactionTypeDeposit,
+			Amount: 10000, Currency: "USD", Timestamp: base,
+		}))
+		checked := NewPostgresRepository(dbpool, WithSaveChecker(betCap{userID: "limits-racer", max: 1000, from: base}))
+
+		// Без блокировки несколько проверок увидели бы одну и ту же сумму ставок
+		var wg sync.WaitGroup
+		var saved, rejected atomic.Int32
+		for i := range 10 {
+			wg.Go(func() {
+				_, err := checked.CreateTransaction(ctx, models.Transaction{
+					TransactionID: fmt.Sprintf("limits-racer-bet-%d", i), UserID: "limits-racer", TransactionType: models.TransactionTypeBet,
+					Amount: 300, Currency: "USD", Timestamp: base,
+				})
+				switch {
+				case err == nil:
+					saved.Add(1)
+				case errors.Is(err, errBetCap):
+					rejected.Add(1)
+				default:
+					t.Error(err)
+				}
+			})
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(3), saved.Load())
+		assert.Equal(t, int32(7), rejected.Load())
+	})
+	t.Run("should not count a redelivered transaction of the batch twice", func(t *testing.T) {
+		base := time.Now().UTC().Truncate(time.Microsecond)
+		require.NoError(t, txRepo.SaveTransaction(ctx, models.Transaction{
+			TransactionID: "limits-redelivered-dep", UserID: "limits-redelivered", TransactionType: models.TransactionTypeDeposit,
+			Amount: 10000, Currency: "USD", Timestamp: base,
+		}))
+		checked := NewPostgresRepository(dbpool, WithSaveChecker(betCap{userID: "limits-redelivered", max: 1000, from: base}))
+		bet := models.Transaction{
+			TransactionID: "limits-redelivered-bet-1", UserID: "limits-redelivered", TransactionType: models.TransactionTypeBet,
+			Amount: 600, Currency: "USD", Timestamp: base,
+		}
+		_, err := checked.CreateTransaction(ctx, bet)
+		require.NoError(t, err)
+
+		// Сохраненная ставка учитывается один раз: 600 + 300 не превышает лимит
+		created, err := checked.CreateTransactions(ctx, []models.Transaction{bet, {
+			TransactionID: "limits-redelivered-bet-2", UserID: "limits-redelivered", TransactionType: models.TransactionTypeBet,
+			Amount: 300, Currency: "USD", Timestamp: base,
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"limits-redelivered-bet-2"}, created)
+	})
+}
+
+var errBetCap = errors.New("bet cap exceeded")
+
+// betCap отклоняет ставки пользователя userID, если их сумма с from превысит max.
+type betCap struct {
+	userID string
+	max    int64
+	from   time.Time
+}
+
+func (c betCap) CheckSave(ctx context.Context, limits LimitRepository, tx models.Transaction, pending []models.Transaction) error {
+	if tx.UserID != c.userID || tx.TransactionType != models.TransactionTypeBet {
+		return nil
+	}
+	totals, err := limits.GetPlayTotals(ctx, tx.UserID, tx.Currency, c.from, c.from.Add(time.Hour))
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		totals.Add(p)
+	}
+	if totals.Bets+tx.Amount > c.max {
+		return errBetCap
+	}
+	return nil
+}
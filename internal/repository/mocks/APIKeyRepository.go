@@ -0,0 +1,17 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type APIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *APIKeyRepository) GetAPIKey(ctx context.Context, keyHash string) (models.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	return args.Get(0).(models.APIKey), args.Error(1)
+}
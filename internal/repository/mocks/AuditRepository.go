@@ -0,0 +1,17 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type AuditRepository struct {
+	mock.Mock
+}
+
+func (m *AuditRepository) SaveAccessDenial(ctx context.Context, denial models.AccessDenial) error {
+	args := m.Called(ctx, denial)
+	return args.Error(0)
+}
@@ -0,0 +1,17 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type CurrencyRepository struct {
+	mock.Mock
+}
+
+func (m *CurrencyRepository) GetCurrencies(ctx context.Context) ([]models.Currency, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Currency), args.Error(1)
+}
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type LimitRepository struct {
+	mock.Mock
+}
+
+func (m *LimitRepository) GetUserLimits(ctx context.Context, userID string) (models.UserLimits, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(models.UserLimits), args.Error(1)
+}
+
+func (m *LimitRepository) SetLimits(ctx context.Context, userID string, limits []models.Limit) error {
+	args := m.Called(ctx, userID, limits)
+	return args.Error(0)
+}
+
+func (m *LimitRepository) SetSelfExclusion(ctx context.Context, userID string, excludedUntil time.Time) error {
+	args := m.Called(ctx, userID, excludedUntil)
+	return args.Error(0)
+}
+
+func (m *LimitRepository) GetPlayTotals(ctx context.Context, userID, currency string, from, to time.Time) (models.PlayTotals, error) {
+	args := m.Called(ctx, userID, currency, from, to)
+	return args.Get(0).(models.PlayTotals), args.Error(1)
+}
+
+func (m *LimitRepository) TransactionExists(ctx context.Context, transactionID string) (bool, error) {
+	args := m.Called(ctx, transactionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *LimitRepository) SaveLimitViolation(ctx context.Context, violation models.LimitViolation) error {
+	args := m.Called(ctx, violation)
+	return args.Error(0)
+}
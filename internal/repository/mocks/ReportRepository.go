@@ -0,0 +1,18 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type ReportRepository struct {
+	mock.Mock
+}
+
+func (m *ReportRepository) GetGGR(ctx context.Context, granularity models.Granularity, from, to time.Time) ([]models.GGRRow, error) {
+	args := m.Called(ctx, granularity, from, to)
+	return args.Get(0).([]models.GGRRow), args.Error(1)
+}
@@ -2,8 +2,10 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/internal/repository"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -16,12 +18,54 @@ func (m *TransactionRepository) SaveTransaction(ctx context.Context, tx models.T
 	return args.Error(0)
 }
 
-func (m *TransactionRepository) GetTransactionsByUserID(ctx context.Context, userID string, txType string) ([]models.Transaction, error) {
-	args := m.Called(ctx, userID, txType)
-	return args.Get(0).([]models.Transaction), args.Error(1)
+func (m *TransactionRepository) CreateTransaction(ctx context.Context, tx models.Transaction) (bool, error) {
+	args := m.Called(ctx, tx)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *TransactionRepository) SaveTransactions(ctx context.Context, txs []models.Transaction) error {
+	args := m.Called(ctx, txs)
+	return args.Error(0)
+}
+
+func (m *TransactionRepository) CreateTransactions(ctx context.Context, txs []models.Transaction) ([]string, error) {
+	args := m.Called(ctx, txs)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *TransactionRepository) GetTransactionsByUserID(ctx context.Context, userID string, filter repository.TransactionFilter, page repository.PageRequest) (repository.TransactionPage, error) {
+	args := m.Called(ctx, userID, filter, page)
+	return args.Get(0).(repository.TransactionPage), args.Error(1)
+}
+
+func (m *TransactionRepository) GetAllTransactions(ctx context.Context, filter repository.TransactionFilter, page repository.PageRequest) (repository.TransactionPage, error) {
+	args := m.Called(ctx, filter, page)
+	return args.Get(0).(repository.TransactionPage), args.Error(1)
+}
+
+// StreamTransactions передает в fn транзакции, заданные первым возвращаемым значением,
+// и затем возвращает второе.
+func (m *TransactionRepository) StreamTransactions(ctx context.Context, userID string, filter repository.TransactionFilter, fn func(models.Transaction) error) error {
+	args := m.Called(ctx, userID, filter)
+	for _, tx := range args.Get(0).([]models.Transaction) {
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *TransactionRepository) GetTransactionByID(ctx context.Context, transactionID string) (models.TransactionDetails, error) {
+	args := m.Called(ctx, transactionID)
+	return args.Get(0).(models.TransactionDetails), args.Error(1)
+}
+
+func (m *TransactionRepository) GetRound(ctx context.Context, roundID string) (models.Round, error) {
+	args := m.Called(ctx, roundID)
+	return args.Get(0).(models.Round), args.Error(1)
 }
 
-func (m *TransactionRepository) GetAllTransactions(ctx context.Context, txType string) ([]models.Transaction, error) {
-	args := m.Called(ctx, txType)
-	return args.Get(0).([]models.Transaction), args.Error(1)
+func (m *TransactionRepository) GetUserSummary(ctx context.Context, userID string, from, to *time.Time) (models.UserSummary, error) {
+	args := m.Called(ctx, userID, from, to)
+	return args.Get(0).(models.UserSummary), args.Error(1)
 }
@@ -0,0 +1,17 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type WalletRepository struct {
+	mock.Mock
+}
+
+func (m *WalletRepository) GetBalances(ctx context.Context, userID string) ([]models.Balance, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Balance), args.Error(1)
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+)
+
+const (
+	// DefaultPageLimit используется, если клиент не передал limit.
+	DefaultPageLimit = 50
+	// MaxPageLimit — серверный предел размера страницы.
+	MaxPageLimit = 500
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor указывает на последнюю строку предыдущей страницы.
+// Порядок выдачи: "timestamp" DESC, id DESC.
+type Cursor struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// Encode возвращает непрозрачное строковое представление курсора.
+func (c Cursor) Encode() string {
+	raw := strconv.FormatInt(c.Timestamp.UnixNano(), 10) + ":" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor разбирает курсор, полученный от клиента.
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	tsPart, idPart, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(tsPart, 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id <= 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{Timestamp: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
+
+// PageRequest описывает запрашиваемую страницу.
+type PageRequest struct {
+	Limit int
+	// After — курсор последней строки предыдущей страницы; nil для первой страницы.
+	After *Cursor
+}
+
+// limit возвращает размер страницы с учетом значения по умолчанию и серверного предела.
+func (p PageRequest) limit() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultPageLimit
+	case p.Limit > MaxPageLimit:
+		return MaxPageLimit
+	default:
+		return p.Limit
+	}
+}
+
+// TransactionPage — страница транзакций и курсор следующей страницы.
+type TransactionPage struct {
+	Transactions []models.Transaction `json:"transactions"`
+	// NextCursor пуст, если это последняя страница.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
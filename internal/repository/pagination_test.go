@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	t.Run("should round-trip through encoding", func(t *testing.T) {
+		cursor := Cursor{Timestamp: time.Date(2025, 3, 14, 15, 9, 26, 535897000, time.UTC), ID: 9001}
+
+		decoded, err := DecodeCursor(cursor.Encode())
+		require.NoError(t, err)
+		assert.True(t, cursor.Timestamp.Equal(decoded.Timestamp))
+		assert.Equal(t, cursor.ID, decoded.ID)
+	})
+
+	t.Run("should reject malformed cursors", func(t *testing.T) {
+		for _, raw := range []string{"", "!!!", "bm9jb2xvbg", "MTIzOmFiYw", "YWJjOjE", "MTIzOjA"} {
+			_, err := DecodeCursor(raw)
+			assert.ErrorIs(t, err, ErrInvalidCursor, raw)
+		}
+	})
+}
+
+func TestPageRequest_Limit(t *testing.T) {
+	assert.Equal(t, DefaultPageLimit, PageRequest{}.limit())
+	assert.Equal(t, 10, PageRequest{Limit: 10}.limit())
+	assert.Equal(t, MaxPageLimit, PageRequest{Limit: MaxPageLimit + 1}.limit())
+}
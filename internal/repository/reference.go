@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrInvalidReference возвращается, если refund или rollback ссылается на несуществующую ставку,
+// ставку другого пользователя, в другой валюте или транзакцию другого типа, либо превышает сумму ставки.
+var ErrInvalidReference = errors.New("invalid reference transaction")
+
+const (
+	pgCodeUniqueViolation = "23505"
+	// rollbackReferenceIndex запрещает повторную отмену одной ставки.
+	rollbackReferenceIndex = "idx_transactions_rollback_reference"
+)
+
+// validateReference проверяет уже вставленную в dbTx транзакцию refund или rollback:
+// исходная транзакция должна быть ставкой того же пользователя в той же валюте, сумма возвратов по ней
+// не должна превышать сумму ставки, а rollback должен отменять ставку целиком.
+func validateReference(ctx context.Context, dbTx pgx.Tx, tx models.Transaction) error {
+	var (
+		userID   string
+		txType   models.TransactionType
+		amount   int64
+		currency string
+	)
+	// FOR UPDATE сериализует конкурентные возвраты по одной ставке.
+	err := dbTx.QueryRow(ctx, `
+		SELECT user_id, transaction_type, amount, currency FROM transactions
+		WHERE transaction_id = $1
+		FOR UPDATE
+	`, tx.ReferenceTransactionID).Scan(&userID, &txType, &amount, &currency)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: transaction %s not found", ErrInvalidReference, tx.ReferenceTransactionID)
+	}
+	if err != nil {
+		return fmt.Errorf("could not load reference transaction: %w", err)
+	}
+
+	if txType != models.TransactionTypeBet {
+		return fmt.Errorf("%w: transaction %s is a %s, not a bet", ErrInvalidReference, tx.ReferenceTransactionID, txType)
+	}
+	if userID != tx.UserID {
+		return fmt.Errorf("%w: transaction %s belongs to another user", ErrInvalidReference, tx.ReferenceTransactionID)
+	}
+	if currency != tx.Currency {
+		return fmt.Errorf("%w: transaction %s is in %s, not %s", ErrInvalidReference, tx.ReferenceTransactionID, currency, tx.Currency)
+	}
+
+	var reversed int64
+	err = dbTx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM transactions
+		WHERE reference_transaction_id = $1 AND transaction_id <> $2
+	`, tx.ReferenceTransactionID, tx.TransactionID).Scan(&reversed)
+	if err != nil {
+		return fmt.Errorf("could not sum reversals: %w", err)
+	}
+
+	if tx.TransactionType == models.TransactionTypeRollback && (tx.Amount != amount || reversed != 0) {
+		return fmt.Errorf("%w: rollback must reverse the whole bet %s", ErrInvalidReference, tx.ReferenceTransactionID)
+	}
+	if reversed+tx.Amount > amount {
+		return fmt.Errorf("%w: refunds exceed the amount of bet %s", ErrInvalidReference, tx.ReferenceTransactionID)
+	}
+	return nil
+}
+
+// asReferenceError превращает нарушение уникальности отмены ставки в ErrInvalidReference.
+func asReferenceError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgCodeUniqueViolation && pgErr.ConstraintName == rollbackReferenceIndex {
+		return fmt.Errorf("%w: bet has already been rolled back", ErrInvalidReference)
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresRepository_References(t *testing.T) {
+	ctx := context.Background()
+	dbpool, cleanup := setupTestDB(ctx)
+	defer cleanup()
+
+	txRepo := NewPostgresRepository(dbpool)
+	walletRepo := NewPostgresWalletRepository(dbpool)
+
+	save := func(id string, userID string, txType models.TransactionType, amount int64, ref string) error {
+		return txRepo.SaveTransaction(ctx, models.Transaction{
+			TransactionID:          id,
+			UserID:                 userID,
+			TransactionType:        txType,
+			Amount:                 amount,
+			Currency:               "USD",
+			Timestamp:              time.Now(),
+			ReferenceTransactionID: ref,
+		})
+	}
+	balanceOf := func(userID string) int64 {
+		return balanceIn(ctx, t, walletRepo, userID, "USD").Balance
+	}
+
+	require.NoError(t, save("ref-deposit-1", "ref-user", models.TransactionTypeDeposit, 10000, ""))
+	require.NoError(t, save("ref-deposit-2", "ref-other", models.TransactionTypeDeposit, 10000, ""))
+	require.NoError(t, save("ref-bet-1", "ref-user", models.TransactionTypeBet, 3000, ""))
+	require.NoError(t, save("ref-bet-2", "ref-user", models.TransactionTypeBet, 2000, ""))
+	require.NoError(t, save("ref-win-1", "ref-user", models.TransactionTypeWin, 500, ""))
+	require.Equal(t, int64(5500), balanceOf("ref-user"))
+
+	t.Run("should credit rollback of the whole bet", func(t *testing.T) {
+		require.NoError(t, save("ref-rollback-1", "ref-user", models.TransactionTypeRollback, 3000, "ref-bet-1"))
+		assert.Equal(t, int64(8500), balanceOf("ref-user"))
+	})
+
+	t.Run("should not apply duplicate rollback twice", func(t *testing.T) {
+		require.NoError(t, save("ref-rollback-1", "ref-user", models.TransactionTypeRollback, 3000, "ref-bet-1"))
+		assert.Equal(t, int64(8500), balanceOf("ref-user"))
+	})
+
+	t.Run("should reject second rollback of the same bet", func(t *testing.T) {
+		err := save("ref-rollback-2", "ref-user", models.TransactionTypeRollback, 3000, "ref-bet-1")
+		assert.ErrorIs(t, err, ErrInvalidReference)
+	})
+
+	t.Run("should allow partial refunds up to the bet amount", func(t *testing.T) {
+		require.NoError(t, save("ref-refund-1", "ref-user", models.TransactionTypeRefund, 1500, "ref-bet-2"))
+		require.NoError(t, save("ref-refund-2", "ref-user", models.TransactionTypeRefund, 500, "ref-bet-2"))
+		assert.Equal(t, int64(10500), balanceOf("ref-user"))
+
+		err := save("ref-refund-3", "ref-user", models.TransactionTypeRefund, 1, "ref-bet-2")
+		assert.ErrorIs(t, err, ErrInvalidReference)
+	})
+
+	t.Run("should reject rollback of partially refunded bet", func(t *testing.T) {
+		require.NoError(t, save("ref-bet-3", "ref-user", models.TransactionTypeBet, 1000, ""))
+		require.NoError(t, save("ref-refund-4", "ref-user", models.TransactionTypeRefund, 100, "ref-bet-3"))
+
+		err := save("ref-rollback-3", "ref-user", models.TransactionTypeRollback, 1000, "ref-bet-3")
+		assert.ErrorIs(t, err, ErrInvalidReference)
+	})
+
+	t.Run("should reject invalid references", func(t *testing.T) {
+		assert.ErrorIs(t, save("ref-bad-1", "ref-user", models.TransactionTypeRefund, 100, "does-not-exist"), ErrInvalidReference)
+		assert.ErrorIs(t, save("ref-bad-2", "ref-user", models.TransactionTypeRefund, 100, "ref-win-1"), ErrInvalidReference)
+		assert.ErrorIs(t, save("ref-bad-3", "ref-other", models.TransactionTypeRefund, 100, "ref-bet-3"), ErrInvalidReference)
+		assert.ErrorIs(t, txRepo.SaveTransaction(ctx, models.Transaction{
+			TransactionID:          "ref-bad-4",
+			UserID:                 "ref-user",
+			TransactionType:        models.TransactionTypeRefund,
+			Amount:                 100,
+			Currency:               "EUR",
+			Timestamp:              time.Now(),
+			ReferenceTransactionID: "ref-bet-3",
+		}), ErrInvalidReference)
+
+		var count int
+		require.NoError(t, dbpool.QueryRow(ctx, "SELECT count(*) FROM transactions WHERE transaction_id LIKE 'ref-bad-%'").Scan(&count))
+		assert.Zero(t, count)
+	})
+
+	t.Run("should debit withdrawal and reject overdraw", func(t *testing.T) {
+		require.NoError(t, save("ref-withdrawal-1", "ref-other", models.TransactionTypeWithdrawal, 4000, ""))
+		assert.Equal(t, int64(6000), balanceOf("ref-other"))
+
+		err := save("ref-withdrawal-2", "ref-other", models.TransactionTypeWithdrawal, 6001, "")
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+	})
+
+	t.Run("should return reference transaction id when reading", func(t *testing.T) {
+		page, err := txRepo.GetAllTransactions(ctx, TransactionFilter{
+			Types: []models.TransactionType{models.TransactionTypeRollback},
+		}, PageRequest{})
+		require.NoError(t, err)
+		require.Len(t, page.Transactions, 1)
+		assert.Equal(t, "ref-bet-1", page.Transactions[0].ReferenceTransactionID)
+	})
+
+	t.Run("should get transaction with its reversals", func(t *testing.T) {
+		details, err := txRepo.GetTransactionByID(ctx, "ref-bet-2")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2000), details.Amount)
+		require.Len(t, details.Reversals, 2)
+		assert.Equal(t, "ref-refund-1", details.Reversals[0].TransactionID)
+		assert.Equal(t, "ref-refund-2", details.Reversals[1].TransactionID)
+
+		refund, err := txRepo.GetTransactionByID(ctx, "ref-refund-1")
+		require.NoError(t, err)
+		assert.Equal(t, "ref-bet-2", refund.ReferenceTransactionID)
+		assert.Empty(t, refund.Reversals)
+
+		_, err = txRepo.GetTransactionByID(ctx, "ref-missing")
+		assert.ErrorIs(t, err, ErrTransactionNotFound)
+	})
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReportRepository interface {
+	// GetGGR возвращает агрегаты за периоды с началом в [from, to),
+	// упорядоченные по началу периода и коду валюты.
+	GetGGR(ctx context.Context, granularity models.Granularity, from, to time.Time) ([]models.GGRRow, error)
+}
+
+type postgresReportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresReportRepository(db *pgxpool.Pool) ReportRepository {
+	return &postgresReportRepository{db: db}
+}
+
+// rollupTables — таблицы агрегатов для каждого шага отчета.
+var rollupTables = map[models.Granularity]string{
+	models.GranularityHour: "ggr_hourly",
+	models.GranularityDay:  "ggr_daily",
+}
+
+func (r *postgresReportRepository) GetGGR(ctx context.Context, granularity models.Granularity, from, to time.Time) ([]models.GGRRow, error) {
+	table, ok := rollupTables[granularity]
+	if !ok {
+		return nil, fmt.Errorf("unsupported report granularity: %s", granularity)
+	}
+
+	// Строка периода и валюты разбита на слоты, см. applyRollups.
+	rows, err := r.db.Query(ctx, `
+		SELECT period_start, currency, SUM(bets)::bigint AS total_wagered, SUM(wins)::bigint AS total_won,
+		       SUM(reversals)::bigint AS total_reversed, SUM(bets - wins - reversals)::bigint AS ggr,
+		       SUM(bet_count)::bigint AS bet_count, SUM(win_count)::bigint AS win_count
+		FROM `+table+`
+		WHERE period_start >= $1 AND period_start < $2
+		GROUP BY period_start, currency
+		ORDER BY period_start, currency
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ggr: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.GGRRow])
+	if err != nil {
+		return nil, fmt.Errorf("could not scan ggr: %w", err)
+	}
+	return result, nil
+}
+
+// rollupKey — строка таблицы агрегатов.
+type rollupKey struct {
+	table       string
+	periodStart time.Time
+	currency    string
+}
+
+// rollupSlots — на сколько строк делится агрегат одного периода и валюты.
+const rollupSlots = 16
+
+// applyRollups добавляет игровые транзакции txs к агрегатам GGR в рамках транзакции БД dbTx,
+// в которой они сохраняются. Депозиты и выводы в GGR не входят.
+//
+// Пачка суммируется в памяти и обновляет каждую строку один раз. Строка остается
+// заблокированной до конца транзакции БД, поэтому агрегат периода и валюты разбит на
+// rollupSlots слотов: транзакция обновляет слот своего соединения (pg_backend_pid), и
+// параллельные сохранения ждут друг друга, только если их соединения попали в один слот.
+// Отчеты суммируют слоты. Агрегаты обновляются синхронно, а не фоновой задачей, чтобы
+// отчет сразу учитывал сохраненные транзакции и повторная доставка не учитывалась дважды.
+func applyRollups(ctx context.Context, dbTx pgx.Tx, txs []models.Transaction) error {
+	deltas := make(map[rollupKey]*models.GGRAmounts)
+	for _, tx := range txs {
+		if !tx.TransactionType.IsGameplay() {
+			continue
+		}
+		delta := rollupDelta(tx)
+		for granularity, table := range rollupTables {
+			key := rollupKey{table: table, periodStart: granularity.Truncate(tx.Timestamp), currency: tx.Currency}
+			if deltas[key] == nil {
+				deltas[key] = &models.GGRAmounts{}
+			}
+			deltas[key].Add(delta)
+		}
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	// Все строки агрегатов блокируются в одном порядке, чтобы параллельные
+	// транзакции БД не попадали во взаимную блокировку.
+	keys := make([]rollupKey, 0, len(deltas))
+	for key := range deltas {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.table != b.table {
+			return a.table < b.table
+		}
+		if !a.periodStart.Equal(b.periodStart) {
+			return a.periodStart.Before(b.periodStart)
+		}
+		return a.currency < b.currency
+	})
+
+	batch := &pgx.Batch{}
+	for _, key := range keys {
+		d := deltas[key]
+		batch.Queue(`
+			INSERT INTO `+key.table+` AS r (period_start, currency, slot, bets, wins, reversals, bet_count, win_count)
+			VALUES ($1, $2, pg_backend_pid() % $8, $3, $4, $5, $6, $7)
+			ON CONFLICT (period_start, currency, slot) DO UPDATE SET
+				bets = r.bets + EXCLUDED.bets,
+				wins = r.wins + EXCLUDED.wins,
+				reversals = r.reversals + EXCLUDED.reversals,
+				bet_count = r.bet_count + EXCLUDED.bet_count,
+				win_count = r.win_count + EXCLUDED.win_count
+		`, key.periodStart, key.currency, d.TotalWagered, d.TotalWon, d.TotalReversed, d.BetCount, d.WinCount, rollupSlots)
+	}
+	if err := dbTx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("could not update ggr rollups: %w", err)
+	}
+	return nil
+}
+
+// rollupDelta возвращает вклад игровой транзакции tx в агрегаты.
+func rollupDelta(tx models.Transaction) models.GGRAmounts {
+	switch {
+	case tx.TransactionType == models.TransactionTypeBet:
+		return models.GGRAmounts{TotalWagered: tx.Amount, GGR: tx.Amount, BetCount: 1}
+	case tx.TransactionType == models.TransactionTypeWin:
+		delta := models.GGRAmounts{TotalWon: tx.Amount, GGR: -tx.Amount}
+		if tx.Amount > 0 {
+			delta.WinCount = 1
+		}
+		return delta
+	default:
+		return models.GGRAmounts{TotalReversed: tx.Amount, GGR: -tx.Amount}
+	}
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresReportRepository(t *testing.T) {
+	ctx := context.Background()
+	dbpool, cleanup := setupTestDB(ctx)
+	defer cleanup()
+
+	txRepo := NewPostgresRepository(dbpool)
+	reportRepo := NewPostgresReportRepository(dbpool)
+	seedBalances(ctx, t, dbpool, "report-user")
+
+	base := time.Date(2025, 6, 1, 10, 15, 0, 0, time.UTC)
+	require.NoError(t, txRepo.SaveTransaction(ctx, models.Transaction{TransactionID: "report-bet-1", UserID: "report-user", TransactionType: models.TransactionTypeBet, Amount: 1000, Currency: "USD", Timestamp: base}))
+	require.NoError(t, txRepo.SaveTransactions(ctx, []models.Transaction{
+		{TransactionID: "report-win-1", UserID: "report-user", TransactionType: models.TransactionTypeWin, Amount: 400, Currency: "USD", Timestamp: base.Add(10 * time.Minute)},
+		{TransactionID: "report-bet-2", UserID: "report-user", TransactionType: models.TransactionTypeBet, Amount: 300, Currency: "USD", Timestamp: base.Add(time.Hour)},
+		{TransactionID: "report-refund-1", UserID: "report-user", TransactionType: models.TransactionTypeRefund, Amount: 300, Currency: "USD", Timestamp: base.Add(time.Hour), ReferenceTransactionID: "report-bet-2"},
+		{TransactionID: "report-deposit-1", UserID: "report-user", TransactionType: models.TransactionTypeDeposit, Amount: 5000, Currency: "USD", Timestamp: base},
+	}))
+	// Повторная доставка не должна изменить агрегаты
+	require.NoError(t, txRepo.SaveTransaction(ctx, models.Transaction{TransactionID: "report-bet-1", UserID: "report-user", TransactionType: models.TransactionTypeBet, Amount: 1000, Currency: "USD", Timestamp: base}))
+
+	t.Run("should return hourly rollups", func(t *testing.T) {
+		day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		rows, err := reportRepo.GetGGR(ctx, models.GranularityHour, day, day.AddDate(0, 0, 1))
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.True(t, time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC).Equal(rows[0].PeriodStart))
+		assert.Equal(t, int64(1000), rows[0].TotalWagered)
+		assert.Equal(t, int64(600), rows[0].GGR)
+		assert.Equal(t, int64(1), rows[0].WinCount)
+		assert.Equal(t, int64(300), rows[1].TotalReversed)
+		assert.Equal(t, int64(0), rows[1].GGR)
+	})
+
+	t.Run("should return daily rollups", func(t *testing.T) {
+		day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		rows, err := reportRepo.GetGGR(ctx, models.GranularityDay, day, day.AddDate(0, 0, 1))
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		assert.Equal(t, "USD", rows[0].Currency)
+		assert.Equal(t, int64(1300), rows[0].TotalWagered)
+		assert.Equal(t, int64(600), rows[0].GGR)
+		assert.Equal(t, int64(2), rows[0].BetCount)
+	})
+
+	t.Run("should sum rollup slots of a period", func(t *testing.T) {
+		period := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+		_, err := dbpool.Exec(ctx, `
+			INSERT INTO ggr_hourly (period_start, currency, slot, bets, wins, reversals, bet_count, win_count)
+			VALUES ($1, 'USD', 3, 500, 100, 0, 2, 1), ($1, 'USD', 11, 200, 0, 50, 1, 0)
+		`, period)
+		require.NoError(t, err)
+
+		rows, err := reportRepo.GetGGR(ctx, models.GranularityHour, period, period.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		assert.Equal(t, int64(700), rows[0].TotalWagered)
+		assert.Equal(t, int64(550), rows[0].GGR)
+		assert.Equal(t, int64(3), rows[0].BetCount)
+	})
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrRoundNotFound возвращается, если в раунде нет ни одной транзакции.
+	ErrRoundNotFound = errors.New("round not found")
+	// ErrInvalidRound возвращается, если транзакция добавляется в раунд
+	// другого пользователя или в другой валюте.
+	ErrInvalidRound = errors.New("invalid round")
+)
+
+func (r *postgresRepository) GetRound(ctx context.Context, roundID string) (models.Round, error) {
+	rows, err := r.db.Query(ctx, selectTransactionsSQL+` WHERE round_id = $1 ORDER BY "timestamp", id`, roundID)
+	if err != nil {
+		return models.Round{}, fmt.Errorf("could not query round: %w", err)
+	}
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return models.Round{}, fmt.Errorf("could not query round: %w", err)
+	}
+	if len(transactions) == 0 {
+		return models.Round{}, ErrRoundNotFound
+	}
+	return buildRound(roundID, transactions), nil
+}
+
+// buildRound подсчитывает итоги и статус раунда по его транзакциям.
+func buildRound(roundID string, transactions []models.Transaction) models.Round {
+	first := transactions[0]
+	round := models.Round{
+		RoundID:      roundID,
+		UserID:       first.UserID,
+		Currency:     first.Currency,
+		GameID:       first.GameID,
+		ProviderID:   first.ProviderID,
+		Status:       models.RoundStatusOpen,
+		Transactions: transactions,
+	}
+
+	var (
+		totals  models.PlayTotals
+		settled bool
+	)
+	for _, tx := range transactions {
+		totals.Add(tx)
+		if tx.TransactionType == models.TransactionTypeWin {
+			settled = true
+		}
+	}
+	round.TotalBet = totals.Bets
+	round.TotalWin = totals.Wins
+	round.TotalReversed = totals.Reversals
+	round.NetResult = -totals.Loss()
+
+	// Раунд без выигрыша закрыт, только если все ставки по нему возвращены.
+	if settled || (round.TotalBet > 0 && round.TotalReversed >= round.TotalBet) {
+		round.Status = models.RoundStatusClosed
+	}
+	return round
+}
+
+// roundLockSpace — первый ключ advisory-блокировок раундов, см. userLockSpace.
+const roundLockSpace int32 = 0x726f756e
+
+// lockRounds блокирует раунды txs до конца транзакции БД dbTx. Блокировка сериализует
+// конкурентные вставки в один раунд, чтобы каждая проверка validateRound видела уже
+// зафиксированные транзакции остальных. Все раунды блокируются до проверок и по возрастанию
+// ключей, поэтому пачки с общими раундами не взаимоблокируются.
+func lockRounds(ctx context.Context, dbTx pgx.Tx, txs []models.Transaction) error {
+	for _, key := range roundLockKeys(txs) {
+		if _, err := dbTx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, roundLockSpace, key); err != nil {
+			return fmt.Errorf("could not lock round: %w", err)
+		}
+	}
+	return nil
+}
+
+// roundLockKeys возвращает ключи блокировок раундов txs без повторов и по возрастанию;
+// транзакции без round_id не блокируют ничего.
+func roundLockKeys(txs []models.Transaction) []int32 {
+	return lockKeys(txs, func(tx models.Transaction) string { return tx.RoundID })
+}
+
+// validateRound проверяет уже вставленную в dbTx транзакцию с round_id:
+// все транзакции раунда должны принадлежать одному пользователю и одной валюте.
+// Раунд должен быть заблокирован через lockRounds.
+func validateRound(ctx context.Context, dbTx pgx.Tx, tx models.Transaction) error {
+	var mismatched bool
+	err := dbTx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM transactions
+			WHERE round_id = $1 AND (user_id <> $2 OR currency <> $3)
+		)
+	`, tx.RoundID, tx.UserID, tx.Currency).Scan(&mismatched)
+	if err != nil {
+		return fmt.Errorf("could not check round: %w", err)
+	}
+	if mismatched {
+		return fmt.Errorf("%w: round %s belongs to another user or currency", ErrInvalidRound, tx.RoundID)
+	}
+	return nil
+}
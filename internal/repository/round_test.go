@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildRound(t *testing.T) {
+	bet := func(id string, amount int64) models.Transaction {
+		return models.Transaction{TransactionID: id, UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: amount, Currency: "EUR", GameID: "g-1", ProviderID: "p-1"}
+	}
+	tx := func(id string, txType models.TransactionType, amount int64) models.Transaction {
+		return models.Transaction{TransactionID: id, UserID: "u1", TransactionType: txType, Amount: amount, Currency: "EUR"}
+	}
+
+	tests := []struct {
+		name      string
+		txs       []models.Transaction
+		status    models.RoundStatus
+		netResult int64
+	}{
+		{"bet without settlement", []models.Transaction{bet("b1", 100)}, models.RoundStatusOpen, -100},
+		{"winning round", []models.Transaction{bet("b1", 100), tx("w1", models.TransactionTypeWin, 250)}, models.RoundStatusClosed, 150},
+		{"lost round closed by zero win", []models.Transaction{bet("b1", 100), tx("w1", models.TransactionTypeWin, 0)}, models.RoundStatusClosed, -100},
+		{"rolled back round", []models.Transaction{bet("b1", 100), tx("r1", models.TransactionTypeRollback, 100)}, models.RoundStatusClosed, 0},
+		{"partially refunded round", []models.Transaction{bet("b1", 100), bet("b2", 50), tx("r1", models.TransactionTypeRefund, 100)}, models.RoundStatusOpen, -50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			round := buildRound("round-1", tt.txs)
+
+			assert.Equal(t, "round-1", round.RoundID)
+			assert.Equal(t, "u1", round.UserID)
+			assert.Equal(t, "EUR", round.Currency)
+			assert.Equal(t, "g-1", round.GameID)
+			assert.Equal(t, "p-1", round.ProviderID)
+			assert.Equal(t, tt.status, round.Status)
+			assert.Equal(t, tt.netResult, round.NetResult)
+			assert.Equal(t, tt.txs, round.Transactions)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Суммы считаются в SQL, чтобы не загружать все транзакции пользователя.
+const selectSummarySQL = `
+	SELECT currency,
+	       COALESCE(SUM(amount) FILTER (WHERE transaction_type = 'bet'), 0) AS total_wagered,
+	       COALESCE(SUM(amount) FILTER (WHERE transaction_type = 'win'), 0) AS total_won,
+	       COALESCE(SUM(amount) FILTER (WHERE transaction_type IN ('refund', 'rollback')), 0) AS total_reversed,
+	       COALESCE(SUM(CASE transaction_type
+	                        WHEN 'bet' THEN amount
+	                        WHEN 'win' THEN -amount
+	                        WHEN 'refund' THEN -amount
+	                        WHEN 'rollback' THEN -amount
+	                    END), 0) AS net_result,
+	       COUNT(*) FILTER (WHERE transaction_type = 'bet') AS bet_count,
+	       COUNT(*) FILTER (WHERE transaction_type = 'win' AND amount > 0) AS win_count,
+	       COALESCE(MAX(amount) FILTER (WHERE transaction_type = 'win'), 0) AS largest_win,
+	       MIN("timestamp") AS first_activity,
+	       MAX("timestamp") AS last_activity
+	FROM transactions`
+
+func (r *postgresRepository) GetUserSummary(ctx context.Context, userID string, from, to *time.Time) (models.UserSummary, error) {
+	var q queryBuilder
+	q.where("user_id = ?", userID)
+	TransactionFilter{From: from, To: to}.apply(&q)
+
+	rows, err := r.db.Query(ctx, selectSummarySQL+q.whereClause()+` GROUP BY currency ORDER BY currency`, q.args...)
+	if err != nil {
+		return models.UserSummary{}, fmt.Errorf("could not query user summary: %w", err)
+	}
+	currencies, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.CurrencySummary])
+	if err != nil {
+		return models.UserSummary{}, fmt.Errorf("could not scan user summary: %w", err)
+	}
+
+	return models.UserSummary{UserID: userID, From: from, To: to, Currencies: currencies}, nil
+}
@@ -2,96 +2,472 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"slices"
+	"time"
 
 	"github.com/OlgaPie/casino-transaction-system/internal/models"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrTransactionNotFound возвращается, если транзакции с таким transaction_id нет.
+var ErrTransactionNotFound = errors.New("transaction not found")
+
 type TransactionRepository interface {
 	SaveTransaction(ctx context.Context, tx models.Transaction) error
-	GetTransactionsByUserID(ctx context.Context, userID string, txType string) ([]models.Transaction, error)
-	GetAllTransactions(ctx context.Context, txType string) ([]models.Transaction, error)
+	// CreateTransaction сохраняет транзакцию так же, как SaveTransaction, и сообщает,
+	// была ли она вставлена; false означает, что она была сохранена раньше.
+	CreateTransaction(ctx context.Context, tx models.Transaction) (bool, error)
+	// SaveTransactions сохраняет пачку транзакций в одной транзакции БД:
+	// либо все новые транзакции сохраняются вместе с изменением балансов, либо ни одна.
+	SaveTransactions(ctx context.Context, txs []models.Transaction) error
+	// CreateTransactions сохраняет пачку так же, как SaveTransactions, и возвращает
+	// transaction_id вставленных транзакций; остальные были сохранены раньше.
+	CreateTransactions(ctx context.Context, txs []models.Transaction) ([]string, error)
+	GetTransactionsByUserID(ctx context.Context, userID string, filter TransactionFilter, page PageRequest) (TransactionPage, error)
+	GetAllTransactions(ctx context.Context, filter TransactionFilter, page PageRequest) (TransactionPage, error)
+	// StreamTransactions вызывает fn для каждой подходящей транзакции по мере чтения строк,
+	// не загружая всю выборку в память; порядок тот же, что у страниц.
+	// Пустой userID означает транзакции всех пользователей. Ошибка fn прерывает чтение
+	// и возвращается как есть.
+	StreamTransactions(ctx context.Context, userID string, filter TransactionFilter, fn func(models.Transaction) error) error
+	// GetTransactionByID возвращает транзакцию вместе с возвратами и отменой, которые на нее ссылаются,
+	// или ErrTransactionNotFound.
+	GetTransactionByID(ctx context.Context, transactionID string) (models.TransactionDetails, error)
+	// GetRound возвращает раунд со всеми его транзакциями или ErrRoundNotFound.
+	GetRound(ctx context.Context, roundID string) (models.Round, error)
+	// GetUserSummary агрегирует транзакции пользователя по валютам за период [from, to).
+	// Пустые границы не ограничивают период.
+	GetUserSummary(ctx context.Context, userID string, from, to *time.Time) (models.UserSummary, error)
+}
+
+// SaveChecker проверяет транзакцию перед сохранением, например по лимитам ответственной игры.
+// Репозиторий вызывает его в той же транзакции БД, в которой сохраняет tx, удерживая
+// блокировку ее пользователя, поэтому проверки и сохранения транзакций одного пользователя
+// из разных процессов не пересекаются. limits читает данные в этой транзакции БД;
+// pending — новые транзакции пачки, которые сохраняются раньше tx; уже сохраненные
+// транзакции пачки не проверяются и в pending не попадают.
+// Ошибка отменяет сохранение всей пачки и возвращается обернутой.
+type SaveChecker interface {
+	CheckSave(ctx context.Context, limits LimitRepository, tx models.Transaction, pending []models.Transaction) error
+}
+
+// PostgresOption настраивает репозиторий, созданный NewPostgresRepository.
+type PostgresOption func(*postgresRepository)
+
+// WithSaveChecker включает проверку транзакций checker'ом при сохранении.
+func WithSaveChecker(checker SaveChecker) PostgresOption {
+	return func(r *postgresRepository) {
+		r.checker = checker
+	}
 }
 
 type postgresRepository struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	wallet  *postgresWalletRepository
+	checker SaveChecker
 }
 
-func NewPostgresRepository(db *pgxpool.Pool) TransactionRepository {
-	return &postgresRepository{db: db}
+func NewPostgresRepository(db *pgxpool.Pool, opts ...PostgresOption) TransactionRepository {
+	r := &postgresRepository{db: db, wallet: &postgresWalletRepository{db: db}}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *postgresRepository) SaveTransaction(ctx context.Context, tx models.Transaction) error {
+	_, err := r.CreateTransaction(ctx, tx)
+	return err
+}
+
+func (r *postgresRepository) CreateTransaction(ctx context.Context, tx models.Transaction) (bool, error) {
 	sql := `
-		INSERT INTO transactions (transaction_id, user_id, transaction_type, amount, "timestamp") 
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO transactions (transaction_id, user_id, transaction_type, amount, currency, "timestamp", reference_transaction_id,
+		                          round_id, game_id, provider_id, session_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''))
 		ON CONFLICT (transaction_id) DO NOTHING
 	`
 
-	_, err := r.db.Exec(ctx, sql, tx.TransactionID, tx.UserID, tx.TransactionType, tx.Amount, tx.Timestamp)
+	// Вставка, проверка ссылки, изменение баланса и агрегатов GGR выполняются в одной транзакции БД.
+	var created bool
+	err := pgx.BeginFunc(ctx, r.db, func(dbTx pgx.Tx) error {
+		if err := r.check(ctx, dbTx, []models.Transaction{tx}); err != nil {
+			return err
+		}
+		tag, err := dbTx.Exec(ctx, sql, tx.TransactionID, tx.UserID, tx.TransactionType, tx.Amount, tx.Currency, tx.Timestamp, tx.ReferenceTransactionID,
+			tx.RoundID, tx.GameID, tx.ProviderID, tx.SessionID)
+		if err != nil {
+			return asRejectedError(err)
+		}
+		// Дубликат уже изменил баланс при первой вставке.
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+		if err := lockRounds(ctx, dbTx, []models.Transaction{tx}); err != nil {
+			return err
+		}
+		if err := validateLinks(ctx, dbTx, tx); err != nil {
+			return err
+		}
+		if err := r.wallet.applyTransaction(ctx, dbTx, tx); err != nil {
+			return err
+		}
+		if err := applyRollups(ctx, dbTx, []models.Transaction{tx}); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("could not save transaction: %w", err)
+		return false, fmt.Errorf("could not save transaction: %w", err)
 	}
-	return nil
+	return created, nil
 }
 
-func (r *postgresRepository) GetTransactionsByUserID(ctx context.Context, userID string, txType string) ([]models.Transaction, error) {
-	baseSQL := `SELECT id, transaction_id, user_id, transaction_type, amount, "timestamp" FROM transactions WHERE user_id = $1`
-	args := []any{userID}
+func (r *postgresRepository) SaveTransactions(ctx context.Context, txs []models.Transaction) error {
+	_, err := r.CreateTransactions(ctx, txs)
+	return err
+}
 
-	if txType != "" {
-		baseSQL += " AND transaction_type = $2"
-		args = append(args, txType)
+func (r *postgresRepository) CreateTransactions(ctx context.Context, txs []models.Transaction) ([]string, error) {
+	if len(txs) == 0 {
+		return nil, nil
 	}
 
-	baseSQL += ` ORDER BY "timestamp" DESC`
+	// Повторы transaction_id внутри пачки отбрасываются до загрузки: иначе в таблицу
+	// могла бы попасть одна копия, а баланс измениться по другой.
+	txs = uniqueTransactions(txs)
+
+	var created []string
+	err := pgx.BeginFunc(ctx, r.db, func(dbTx pgx.Tx) error {
+		if err := r.check(ctx, dbTx, txs); err != nil {
+			return err
+		}
+
+		// Пачка загружается через COPY во временную таблицу, а в основную
+		// переносится одним INSERT, чтобы дубликаты отбрасывались через ON CONFLICT.
+		_, err := dbTx.Exec(ctx, `
+			CREATE TEMP TABLE transactions_staging (
+				transaction_id   VARCHAR(255),
+				user_id          VARCHAR(255),
+				transaction_type VARCHAR(10),
+				amount           BIGINT,
+				currency         CHAR(3),
+				"timestamp"      TIMESTAMPTZ,
+				reference_transaction_id VARCHAR(255),
+				round_id         VARCHAR(255),
+				game_id          VARCHAR(255),
+				provider_id      VARCHAR(255),
+				session_id       VARCHAR(255)
+			) ON COMMIT DROP
+		`)
+		if err != nil {
+			return err
+		}
 
-	rows, err := r.db.Query(ctx, baseSQL, args...)
+		_, err = dbTx.CopyFrom(ctx,
+			pgx.Identifier{"transactions_staging"},
+			[]string{"transaction_id", "user_id", "transaction_type", "amount", "currency", "timestamp", "reference_transaction_id",
+				"round_id", "game_id", "provider_id", "session_id"},
+			pgx.CopyFromSlice(len(txs), func(i int) ([]any, error) {
+				tx := txs[i]
+				return []any{tx.TransactionID, tx.UserID, string(tx.TransactionType), tx.Amount, tx.Currency, tx.Timestamp, tx.ReferenceTransactionID,
+					tx.RoundID, tx.GameID, tx.ProviderID, tx.SessionID}, nil
+			}),
+		)
+		if err != nil {
+			return err
+		}
+
+		rows, err := dbTx.Query(ctx, `
+			INSERT INTO transactions (transaction_id, user_id, transaction_type, amount, currency, "timestamp", reference_transaction_id,
+			                          round_id, game_id, provider_id, session_id)
+			SELECT transaction_id, user_id, transaction_type, amount, currency, "timestamp", NULLIF(reference_transaction_id, ''),
+			       NULLIF(round_id, ''), NULLIF(game_id, ''), NULLIF(provider_id, ''), NULLIF(session_id, '')
+			FROM transactions_staging
+			ON CONFLICT (transaction_id) DO NOTHING
+			RETURNING transaction_id
+		`)
+		if err != nil {
+			return err
+		}
+		insertedIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return asRejectedError(err)
+		}
+
+		// Балансы меняются только для реально вставленных строк и в порядке следования в пачке.
+		inserted := make(map[string]bool, len(insertedIDs))
+		for _, id := range insertedIDs {
+			inserted[id] = true
+		}
+		applied := make([]models.Transaction, 0, len(insertedIDs))
+		for _, tx := range txs {
+			if inserted[tx.TransactionID] {
+				applied = append(applied, tx)
+			}
+		}
+		if err := lockRounds(ctx, dbTx, applied); err != nil {
+			return err
+		}
+		for _, tx := range applied {
+			if err := validateLinks(ctx, dbTx, tx); err != nil {
+				return err
+			}
+		}
+		if err := r.wallet.applyTransactions(ctx, dbTx, applied); err != nil {
+			return err
+		}
+		if err := applyRollups(ctx, dbTx, applied); err != nil {
+			return err
+		}
+		created = insertedIDs
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("could not query transactions: %w", err)
+		return nil, fmt.Errorf("could not save transactions: %w", err)
 	}
-	defer rows.Close()
+	return created, nil
+}
 
-	transactions := make([]models.Transaction, 0)
-	for rows.Next() {
-		var tx models.Transaction
-		if err := rows.Scan(&tx.ID, &tx.TransactionID, &tx.UserID, &tx.TransactionType, &tx.Amount, &tx.Timestamp); err != nil {
-			return nil, fmt.Errorf("could not scan transaction row: %w", err)
+// uniqueTransactions возвращает транзакции пачки без повторов transaction_id,
+// оставляя первую копию каждой транзакции и порядок следования.
+func uniqueTransactions(txs []models.Transaction) []models.Transaction {
+	seen := make(map[string]bool, len(txs))
+	unique := make([]models.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if seen[tx.TransactionID] {
+			continue
 		}
-		transactions = append(transactions, tx)
+		seen[tx.TransactionID] = true
+		unique = append(unique, tx)
 	}
+	return unique
+}
 
-	if rows.Err() != nil {
-		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+// userLockSpace — первый ключ advisory-блокировок пользователей (pg_advisory_xact_lock(int, int)),
+// чтобы они не совпадали с другими advisory-блокировками приложения.
+const userLockSpace int32 = 0x75736572
+
+// check блокирует пользователей пачки до конца транзакции БД dbTx и проверяет
+// каждую новую транзакцию checker'ом с учетом предыдущих новых транзакций пачки.
+// Уже сохраненные транзакции не проверяются и не учитываются: они есть в итогах из БД.
+func (r *postgresRepository) check(ctx context.Context, dbTx pgx.Tx, txs []models.Transaction) error {
+	if r.checker == nil {
+		return nil
 	}
 
-	return transactions, nil
+	for _, key := range userLockKeys(txs) {
+		if _, err := dbTx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, userLockSpace, key); err != nil {
+			return fmt.Errorf("could not lock user: %w", err)
+		}
+	}
+
+	stored, err := storedTransactionIDs(ctx, dbTx, txs)
+	if err != nil {
+		return err
+	}
+
+	limits := &postgresLimitRepository{db: dbTx}
+	pending := make([]models.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if stored[tx.TransactionID] {
+			continue
+		}
+		if err := r.checker.CheckSave(ctx, limits, tx, pending); err != nil {
+			return err
+		}
+		pending = append(pending, tx)
+	}
+	return nil
 }
 
-func (r *postgresRepository) GetAllTransactions(ctx context.Context, txType string) ([]models.Transaction, error) {
-	baseSQL := `SELECT id, transaction_id, user_id, transaction_type, amount, "timestamp" FROM transactions`
-	var args []any
+// storedTransactionIDs возвращает transaction_id транзакций txs, которые уже сохранены.
+func storedTransactionIDs(ctx context.Context, dbTx pgx.Tx, txs []models.Transaction) (map[string]bool, error) {
+	ids := make([]string, len(txs))
+	for i, tx := range txs {
+		ids[i] = tx.TransactionID
+	}
+	rows, err := dbTx.Query(ctx, `SELECT transaction_id FROM transactions WHERE transaction_id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("could not find stored transactions: %w", err)
+	}
+	stored, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("could not scan stored transactions: %w", err)
+	}
 
-	if txType != "" {
-		baseSQL += " WHERE transaction_type = $1"
-		args = append(args, txType)
+	result := make(map[string]bool, len(stored))
+	for _, id := range stored {
+		result[id] = true
 	}
+	return result, nil
+}
 
-	baseSQL += ` ORDER BY "timestamp" DESC`
+// userLockKeys возвращает ключи блокировок пользователей txs без повторов и по возрастанию:
+// пачки с общими пользователями берут блокировки в одном порядке и не взаимоблокируются.
+func userLockKeys(txs []models.Transaction) []int32 {
+	return lockKeys(txs, func(tx models.Transaction) string { return tx.UserID })
+}
 
-	rows, err := r.db.Query(ctx, baseSQL, args...)
+// lockKeys возвращает хеши непустых значений key(tx) для txs без повторов и по возрастанию.
+func lockKeys(txs []models.Transaction, key func(tx models.Transaction) string) []int32 {
+	keys := make([]int32, 0, len(txs))
+	for _, tx := range txs {
+		value := key(tx)
+		if value == "" {
+			continue
+		}
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(value))
+		keys = append(keys, int32(hash.Sum32()))
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// validateLinks проверяет связи уже вставленной в dbTx транзакции с исходной ставкой и раундом.
+// Раунд транзакции должен быть заблокирован через lockRounds.
+func validateLinks(ctx context.Context, dbTx pgx.Tx, tx models.Transaction) error {
+	if tx.TransactionType.RequiresReference() {
+		if err := validateReference(ctx, dbTx, tx); err != nil {
+			return err
+		}
+	}
+	if tx.RoundID != "" {
+		return validateRound(ctx, dbTx, tx)
+	}
+	return nil
+}
+
+const selectTransactionsSQL = `
+	SELECT id, transaction_id, user_id, transaction_type, amount, currency, "timestamp", COALESCE(reference_transaction_id, ''),
+	       COALESCE(round_id, ''), COALESCE(game_id, ''), COALESCE(provider_id, ''), COALESCE(session_id, '')
+	FROM transactions`
+
+func (r *postgresRepository) GetTransactionsByUserID(ctx context.Context, userID string, filter TransactionFilter, page PageRequest) (TransactionPage, error) {
+	var q queryBuilder
+	q.where("user_id = ?", userID)
+	filter.apply(&q)
+
+	result, err := r.queryPage(ctx, &q, page)
+	if err != nil {
+		return TransactionPage{}, fmt.Errorf("could not query transactions: %w", err)
+	}
+	return result, nil
+}
+
+func (r *postgresRepository) GetAllTransactions(ctx context.Context, filter TransactionFilter, page PageRequest) (TransactionPage, error) {
+	var q queryBuilder
+	filter.apply(&q)
+
+	result, err := r.queryPage(ctx, &q, page)
+	if err != nil {
+		return TransactionPage{}, fmt.Errorf("could not query all transactions: %w", err)
+	}
+	return result, nil
+}
+
+func (r *postgresRepository) GetTransactionByID(ctx context.Context, transactionID string) (models.TransactionDetails, error) {
+	// Транзакция и ссылающиеся на нее возвраты читаются одним запросом.
+	rows, err := r.db.Query(ctx, selectTransactionsSQL+`
+		WHERE transaction_id = $1 OR reference_transaction_id = $1
+		ORDER BY "timestamp", id
+	`, transactionID)
+	if err != nil {
+		return models.TransactionDetails{}, fmt.Errorf("could not query transaction: %w", err)
+	}
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return models.TransactionDetails{}, fmt.Errorf("could not query transaction: %w", err)
+	}
+
+	details := models.TransactionDetails{Reversals: make([]models.Transaction, 0)}
+	found := false
+	for _, tx := range transactions {
+		if tx.TransactionID == transactionID {
+			details.Transaction, found = tx, true
+			continue
+		}
+		details.Reversals = append(details.Reversals, tx)
+	}
+	if !found {
+		return models.TransactionDetails{}, ErrTransactionNotFound
+	}
+	return details, nil
+}
+
+// queryPage дополняет запрос keyset-условием, сортировкой и LIMIT.
+// Запрашивается на одну строку больше, чтобы понять, есть ли следующая страница.
+func (r *postgresRepository) queryPage(ctx context.Context, q *queryBuilder, page PageRequest) (TransactionPage, error) {
+	limit := page.limit()
+
+	if page.After != nil {
+		q.where(`("timestamp", id) < (?, ?)`, page.After.Timestamp, page.After.ID)
+	}
+
+	sql := selectTransactionsSQL + q.whereClause() + ` ORDER BY "timestamp" DESC, id DESC LIMIT ` + q.arg(limit+1)
+
+	rows, err := r.db.Query(ctx, sql, q.args...)
+	if err != nil {
+		return TransactionPage{}, err
+	}
+
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return TransactionPage{}, err
+	}
+
+	result := TransactionPage{Transactions: transactions}
+	if len(transactions) > limit {
+		result.Transactions = transactions[:limit]
+		last := result.Transactions[limit-1]
+		result.NextCursor = Cursor{Timestamp: last.Timestamp, ID: last.ID}.Encode()
+	}
+	return result, nil
+}
+
+func (r *postgresRepository) StreamTransactions(ctx context.Context, userID string, filter TransactionFilter, fn func(models.Transaction) error) error {
+	var q queryBuilder
+	if userID != "" {
+		q.where("user_id = ?", userID)
+	}
+	filter.apply(&q)
+
+	rows, err := r.db.Query(ctx, selectTransactionsSQL+q.whereClause()+` ORDER BY "timestamp" DESC, id DESC`, q.args...)
 	if err != nil {
-		return nil, fmt.Errorf("could not query all transactions: %w", err)
+		return fmt.Errorf("could not query transactions: %w", err)
 	}
 	defer rows.Close()
 
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	if rows.Err() != nil {
+		return fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return nil
+}
+
+func scanTransactions(rows pgx.Rows) ([]models.Transaction, error) {
+	defer rows.Close()
+
 	transactions := make([]models.Transaction, 0)
 	for rows.Next() {
-		var tx models.Transaction
-		if err := rows.Scan(&tx.ID, &tx.TransactionID, &tx.UserID, &tx.TransactionType, &tx.Amount, &tx.Timestamp); err != nil {
-			return nil, fmt.Errorf("could not scan transaction row: %w", err)
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
 		}
 		transactions = append(transactions, tx)
 	}
@@ -102,3 +478,13 @@ func (r *postgresRepository) GetAllTransactions(ctx context.Context, txType stri
 
 	return transactions, nil
 }
+
+// scanTransaction читает текущую строку выборки selectTransactionsSQL.
+func scanTransaction(rows pgx.Rows) (models.Transaction, error) {
+	var tx models.Transaction
+	if err := rows.Scan(&tx.ID, &tx.TransactionID, &tx.UserID, &tx.TransactionType, &tx.Amount, &tx.Currency, &tx.Timestamp, &tx.ReferenceTransactionID,
+		&tx.RoundID, &tx.GameID, &tx.ProviderID, &tx.SessionID); err != nil {
+		return models.Transaction{}, fmt.Errorf("could not scan transaction row: %w", err)
+	}
+	return tx, nil
+}
@@ -2,11 +2,15 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"testing"
 	"time"
 
+	"github.com/OlgaPie/casino-transaction-system/internal/migrate"
 	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/OlgaPie/casino-transaction-system/migrations"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -44,19 +48,9 @@ func setupTestDB(ctx context.Context) (*pgxpool.Pool, func()) {
 		log.Fatalf("failed to connect to test db: %s", err)
 	}
 
-	// Создаем таблицу
-	_, err = dbpool.Exec(ctx, `CREATE TABLE transactions (
-        id SERIAL PRIMARY KEY,
-        transaction_id VARCHAR(255) NOT NULL,
-        user_id VARCHAR(255) NOT NULL,
-        transaction_type VARCHAR(10) NOT NULL CHECK (transaction_type IN ('bet', 'win')),
-        amount BIGINT NOT NULL,
-        "timestamp" TIMESTAMPTZ NOT NULL
-    );
-    CREATE UNIQUE INDEX idx_transactions_transaction_id ON transactions (transaction_id);
-    CREATE INDEX idx_transactions_user_id ON transactions (user_id);`)
-	if err != nil {
-		log.Fatalf("failed to create table: %s", err)
+	// Применяем миграции из migrations/
+	if err := applyMigrations(ctx, dbpool); err != nil {
+		log.Fatalf("failed to apply migrations: %s", err)
 	}
 
 	// Возвращаем пул соединений и функцию для очистки (остановки контейнера)
@@ -69,6 +63,53 @@ func setupTestDB(ctx context.Context) (*pgxpool.Pool, func()) {
 	return dbpool, cleanup
 }
 
+// applyMigrations применяет миграции из migrations/ так же, как сервисы при запуске.
+func applyMigrations(ctx context.Context, dbpool *pgxpool.Pool) error {
+	migrator, err := migrate.New(dbpool, migrations.FS, nil)
+	if err != nil {
+		return err
+	}
+	return migrator.Up(ctx)
+}
+
+// seedBalances зачисляет пользователям сумму, достаточную для тестовых ставок.
+func seedBalances(ctx context.Context, t *testing.T, dbpool *pgxpool.Pool, userIDs ...string) {
+	for _, userID := range userIDs {
+		_, err := dbpool.Exec(ctx, "INSERT INTO balances (user_id, currency, balance) VALUES ($1, 'USD', 1000000)", userID)
+		require.NoError(t, err)
+	}
+}
+
+func TestUniqueTransactions(t *testing.T) {
+	txs := []models.Transaction{
+		{TransactionID: "a", Amount: 100},
+		{TransactionID: "b", Amount: 200},
+		{TransactionID: "a", Amount: 999},
+		{TransactionID: "c", Amount: 300},
+	}
+
+	unique := uniqueTransactions(txs)
+	assert.Equal(t, []models.Transaction{txs[0], txs[1], txs[3]}, unique, "first copy wins, order is kept")
+}
+
+func TestUserLockKeys(t *testing.T) {
+	txs := []models.Transaction{{UserID: "u2"}, {UserID: "u1"}, {UserID: "u2"}, {UserID: "u3"}}
+
+	keys := userLockKeys(txs)
+	assert.Len(t, keys, 3, "one lock per user")
+	assert.IsIncreasing(t, keys, "locks are taken in the same order by every batch")
+	assert.Equal(t, keys, userLockKeys([]models.Transaction{{UserID: "u3"}, {UserID: "u1"}, {UserID: "u2"}}))
+}
+
+func TestRoundLockKeys(t *testing.T) {
+	txs := []models.Transaction{{RoundID: "r2"}, {RoundID: "r1"}, {}, {RoundID: "r2"}}
+
+	keys := roundLockKeys(txs)
+	assert.Len(t, keys, 2, "one lock per round, none without round")
+	assert.IsIncreasing(t, keys, "locks are taken in the same order by every batch")
+	assert.Equal(t, keys, roundLockKeys([]models.Transaction{{RoundID: "r1"}, {RoundID: "r2"}}))
+}
+
 func TestPostgresRepository(t *testing.T) {
 	ctx := context.Background()
 	dbpool, cleanup := setupTestDB(ctx)
@@ -76,6 +117,9 @@ func TestPostgresRepository(t *testing.T) {
 
 	repo := NewPostgresRepository(dbpool)
 
+	// Ставки списываются с баланса, поэтому заранее пополняем счета
+	seedBalances(ctx, t, dbpool, "user123", "user-paged", "user-filter", "user-round")
+
 	// --- Тестируем SaveTransaction ---
 	t.Run("should save and retrieve a transaction", func(t *testing.T) {
 		tx := models.Transaction{
@@ -83,6 +127,7 @@ func TestPostgresRepository(t *testing.T) {
 			UserID:          "user123",
 			TransactionType: models.TransactionTypeBet,
 			Amount:          10050,
+			Currency:        "USD",
 			Timestamp:       time.Now(),
 		}
 
@@ -90,8 +135,9 @@ func TestPostgresRepository(t *testing.T) {
 		require.NoError(t, err) // require прерывает тест при ошибке
 
 		// --- Тестируем GetTransactionsByUserID ---
-		retrieved, err := repo.GetTransactionsByUserID(ctx, "user123", "")
+		page, err := repo.GetTransactionsByUserID(ctx, "user123", TransactionFilter{}, PageRequest{})
 		require.NoError(t, err)
+		retrieved := page.Transactions
 		require.Len(t, retrieved, 1)
 		assert.Equal(t, tx.UserID, retrieved[0].UserID)
 		assert.Equal(t, tx.TransactionType, retrieved[0].TransactionType)
@@ -100,35 +146,220 @@ func TestPostgresRepository(t *testing.T) {
 
 	// --- Тестируем фильтрацию ---
 	t.Run("should filter transactions by type", func(t *testing.T) {
-		winTx := models.Transaction{TransactionID: "test-repo-002", UserID: "user456", TransactionType: models.TransactionTypeWin, Amount: 20000, Timestamp: time.Now()}
-		betTx := models.Transaction{TransactionID: "test-repo-003", UserID: "user456", TransactionType: models.TransactionTypeBet, Amount: 5000, Timestamp: time.Now()}
+		winTx := models.Transaction{TransactionID: "test-repo-002", UserID: "user456", TransactionType: models.TransactionTypeWin, Amount: 20000, Currency: "USD", Timestamp: time.Now()}
+		betTx := models.Transaction{TransactionID: "test-repo-003", UserID: "user456", TransactionType: models.TransactionTypeBet, Amount: 5000, Currency: "USD", Timestamp: time.Now()}
 
 		require.NoError(t, repo.SaveTransaction(ctx, winTx))
 		require.NoError(t, repo.SaveTransaction(ctx, betTx))
 
 		// Проверяем фильтр по "win"
-		wins, err := repo.GetTransactionsByUserID(ctx, "user456", "win")
+		wins, err := repo.GetTransactionsByUserID(ctx, "user456", TransactionFilter{Types: []models.TransactionType{models.TransactionTypeWin}}, PageRequest{})
 		require.NoError(t, err)
-		require.Len(t, wins, 1)
-		assert.Equal(t, models.TransactionTypeWin, wins[0].TransactionType)
+		require.Len(t, wins.Transactions, 1)
+		assert.Equal(t, models.TransactionTypeWin, wins.Transactions[0].TransactionType)
 
 		// Проверяем фильтр по "bet"
-		bets, err := repo.GetTransactionsByUserID(ctx, "user456", "bet")
+		bets, err := repo.GetTransactionsByUserID(ctx, "user456", TransactionFilter{Types: []models.TransactionType{models.TransactionTypeBet}}, PageRequest{})
 		require.NoError(t, err)
-		require.Len(t, bets, 1)
-		assert.Equal(t, models.TransactionTypeBet, bets[0].TransactionType)
+		require.Len(t, bets.Transactions, 1)
+		assert.Equal(t, models.TransactionTypeBet, bets.Transactions[0].TransactionType)
 	})
 
 	// --- Тестируем GetAllTransactions ---
 	t.Run("should get all transactions with filtering", func(t *testing.T) {
 		// Получаем все транзакции (у нас их 3 из предыдущих тестов)
-		all, err := repo.GetAllTransactions(ctx, "")
+		all, err := repo.GetAllTransactions(ctx, TransactionFilter{}, PageRequest{})
 		require.NoError(t, err)
-		assert.Len(t, all, 3)
+		assert.Len(t, all.Transactions, 3)
+		assert.Empty(t, all.NextCursor)
 
 		// Получаем только выигрыши (1 транзакция)
-		allWins, err := repo.GetAllTransactions(ctx, "win")
+		allWins, err := repo.GetAllTransactions(ctx, TransactionFilter{Types: []models.TransactionType{models.TransactionTypeWin}}, PageRequest{})
+		require.NoError(t, err)
+		assert.Len(t, allWins.Transactions, 1)
+	})
+
+	// --- Тестируем пагинацию ---
+	t.Run("should paginate user transactions with cursor", func(t *testing.T) {
+		// Одинаковое время у части транзакций проверяет сортировку по id
+		base := time.Now().Truncate(time.Microsecond)
+		timestamps := []time.Time{base, base, base.Add(-time.Minute), base.Add(-2 * time.Minute), base.Add(-2 * time.Minute)}
+		for i, ts := range timestamps {
+			tx := models.Transaction{
+				TransactionID:   fmt.Sprintf("test-repo-page-%d", i),
+				UserID:          "user-paged",
+				TransactionType: models.TransactionTypeBet,
+				Amount:          100,
+				Currency:        "USD",
+				Timestamp:       ts,
+			}
+			require.NoError(t, repo.SaveTransaction(ctx, tx))
+		}
+
+		var collected []models.Transaction
+		page := PageRequest{Limit: 2}
+		for range 3 {
+			result, err := repo.GetTransactionsByUserID(ctx, "user-paged", TransactionFilter{}, page)
+			require.NoError(t, err)
+			collected = append(collected, result.Transactions...)
+			if result.NextCursor == "" {
+				break
+			}
+			cursor, err := DecodeCursor(result.NextCursor)
+			require.NoError(t, err)
+			page.After = &cursor
+		}
+
+		require.Len(t, collected, len(timestamps))
+		seen := make(map[string]bool)
+		for i, tx := range collected {
+			assert.False(t, seen[tx.TransactionID], "duplicate transaction %s", tx.TransactionID)
+			seen[tx.TransactionID] = true
+			if i > 0 {
+				prev := collected[i-1]
+				assert.True(t, prev.Timestamp.After(tx.Timestamp) || (prev.Timestamp.Equal(tx.Timestamp) && prev.ID > tx.ID))
+			}
+		}
+	})
+
+	// --- Тестируем расширенные фильтры ---
+	t.Run("should filter by time range, amount range and transaction id prefix", func(t *testing.T) {
+		base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		txs := []models.Transaction{
+			{TransactionID: "flt_a-1", UserID: "user-filter", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD", Timestamp: base},
+			{TransactionID: "flt_a-2", UserID: "user-filter", TransactionType: models.TransactionTypeWin, Amount: 500, Currency: "USD", Timestamp: base.Add(time.Hour)},
+			{TransactionID: "flt_b-1", UserID: "user-filter", TransactionType: models.TransactionTypeBet, Amount: 1000, Currency: "USD", Timestamp: base.Add(2 * time.Hour)},
+			{TransactionID: "fltXa-3", UserID: "user-filter", TransactionType: models.TransactionTypeBet, Amount: 200, Currency: "USD", Timestamp: base.Add(3 * time.Hour)},
+		}
+		for _, tx := range txs {
+			require.NoError(t, repo.SaveTransaction(ctx, tx))
+		}
+
+		from, to := base.Add(time.Hour), base.Add(3*time.Hour)
+		inRange, err := repo.GetTransactionsByUserID(ctx, "user-filter", TransactionFilter{From: &from, To: &to}, PageRequest{})
+		require.NoError(t, err)
+		require.Len(t, inRange.Transactions, 2)
+		assert.Equal(t, "flt_b-1", inRange.Transactions[0].TransactionID)
+		assert.Equal(t, "flt_a-2", inRange.Transactions[1].TransactionID)
+
+		minAmount, maxAmount := int64(200), int64(500)
+		byAmount, err := repo.GetTransactionsByUserID(ctx, "user-filter", TransactionFilter{MinAmount: &minAmount, MaxAmount: &maxAmount}, PageRequest{})
 		require.NoError(t, err)
-		assert.Len(t, allWins, 1)
+		assert.Len(t, byAmount.Transactions, 2)
+
+		// "_" в префиксе должен сравниваться буквально, а не как любой символ
+		byPrefix, err := repo.GetAllTransactions(ctx, TransactionFilter{TransactionIDPrefix: "flt_a"}, PageRequest{})
+		require.NoError(t, err)
+		assert.Len(t, byPrefix.Transactions, 2)
+
+		multiType, err := repo.GetTransactionsByUserID(ctx, "user-filter", TransactionFilter{
+			Types: []models.TransactionType{models.TransactionTypeBet, models.TransactionTypeWin},
+		}, PageRequest{})
+		require.NoError(t, err)
+		assert.Len(t, multiType.Transactions, 4)
+	})
+	t.Run("should group transactions into rounds", func(t *testing.T) {
+		base := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+		txs := []models.Transaction{
+			{TransactionID: "round-bet-1", UserID: "user-round", TransactionType: models.TransactionTypeBet, Amount: 300, Currency: "USD", Timestamp: base, RoundID: "round-1", GameID: "slots-1", ProviderID: "prov-1", SessionID: "sess-1"},
+			{TransactionID: "round-bet-2", UserID: "user-round", TransactionType: models.TransactionTypeBet, Amount: 200, Currency: "USD", Timestamp: base.Add(time.Second), RoundID: "round-1", GameID: "slots-1", ProviderID: "prov-1", SessionID: "sess-1"},
+			{TransactionID: "round-win-1", UserID: "user-round", TransactionType: models.TransactionTypeWin, Amount: 800, Currency: "USD", Timestamp: base.Add(2 * time.Second), RoundID: "round-1", GameID: "slots-1", ProviderID: "prov-1", SessionID: "sess-1"},
+			{TransactionID: "round-bet-3", UserID: "user-round", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD", Timestamp: base.Add(3 * time.Second), RoundID: "round-2", GameID: "slots-1"},
+		}
+		require.NoError(t, repo.SaveTransactions(ctx, txs))
+
+		round, err := repo.GetRound(ctx, "round-1")
+		require.NoError(t, err)
+		assert.Equal(t, "user-round", round.UserID)
+		assert.Equal(t, models.RoundStatusClosed, round.Status)
+		assert.Equal(t, int64(300), round.NetResult)
+		require.Len(t, round.Transactions, 3)
+		assert.Equal(t, "round-bet-1", round.Transactions[0].TransactionID)
+		assert.Equal(t, "sess-1", round.Transactions[0].SessionID)
+
+		open, err := repo.GetRound(ctx, "round-2")
+		require.NoError(t, err)
+		assert.Equal(t, models.RoundStatusOpen, open.Status)
+
+		_, err = repo.GetRound(ctx, "round-missing")
+		assert.ErrorIs(t, err, ErrRoundNotFound)
+
+		byGame, err := repo.GetTransactionsByUserID(ctx, "user-round", TransactionFilter{GameID: "slots-1", RoundID: "round-2"}, PageRequest{})
+		require.NoError(t, err)
+		require.Len(t, byGame.Transactions, 1)
+		assert.Equal(t, "round-bet-3", byGame.Transactions[0].TransactionID)
+	})
+
+	t.Run("should reject transaction joining round of another user", func(t *testing.T) {
+		tx := models.Transaction{TransactionID: "round-foreign-1", UserID: "user123", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD", Timestamp: time.Now(), RoundID: "round-1"}
+		require.ErrorIs(t, repo.SaveTransaction(ctx, tx), ErrInvalidRound)
+	})
+
+	t.Run("should aggregate user summary per currency", func(t *testing.T) {
+		summary, err := repo.GetUserSummary(ctx, "user-round", nil, nil)
+		require.NoError(t, err)
+		require.Len(t, summary.Currencies, 1)
+		usd := summary.Currencies[0]
+		assert.Equal(t, "USD", usd.Currency)
+		assert.Equal(t, int64(600), usd.TotalWagered)
+		assert.Equal(t, int64(800), usd.TotalWon)
+		assert.Equal(t, int64(-200), usd.NetResult)
+		assert.Equal(t, int64(3), usd.BetCount)
+		assert.Equal(t, int64(1), usd.WinCount)
+		assert.Equal(t, int64(800), usd.LargestWin)
+		assert.True(t, time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC).Equal(usd.FirstActivity))
+
+		from := time.Date(2025, 7, 1, 12, 0, 3, 0, time.UTC)
+		bounded, err := repo.GetUserSummary(ctx, "user-round", &from, nil)
+		require.NoError(t, err)
+		require.Len(t, bounded.Currencies, 1)
+		assert.Equal(t, int64(100), bounded.Currencies[0].NetResult)
+		assert.Equal(t, int64(0), bounded.Currencies[0].WinCount)
+
+		empty, err := repo.GetUserSummary(ctx, "user-without-activity", nil, nil)
+		require.NoError(t, err)
+		assert.Empty(t, empty.Currencies)
+	})
+
+	t.Run("should report created transactions", func(t *testing.T) {
+		txs := []models.Transaction{
+			{TransactionID: "create-1", UserID: "user123", TransactionType: models.TransactionTypeWin, Amount: 100, Currency: "USD", Timestamp: time.Now()},
+			{TransactionID: "test-repo-001", UserID: "user123", TransactionType: models.TransactionTypeBet, Amount: 10050, Currency: "USD", Timestamp: time.Now()},
+		}
+		created, err := repo.CreateTransactions(ctx, txs)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"create-1"}, created)
+
+		created, err = repo.CreateTransactions(ctx, txs)
+		require.NoError(t, err)
+		assert.Empty(t, created)
+
+		tx := models.Transaction{TransactionID: "create-2", UserID: "user123", TransactionType: models.TransactionTypeWin, Amount: 100, Currency: "USD", Timestamp: time.Now()}
+		inserted, err := repo.CreateTransaction(ctx, tx)
+		require.NoError(t, err)
+		assert.True(t, inserted)
+
+		inserted, err = repo.CreateTransaction(ctx, tx)
+		require.NoError(t, err)
+		assert.False(t, inserted)
+	})
+
+	t.Run("should stream transactions in page order", func(t *testing.T) {
+		var streamed []string
+		err := repo.StreamTransactions(ctx, "user-round", TransactionFilter{RoundID: "round-1"}, func(tx models.Transaction) error {
+			streamed = append(streamed, tx.TransactionID)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"round-win-1", "round-bet-2", "round-bet-1"}, streamed)
+
+		stop := errors.New("stop")
+		count := 0
+		err = repo.StreamTransactions(ctx, "", TransactionFilter{}, func(models.Transaction) error {
+			count++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, count)
 	})
 }
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrInsufficientFunds возвращается, если ставка или вывод средств превышает баланс пользователя.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+type WalletRepository interface {
+	// GetBalances возвращает балансы пользователя по всем валютам, в которых были движения,
+	// упорядоченные по коду валюты.
+	GetBalances(ctx context.Context, userID string) ([]models.Balance, error)
+}
+
+type postgresWalletRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresWalletRepository(db *pgxpool.Pool) WalletRepository {
+	return &postgresWalletRepository{db: db}
+}
+
+func (r *postgresWalletRepository) GetBalances(ctx context.Context, userID string) ([]models.Balance, error) {
+	// Суммы в разных валютах не складываются: у каждой валюты своя строка баланса.
+	rows, err := r.db.Query(ctx, `
+		SELECT user_id, currency, balance, updated_at FROM balances
+		WHERE user_id = $1
+		ORDER BY currency
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get balances: %w", err)
+	}
+	balances, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Balance])
+	if err != nil {
+		return nil, fmt.Errorf("could not scan balances: %w", err)
+	}
+	return balances, nil
+}
+
+// applyTransaction изменяет баланс в рамках транзакции БД dbTx, в которой сохраняется tx.
+// Ставка и вывод средств списывают сумму и отклоняются с ErrInsufficientFunds,
+// если средств недостаточно; выигрыш, возврат, отмена ставки и депозит зачисляют сумму.
+func (r *postgresWalletRepository) applyTransaction(ctx context.Context, dbTx pgx.Tx, tx models.Transaction) error {
+	sql, args, err := balanceStatement(tx)
+	if err != nil {
+		return err
+	}
+	tag, err := dbTx.Exec(ctx, sql, args...)
+	return checkBalanceResult(tx, tag, err)
+}
+
+// applyTransactions изменяет баланс для нескольких транзакций в порядке следования,
+// отправляя все запросы одним пакетом (pipeline).
+func (r *postgresWalletRepository) applyTransactions(ctx context.Context, dbTx pgx.Tx, txs []models.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, tx := range txs {
+		sql, args, err := balanceStatement(tx)
+		if err != nil {
+			return err
+		}
+		batch.Queue(sql, args...)
+	}
+
+	results := dbTx.SendBatch(ctx, batch)
+	for _, tx := range txs {
+		tag, err := results.Exec()
+		if err := checkBalanceResult(tx, tag, err); err != nil {
+			_ = results.Close()
+			return err
+		}
+	}
+	return results.Close()
+}
+
+// balanceStatement возвращает запрос, изменяющий баланс под действием tx.
+func balanceStatement(tx models.Transaction) (string, []any, error) {
+	if !tx.TransactionType.Valid() {
+		return "", nil, fmt.Errorf("unsupported transaction type for balance: %s", tx.TransactionType)
+	}
+	if tx.TransactionType.IsDebit() {
+		// Условие balance >= $2 вместе с блокировкой строки исключает уход в минус
+		// при конкурентных списаниях.
+		return `
+			UPDATE balances SET balance = balance - $2, updated_at = now()
+			WHERE user_id = $1 AND currency = $3 AND balance >= $2
+		`, []any{tx.UserID, tx.Amount, tx.Currency}, nil
+	}
+	return `
+		INSERT INTO balances (user_id, currency, balance) VALUES ($1, $3, $2)
+		ON CONFLICT (user_id, currency) DO UPDATE SET balance = balances.balance + EXCLUDED.balance, updated_at = now()
+	`, []any{tx.UserID, tx.Amount, tx.Currency}, nil
+}
+
+// checkBalanceResult проверяет результат запроса из balanceStatement:
+// списание, не затронувшее ни одной строки, означает нехватку средств.
+func checkBalanceResult(tx models.Transaction, tag pgconn.CommandTag, err error) error {
+	if err != nil {
+		return fmt.Errorf("could not update balance: %w", err)
+	}
+	if tx.TransactionType.IsDebit() && tag.RowsAffected() == 0 {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresWalletRepository(t *testing.T) {
+	ctx := context.Background()
+	dbpool, cleanup := setupTestDB(ctx)
+	defer cleanup()
+
+	txRepo := NewPostgresRepository(dbpool)
+	walletRepo := NewPostgresWalletRepository(dbpool)
+
+	t.Run("should return zero balance for unknown user", func(t *testing.T) {
+		balances, err := walletRepo.GetBalances(ctx, "wallet-unknown")
+		require.NoError(t, err)
+		assert.Empty(t, balances)
+
+		balance := balanceIn(ctx, t, walletRepo, "wallet-unknown", "USD")
+		assert.Equal(t, int64(0), balance.Balance)
+		assert.Nil(t, balance.UpdatedAt)
+	})
+
+	t.Run("should credit wins and debit bets", func(t *testing.T) {
+		win := models.Transaction{TransactionID: "wallet-001", UserID: "wallet-user", TransactionType: models.TransactionTypeWin, Amount: 5000, Currency: "USD", Timestamp: time.Now()}
+		bet := models.Transaction{TransactionID: "wallet-002", UserID: "wallet-user", TransactionType: models.TransactionTypeBet, Amount: 1500, Currency: "USD", Timestamp: time.Now()}
+
+		require.NoError(t, txRepo.SaveTransaction(ctx, win))
+		require.NoError(t, txRepo.SaveTransaction(ctx, bet))
+
+		balance := balanceIn(ctx, t, walletRepo, "wallet-user", "USD")
+		assert.Equal(t, int64(3500), balance.Balance)
+		assert.NotNil(t, balance.UpdatedAt)
+	})
+
+	t.Run("should not apply duplicate transaction twice", func(t *testing.T) {
+		duplicate := models.Transaction{TransactionID: "wallet-001", UserID: "wallet-user", TransactionType: models.TransactionTypeWin, Amount: 5000, Currency: "USD", Timestamp: time.Now()}
+		require.NoError(t, txRepo.SaveTransaction(ctx, duplicate))
+
+		balance := balanceIn(ctx, t, walletRepo, "wallet-user", "USD")
+		assert.Equal(t, int64(3500), balance.Balance)
+	})
+
+	t.Run("should reject bet that would overdraw and not store it", func(t *testing.T) {
+		bet := models.Transaction{TransactionID: "wallet-003", UserID: "wallet-user", TransactionType: models.TransactionTypeBet, Amount: 3501, Currency: "USD", Timestamp: time.Now()}
+
+		err := txRepo.SaveTransaction(ctx, bet)
+		require.ErrorIs(t, err, ErrInsufficientFunds)
+
+		var count int
+		require.NoError(t, dbpool.QueryRow(ctx, "SELECT count(*) FROM transactions WHERE transaction_id = $1", bet.TransactionID).Scan(&count))
+		assert.Zero(t, count)
+
+		balance := balanceIn(ctx, t, walletRepo, "wallet-user", "USD")
+		assert.Equal(t, int64(3500), balance.Balance)
+	})
+
+	t.Run("should reject bet for user without balance", func(t *testing.T) {
+		bet := models.Transaction{TransactionID: "wallet-004", UserID: "wallet-empty", TransactionType: models.TransactionTypeBet, Amount: 1, Currency: "USD", Timestamp: time.Now()}
+		require.ErrorIs(t, txRepo.SaveTransaction(ctx, bet), ErrInsufficientFunds)
+	})
+
+	t.Run("should save batch and apply balances in order, skipping duplicates", func(t *testing.T) {
+		now := time.Now()
+		batch := []models.Transaction{
+			{TransactionID: "wallet-batch-001", UserID: "wallet-batch", TransactionType: models.TransactionTypeWin, Amount: 1000, Currency: "USD", Timestamp: now},
+			{TransactionID: "wallet-batch-002", UserID: "wallet-batch", TransactionType: models.TransactionTypeBet, Amount: 700, Currency: "USD", Timestamp: now},
+			// Повтор внутри пачки
+			{TransactionID: "wallet-batch-001", UserID: "wallet-batch", TransactionType: models.TransactionTypeWin, Amount: 1000, Currency: "USD", Timestamp: now},
+			// Уже сохранена ранее
+			{TransactionID: "wallet-001", UserID: "wallet-user", TransactionType: models.TransactionTypeWin, Amount: 5000, Currency: "USD", Timestamp: now},
+		}
+		require.NoError(t, txRepo.SaveTransactions(ctx, batch))
+
+		balance := balanceIn(ctx, t, walletRepo, "wallet-batch", "USD")
+		assert.Equal(t, int64(300), balance.Balance)
+
+		other := balanceIn(ctx, t, walletRepo, "wallet-user", "USD")
+		assert.Equal(t, int64(3500), other.Balance)
+
+		var count int
+		require.NoError(t, dbpool.QueryRow(ctx, "SELECT count(*) FROM transactions WHERE user_id = 'wallet-batch'").Scan(&count))
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("should reject whole batch when a bet would overdraw", func(t *testing.T) {
+		now := time.Now()
+		batch := []models.Transaction{
+			{TransactionID: "wallet-batch-003", UserID: "wallet-batch", TransactionType: models.TransactionTypeWin, Amount: 100, Currency: "USD", Timestamp: now},
+			{TransactionID: "wallet-batch-004", UserID: "wallet-batch", TransactionType: models.TransactionTypeBet, Amount: 1000, Currency: "USD", Timestamp: now},
+		}
+		require.ErrorIs(t, txRepo.SaveTransactions(ctx, batch), ErrInsufficientFunds)
+
+		var count int
+		require.NoError(t, dbpool.QueryRow(ctx, "SELECT count(*) FROM transactions WHERE transaction_id IN ('wallet-batch-003', 'wallet-batch-004')").Scan(&count))
+		assert.Zero(t, count)
+
+		balance := balanceIn(ctx, t, walletRepo, "wallet-batch", "USD")
+		assert.Equal(t, int64(300), balance.Balance)
+	})
+
+	t.Run("should store and apply the first copy of a transaction repeated in a batch", func(t *testing.T) {
+		now := time.Now()
+		created, err := txRepo.CreateTransactions(ctx, []models.Transaction{
+			{TransactionID: "wallet-repeat-001", UserID: "wallet-repeat", TransactionType: models.TransactionTypeDeposit, Amount: 100, Currency: "USD", Timestamp: now},
+			{TransactionID: "wallet-repeat-001", UserID: "wallet-repeat", TransactionType: models.TransactionTypeDeposit, Amount: 900, Currency: "USD", Timestamp: now},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"wallet-repeat-001"}, created)
+
+		var stored int64
+		require.NoError(t, dbpool.QueryRow(ctx, "SELECT amount FROM transactions WHERE transaction_id = 'wallet-repeat-001'").Scan(&stored))
+		assert.Equal(t, int64(100), stored)
+		assert.Equal(t, stored, balanceIn(ctx, t, walletRepo, "wallet-repeat", "USD").Balance)
+	})
+
+	t.Run("should keep separate balances per currency", func(t *testing.T) {
+		deposit := models.Transaction{TransactionID: "wallet-eur-001", UserID: "wallet-user", TransactionType: models.TransactionTypeDeposit, Amount: 700, Currency: "EUR", Timestamp: time.Now()}
+		require.NoError(t, txRepo.SaveTransaction(ctx, deposit))
+
+		bet := models.Transaction{TransactionID: "wallet-eur-002", UserID: "wallet-user", TransactionType: models.TransactionTypeBet, Amount: 1000, Currency: "EUR", Timestamp: time.Now()}
+		require.ErrorIs(t, txRepo.SaveTransaction(ctx, bet), ErrInsufficientFunds, "USD funds must not cover EUR bets")
+
+		balances, err := walletRepo.GetBalances(ctx, "wallet-user")
+		require.NoError(t, err)
+		require.Len(t, balances, 2)
+		assert.Equal(t, "EUR", balances[0].Currency)
+		assert.Equal(t, int64(700), balances[0].Balance)
+		assert.Equal(t, "USD", balances[1].Currency)
+		assert.Equal(t, int64(3500), balances[1].Balance)
+	})
+
+	t.Run("should reject unknown currency", func(t *testing.T) {
+		win := models.Transaction{TransactionID: "wallet-xxx-001", UserID: "wallet-user", TransactionType: models.TransactionTypeWin, Amount: 100, Currency: "XXX", Timestamp: time.Now()}
+		require.ErrorIs(t, txRepo.SaveTransaction(ctx, win), ErrUnknownCurrency)
+		require.ErrorIs(t, txRepo.SaveTransactions(ctx, []models.Transaction{win}), ErrUnknownCurrency)
+	})
+}
+
+// balanceIn возвращает баланс пользователя в валюте currency или нулевой баланс, если движений не было.
+func balanceIn(ctx context.Context, t *testing.T, walletRepo WalletRepository, userID, currency string) models.Balance {
+	t.Helper()
+	balances, err := walletRepo.GetBalances(ctx, userID)
+	require.NoError(t, err)
+	for _, b := range balances {
+		if b.Currency == currency {
+			return b
+		}
+	}
+	return models.Balance{UserID: userID, Currency: currency}
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// HeaderCarrier адаптирует заголовки сообщения Kafka к propagation.TextMapCarrier.
+type HeaderCarrier struct {
+	Headers *[]kafka.Header
+}
+
+func (c HeaderCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set заменяет заголовок key, если он уже есть, иначе добавляет его.
+func (c HeaderCarrier) Set(key, value string) {
+	for i, h := range *c.Headers {
+		if h.Key == key {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, len(*c.Headers))
+	for i, h := range *c.Headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
+
+var _ propagation.TextMapCarrier = HeaderCarrier{}
+
+// Inject записывает контекст трассировки ctx в заголовки msg.
+func Inject(ctx context.Context, msg *kafka.Message) {
+	propagator.Inject(ctx, HeaderCarrier{Headers: &msg.Headers})
+}
+
+// Extract возвращает ctx, дополненный контекстом трассировки из заголовков msg.
+func Extract(ctx context.Context, msg kafka.Message) context.Context {
+	return propagator.Extract(ctx, HeaderCarrier{Headers: &msg.Headers})
+}
+
+// MessageAttributes описывает положение сообщения в Kafka атрибутами span'а.
+func MessageAttributes(msg kafka.Message) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.MessagingSystemKafka,
+		semconv.MessagingDestinationName(msg.Topic),
+		semconv.MessagingDestinationPartitionID(strconv.Itoa(msg.Partition)),
+		semconv.MessagingKafkaOffset(int(msg.Offset)),
+	}
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName — имя, под которым компоненты сервиса создают свои span'ы.
+const instrumentationName = "github.com/OlgaPie/casino-transaction-system"
+
+// propagator переносит контекст трассировки в формате W3C Trace Context
+// (заголовки traceparent и tracestate) через HTTP-запросы и сообщения Kafka.
+var propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Config задает экспорт span'ов. Пустой Endpoint отключает трассировку.
+type Config struct {
+	// ServiceName попадает в атрибут service.name всех span'ов.
+	ServiceName string
+	// Endpoint — URL OTLP/HTTP collector'а, например http://localhost:4318.
+	Endpoint string
+	// SampleRatio — доля трассировок, начатых в этом сервисе, которые будут записаны.
+	// Для продолжения чужой трассировки действует решение вызывающей стороны.
+	SampleRatio float64
+}
+
+// Shutdown отправляет накопленные span'ы и останавливает экспорт.
+type Shutdown func(ctx context.Context) error
+
+// NewTracerProvider создает провайдер, экспортирующий span'ы по OTLP/HTTP.
+// Без cfg.Endpoint возвращает no-op провайдер, который ничего не записывает.
+func NewTracerProvider(ctx context.Context, cfg Config) (trace.TracerProvider, Shutdown, error) {
+	if cfg.Endpoint == "" {
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create OTLP exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	return tp, tp.Shutdown, nil
+}
+
+// Tracer возвращает tracer сервиса; nil tp означает no-op.
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return tp.Tracer(instrumentationName)
+}
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929b0e0e4736-00f067aa0ba902b7-01"
+
+func newRecorder() (*tracetest.SpanRecorder, trace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	return recorder, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+}
+
+func TestNewTracerProvider_Disabled(t *testing.T) {
+	tp, shutdown, err := NewTracerProvider(context.Background(), Config{ServiceName: "test"})
+	require.NoError(t, err)
+
+	_, span := tp.Tracer("test").Start(context.Background(), "noop")
+	assert.False(t, span.SpanContext().IsValid())
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestKafkaPropagation(t *testing.T) {
+	_, tp := newRecorder()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "publish")
+	defer span.End()
+
+	msg := kafka.Message{Headers: []kafka.Header{{Key: "traceparent", Value: []byte("stale")}, {Key: "source", Value: []byte("api")}}}
+	Inject(ctx, &msg)
+
+	// Устаревший заголовок заменяется, а не дублируется.
+	assert.Len(t, msg.Headers, 2)
+	assert.Equal(t, []string{"traceparent", "source"}, HeaderCarrier{Headers: &msg.Headers}.Keys())
+
+	extracted := trace.SpanContextFromContext(Extract(context.Background(), msg))
+	assert.Equal(t, span.SpanContext().TraceID(), extracted.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), extracted.SpanID())
+	assert.True(t, extracted.IsRemote())
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder, tp := newRecorder()
+
+	r := chi.NewRouter()
+	r.Use(Middleware(tp))
+	r.Get("/users/{userID}/balance", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/u1/balance", nil)
+	req.Header.Set("traceparent", traceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /users/{userID}/balance", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929b0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Contains(t, span.Attributes(), semconv.HTTPRoute("/users/{userID}/balance"))
+	assert.Contains(t, span.Attributes(), semconv.HTTPResponseStatusCode(http.StatusInternalServerError))
+	assert.Equal(t, codes.Error, span.Status().Code)
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder, tp := newRecorder()
+	tracer := NewQueryTracer(tp)
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{
+		SQL:  "select balance from balances where user_id = $1",
+		Args: []any{"secret-user"},
+	})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+
+	ctx = tracer.TraceCopyFromStart(context.Background(), nil, pgx.TraceCopyFromStartData{TableName: pgx.Identifier{"transactions_staging"}})
+	tracer.TraceCopyFromEnd(ctx, nil, pgx.TraceCopyFromEndData{Err: errors.New("copy failed")})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "SELECT", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), semconv.DBQueryText("select balance from balances where user_id = $1"))
+	for _, attr := range spans[0].Attributes() {
+		assert.NotContains(t, attr.Value.Emit(), "secret-user", "query arguments must not be recorded")
+	}
+	assert.Equal(t, `COPY "transactions_staging"`, spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
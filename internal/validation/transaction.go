@@ -0,0 +1,78 @@
+package validation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+)
+
+// Normalize дополняет транзакцию из внешнего источника и проверяет ее.
+// Пустая валюта заменяется на defaultCurrency, пустое время — на now,
+// а пустой transaction_id генерируется по содержимому транзакции.
+// Эти правила одинаковы для сообщений из Kafka и запросов к API.
+func Normalize(tx models.Transaction, defaultCurrency string, now time.Time) (models.Transaction, error) {
+	if tx.Currency == "" {
+		tx.Currency = defaultCurrency
+	}
+
+	if err := Validate(tx); err != nil {
+		return tx, err
+	}
+
+	if tx.Timestamp.IsZero() {
+		tx.Timestamp = now
+	}
+	if tx.TransactionID == "" {
+		tx.TransactionID = GenerateTransactionID(tx)
+	}
+	return tx, nil
+}
+
+// Validate проверяет транзакцию до сохранения. Поддерживается ли валюта,
+// существует ли исходная ставка и чей это раунд, проверяется при сохранении.
+func Validate(tx models.Transaction) error {
+	if tx.UserID == "" {
+		return errors.New("missing user_id")
+	}
+	if !models.IsUserID(tx.UserID) {
+		return fmt.Errorf("invalid user_id: %q", tx.UserID)
+	}
+	if !tx.TransactionType.Valid() {
+		return fmt.Errorf("invalid transaction_type: %s", tx.TransactionType)
+	}
+	// Нулевой выигрыш допустим: им провайдер закрывает проигранный раунд.
+	if tx.Amount < 0 || (tx.Amount == 0 && (tx.TransactionType != models.TransactionTypeWin || tx.RoundID == "")) {
+		return fmt.Errorf("invalid amount: %d", tx.Amount)
+	}
+	if tx.RoundID != "" && !tx.TransactionType.IsGameplay() {
+		return fmt.Errorf("unexpected round_id for %s", tx.TransactionType)
+	}
+	if tx.Currency == "" {
+		return errors.New("missing currency")
+	}
+	if !models.IsCurrencyCode(tx.Currency) {
+		return fmt.Errorf("invalid currency: %q", tx.Currency)
+	}
+	if tx.TransactionType.RequiresReference() {
+		if tx.ReferenceTransactionID == "" {
+			return fmt.Errorf("missing reference_transaction_id for %s", tx.TransactionType)
+		}
+		if tx.ReferenceTransactionID == tx.TransactionID {
+			return fmt.Errorf("transaction %s references itself", tx.TransactionID)
+		}
+	} else if tx.ReferenceTransactionID != "" {
+		return fmt.Errorf("unexpected reference_transaction_id for %s", tx.TransactionType)
+	}
+	return nil
+}
+
+// GenerateTransactionID возвращает детерминированный transaction_id для транзакции без него.
+func GenerateTransactionID(tx models.Transaction) string {
+	data := fmt.Sprintf("%s:%s:%d:%s:%d", tx.UserID, tx.TransactionType, tx.Amount, tx.Currency, tx.Timestamp.UnixNano())
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OlgaPie/casino-transaction-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tx      models.Transaction
+		wantErr bool
+	}{
+		{"bet", models.Transaction{UserID: "u1", TransactionID: "v-1", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD"}, false},
+		{"deposit", models.Transaction{UserID: "u1", TransactionID: "v-2", TransactionType: models.TransactionTypeDeposit, Amount: 100, Currency: "USD"}, false},
+		{"withdrawal", models.Transaction{UserID: "u1", TransactionID: "v-3", TransactionType: models.TransactionTypeWithdrawal, Amount: 100, Currency: "USD"}, false},
+		{"rollback with reference", models.Transaction{UserID: "u1", TransactionID: "v-4", TransactionType: models.TransactionTypeRollback, Amount: 100, Currency: "USD", ReferenceTransactionID: "v-1"}, false},
+		{"refund with reference", models.Transaction{UserID: "u1", TransactionID: "v-5", TransactionType: models.TransactionTypeRefund, Amount: 50, Currency: "USD", ReferenceTransactionID: "v-1"}, false},
+		{"unknown type", models.Transaction{UserID: "u1", TransactionID: "v-6", TransactionType: "bonus", Amount: 100, Currency: "USD"}, true},
+		{"zero amount", models.Transaction{UserID: "u1", TransactionID: "v-7", TransactionType: models.TransactionTypeWin}, true},
+		{"rollback without reference", models.Transaction{UserID: "u1", TransactionID: "v-8", TransactionType: models.TransactionTypeRollback, Amount: 100, Currency: "USD"}, true},
+		{"refund referencing itself", models.Transaction{UserID: "u1", TransactionID: "v-9", TransactionType: models.TransactionTypeRefund, Amount: 100, Currency: "USD", ReferenceTransactionID: "v-9"}, true},
+		{"deposit with reference", models.Transaction{UserID: "u1", TransactionID: "v-10", TransactionType: models.TransactionTypeDeposit, Amount: 100, Currency: "USD", ReferenceTransactionID: "v-1"}, true},
+		{"missing currency", models.Transaction{UserID: "u1", TransactionID: "v-11", TransactionType: models.TransactionTypeBet, Amount: 100}, true},
+		{"lowercase currency", models.Transaction{UserID: "u1", TransactionID: "v-12", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "usd"}, true},
+		{"malformed currency", models.Transaction{UserID: "u1", TransactionID: "v-13", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "EURO"}, true},
+		{"bet in round", models.Transaction{UserID: "u1", TransactionID: "v-14", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD", RoundID: "r-1", GameID: "g-1"}, false},
+		{"zero win closing round", models.Transaction{UserID: "u1", TransactionID: "v-15", TransactionType: models.TransactionTypeWin, Currency: "USD", RoundID: "r-1"}, false},
+		{"zero bet in round", models.Transaction{UserID: "u1", TransactionID: "v-16", TransactionType: models.TransactionTypeBet, Currency: "USD", RoundID: "r-1"}, true},
+		{"deposit in round", models.Transaction{UserID: "u1", TransactionID: "v-17", TransactionType: models.TransactionTypeDeposit, Amount: 100, Currency: "USD", RoundID: "r-1"}, true},
+		{"missing user", models.Transaction{TransactionID: "v-18", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD"}, true},
+		{"user with spaces", models.Transaction{UserID: "user 1", TransactionID: "v-19", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD"}, true},
+		{"too long user", models.Transaction{UserID: strings.Repeat("u", models.MaxUserIDLength+1), TransactionID: "v-20", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD"}, true},
+		{"user with allowed symbols", models.Transaction{UserID: "tenant:user-1_a.b@c", TransactionID: "v-21", TransactionType: models.TransactionTypeBet, Amount: 100, Currency: "USD"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.tx)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should fill currency, timestamp and transaction id", func(t *testing.T) {
+		tx, err := Normalize(models.Transaction{UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: 100}, "EUR", now)
+		require.NoError(t, err)
+		assert.Equal(t, "EUR", tx.Currency)
+		assert.Equal(t, now, tx.Timestamp)
+		assert.Len(t, tx.TransactionID, 64)
+
+		again, err := Normalize(models.Transaction{UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: 100}, "EUR", now)
+		require.NoError(t, err)
+		assert.Equal(t, tx.TransactionID, again.TransactionID)
+	})
+
+	t.Run("should keep provided fields", func(t *testing.T) {
+		ts := now.Add(-time.Hour)
+		tx, err := Normalize(models.Transaction{TransactionID: "n-1", UserID: "u1", TransactionType: models.TransactionTypeWin, Amount: 100, Currency: "USD", Timestamp: ts}, "EUR", now)
+		require.NoError(t, err)
+		assert.Equal(t, "n-1", tx.TransactionID)
+		assert.Equal(t, "USD", tx.Currency)
+		assert.Equal(t, ts, tx.Timestamp)
+	})
+
+	t.Run("should reject transaction without currency and default", func(t *testing.T) {
+		_, err := Normalize(models.Transaction{UserID: "u1", TransactionType: models.TransactionTypeBet, Amount: 100}, "", now)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,2 @@
+DROP INDEX idx_transactions_timestamp_id;
+DROP INDEX idx_transactions_user_id_timestamp_id;
@@ -0,0 +1,3 @@
+-- Индексы для keyset-пагинации: ORDER BY "timestamp" DESC, id DESC
+CREATE INDEX idx_transactions_user_id_timestamp_id ON transactions (user_id, "timestamp" DESC, id DESC);
+CREATE INDEX idx_transactions_timestamp_id ON transactions ("timestamp" DESC, id DESC);
@@ -0,0 +1 @@
+DROP INDEX idx_transactions_transaction_id_pattern;
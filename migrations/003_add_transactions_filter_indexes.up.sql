@@ -0,0 +1,4 @@
+-- Поиск по префиксу transaction_id (LIKE 'prefix%') использует обычный B-tree индекс только
+-- при collation "C". Индекс с классом операторов varchar_pattern_ops сравнивает строки побайтно,
+-- поэтому подходит для такого поиска при любой collation базы.
+CREATE INDEX idx_transactions_transaction_id_pattern ON transactions (transaction_id varchar_pattern_ops);
@@ -0,0 +1,6 @@
+CREATE TABLE balances
+(
+    user_id    VARCHAR(255) PRIMARY KEY,
+    balance    BIGINT       NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    updated_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
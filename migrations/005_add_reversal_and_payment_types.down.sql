@@ -0,0 +1,13 @@
+-- Откат невозможен, пока в таблице есть транзакции новых типов: ограничение не будет создано
+DROP INDEX idx_transactions_rollback_reference;
+DROP INDEX idx_transactions_reference_transaction_id;
+ALTER TABLE transactions
+    DROP CONSTRAINT transactions_reference_transaction_id_check;
+ALTER TABLE transactions
+    DROP COLUMN reference_transaction_id;
+
+ALTER TABLE transactions
+    DROP CONSTRAINT transactions_transaction_type_check;
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_transaction_type_check
+        CHECK (transaction_type IN ('bet', 'win'));
@@ -0,0 +1,18 @@
+ALTER TABLE transactions
+    DROP CONSTRAINT transactions_transaction_type_check;
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_transaction_type_check
+        CHECK (transaction_type IN ('bet', 'win', 'refund', 'rollback', 'deposit', 'withdrawal'));
+
+-- refund и rollback ссылаются на исходную ставку, остальные типы ссылки не имеют
+ALTER TABLE transactions
+    ADD COLUMN reference_transaction_id VARCHAR(255);
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_reference_transaction_id_check
+        CHECK ((transaction_type IN ('refund', 'rollback')) = (reference_transaction_id IS NOT NULL));
+
+CREATE INDEX idx_transactions_reference_transaction_id ON transactions (reference_transaction_id)
+    WHERE reference_transaction_id IS NOT NULL;
+-- Ставку можно отменить только один раз
+CREATE UNIQUE INDEX idx_transactions_rollback_reference ON transactions (reference_transaction_id)
+    WHERE transaction_type = 'rollback';
@@ -0,0 +1,14 @@
+-- Откат невозможен, пока у пользователя есть балансы в нескольких валютах:
+-- первичный ключ по user_id не будет создан
+ALTER TABLE balances
+    DROP CONSTRAINT balances_pkey;
+ALTER TABLE balances
+    ADD PRIMARY KEY (user_id);
+ALTER TABLE balances
+    DROP COLUMN currency;
+
+DROP INDEX idx_transactions_currency_timestamp_id;
+ALTER TABLE transactions
+    DROP COLUMN currency;
+
+DROP TABLE currencies;
@@ -0,0 +1,51 @@
+-- Валюты ISO 4217 и число знаков дробной части (minor units).
+-- Все суммы хранятся в минимальных единицах валюты: для USD это центы, для JPY — иены.
+CREATE TABLE currencies
+(
+    code        CHAR(3)  PRIMARY KEY CHECK (code ~ '^[A-Z]{3}$'),
+    minor_units SMALLINT NOT NULL CHECK (minor_units BETWEEN 0 AND 4)
+);
+
+INSERT INTO currencies (code, minor_units)
+VALUES ('AUD', 2),
+       ('BHD', 3),
+       ('BRL', 2),
+       ('CAD', 2),
+       ('CHF', 2),
+       ('CLP', 0),
+       ('CZK', 2),
+       ('DKK', 2),
+       ('EUR', 2),
+       ('GBP', 2),
+       ('HUF', 2),
+       ('INR', 2),
+       ('JPY', 0),
+       ('KRW', 0),
+       ('KWD', 3),
+       ('MXN', 2),
+       ('NOK', 2),
+       ('NZD', 2),
+       ('PLN', 2),
+       ('RON', 2),
+       ('SEK', 2),
+       ('TRY', 2),
+       ('UAH', 2),
+       ('USD', 2),
+       ('ZAR', 2);
+
+-- Существующие записи созданы до поддержки валют и считаются долларовыми
+ALTER TABLE transactions
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD' REFERENCES currencies (code);
+ALTER TABLE transactions
+    ALTER COLUMN currency DROP DEFAULT;
+CREATE INDEX idx_transactions_currency_timestamp_id ON transactions (currency, "timestamp" DESC, id DESC);
+
+-- Баланс ведется отдельно по каждой валюте
+ALTER TABLE balances
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD' REFERENCES currencies (code);
+ALTER TABLE balances
+    ALTER COLUMN currency DROP DEFAULT;
+ALTER TABLE balances
+    DROP CONSTRAINT balances_pkey;
+ALTER TABLE balances
+    ADD PRIMARY KEY (user_id, currency);
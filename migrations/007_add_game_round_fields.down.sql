@@ -0,0 +1,15 @@
+DROP INDEX idx_transactions_session_id;
+DROP INDEX idx_transactions_provider_id_timestamp_id;
+DROP INDEX idx_transactions_game_id_timestamp_id;
+DROP INDEX idx_transactions_round_id;
+
+ALTER TABLE transactions
+    DROP CONSTRAINT transactions_amount_check;
+ALTER TABLE transactions
+    DROP CONSTRAINT transactions_round_id_check;
+
+ALTER TABLE transactions
+    DROP COLUMN session_id,
+    DROP COLUMN provider_id,
+    DROP COLUMN game_id,
+    DROP COLUMN round_id;
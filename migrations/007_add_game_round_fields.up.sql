@@ -0,0 +1,26 @@
+-- Игровой контекст транзакции: раунд (спин, раздача), игра, провайдер и игровая сессия.
+-- Все поля необязательны; депозиты и выводы средств к раундам не относятся.
+ALTER TABLE transactions
+    ADD COLUMN round_id    VARCHAR(255),
+    ADD COLUMN game_id     VARCHAR(255),
+    ADD COLUMN provider_id VARCHAR(255),
+    ADD COLUMN session_id  VARCHAR(255);
+
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_round_id_check
+        CHECK (round_id IS NULL OR transaction_type NOT IN ('deposit', 'withdrawal'));
+
+-- Нулевая сумма допускается только для выигрыша, закрывающего проигранный раунд
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_amount_check
+        CHECK (amount > 0 OR (amount = 0 AND transaction_type = 'win' AND round_id IS NOT NULL));
+
+-- Индексы для GET /rounds/{roundID} (в порядке совершения) и фильтров по игровому контексту
+CREATE INDEX idx_transactions_round_id ON transactions (round_id, "timestamp", id)
+    WHERE round_id IS NOT NULL;
+CREATE INDEX idx_transactions_game_id_timestamp_id ON transactions (game_id, "timestamp" DESC, id DESC)
+    WHERE game_id IS NOT NULL;
+CREATE INDEX idx_transactions_provider_id_timestamp_id ON transactions (provider_id, "timestamp" DESC, id DESC)
+    WHERE provider_id IS NOT NULL;
+CREATE INDEX idx_transactions_session_id ON transactions (session_id)
+    WHERE session_id IS NOT NULL;
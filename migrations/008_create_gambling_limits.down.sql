@@ -0,0 +1,3 @@
+DROP TABLE limit_violations;
+DROP TABLE self_exclusions;
+DROP TABLE user_limits;
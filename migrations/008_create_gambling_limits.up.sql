@@ -0,0 +1,40 @@
+-- Лимиты ответственной игры: на проигрыш (ставки минус выигрыши и возвраты)
+-- и на сумму ставок за календарный день, неделю или месяц в UTC.
+-- Лимит задается отдельно для каждой валюты.
+CREATE TABLE user_limits
+(
+    user_id    VARCHAR(255) NOT NULL,
+    limit_type VARCHAR(10)  NOT NULL CHECK (limit_type IN ('loss', 'wager')),
+    period     VARCHAR(10)  NOT NULL CHECK (period IN ('day', 'week', 'month')),
+    currency   CHAR(3)      NOT NULL REFERENCES currencies (code),
+    amount     BIGINT       NOT NULL CHECK (amount > 0),
+    updated_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, limit_type, period, currency)
+);
+
+-- Самоисключение: до excluded_until пользователь не может делать ставки и депозиты
+CREATE TABLE self_exclusions
+(
+    user_id        VARCHAR(255) PRIMARY KEY,
+    excluded_until TIMESTAMPTZ  NOT NULL,
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+-- Журнал отклоненных из-за лимитов транзакций
+CREATE TABLE limit_violations
+(
+    id               BIGSERIAL PRIMARY KEY,
+    user_id          VARCHAR(255) NOT NULL,
+    transaction_id   VARCHAR(255) NOT NULL,
+    violation_type   VARCHAR(20)  NOT NULL CHECK (violation_type IN ('self_exclusion', 'loss_limit', 'wager_limit')),
+    period           VARCHAR(10),
+    currency         CHAR(3)      NOT NULL,
+    limit_amount     BIGINT,
+    current_amount   BIGINT,
+    attempted_amount BIGINT       NOT NULL,
+    occurred_at      TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_limit_violations_user_id_occurred_at ON limit_violations (user_id, occurred_at DESC);
+-- Повторно доставленное сообщение не дублирует запись о нарушении
+CREATE UNIQUE INDEX idx_limit_violations_transaction_id ON limit_violations (transaction_id, violation_type);
@@ -0,0 +1,2 @@
+DROP TABLE ggr_daily;
+DROP TABLE ggr_hourly;
@@ -0,0 +1,44 @@
+-- Агрегаты игровых транзакций для отчетов GGR по часам и дням в UTC.
+-- Обновляются в той же транзакции БД, в которой сохраняется транзакция,
+-- поэтому отчеты не сканируют таблицу transactions.
+CREATE TABLE ggr_hourly
+(
+    period_start TIMESTAMPTZ NOT NULL,
+    currency     CHAR(3)     NOT NULL REFERENCES currencies (code),
+    bets         BIGINT      NOT NULL DEFAULT 0,
+    wins         BIGINT      NOT NULL DEFAULT 0,
+    reversals    BIGINT      NOT NULL DEFAULT 0,
+    bet_count    BIGINT      NOT NULL DEFAULT 0,
+    win_count    BIGINT      NOT NULL DEFAULT 0,
+    PRIMARY KEY (period_start, currency)
+);
+
+CREATE TABLE ggr_daily
+(
+    period_start TIMESTAMPTZ NOT NULL,
+    currency     CHAR(3)     NOT NULL REFERENCES currencies (code),
+    bets         BIGINT      NOT NULL DEFAULT 0,
+    wins         BIGINT      NOT NULL DEFAULT 0,
+    reversals    BIGINT      NOT NULL DEFAULT 0,
+    bet_count    BIGINT      NOT NULL DEFAULT 0,
+    win_count    BIGINT      NOT NULL DEFAULT 0,
+    PRIMARY KEY (period_start, currency)
+);
+
+-- Заполнение агрегатов по уже сохраненным транзакциям
+INSERT INTO ggr_hourly (period_start, currency, bets, wins, reversals, bet_count, win_count)
+SELECT date_trunc('hour', "timestamp", 'UTC'),
+       currency,
+       COALESCE(SUM(amount) FILTER (WHERE transaction_type = 'bet'), 0),
+       COALESCE(SUM(amount) FILTER (WHERE transaction_type = 'win'), 0),
+       COALESCE(SUM(amount) FILTER (WHERE transaction_type IN ('refund', 'rollback')), 0),
+       COUNT(*) FILTER (WHERE transaction_type = 'bet'),
+       COUNT(*) FILTER (WHERE transaction_type = 'win' AND amount > 0)
+FROM transactions
+WHERE transaction_type IN ('bet', 'win', 'refund', 'rollback')
+GROUP BY 1, 2;
+
+INSERT INTO ggr_daily (period_start, currency, bets, wins, reversals, bet_count, win_count)
+SELECT date_trunc('day', period_start, 'UTC'), currency, SUM(bets), SUM(wins), SUM(reversals), SUM(bet_count), SUM(win_count)
+FROM ggr_hourly
+GROUP BY 1, 2;
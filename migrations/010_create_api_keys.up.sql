@@ -0,0 +1,12 @@
+-- Статические API-ключи для доступа к API. Хранится только SHA-256 ключа в hex:
+-- ключ не восстановить из БД, а поиск по хешу не требует перебора.
+CREATE TABLE api_keys
+(
+    id         BIGSERIAL PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL UNIQUE,
+    key_hash   CHAR(64)     NOT NULL UNIQUE,
+    scopes     TEXT[]       NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    -- Отозванный ключ перестает действовать, но остается в таблице для истории
+    revoked_at TIMESTAMPTZ
+);
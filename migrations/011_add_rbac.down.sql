@@ -0,0 +1,4 @@
+DROP TABLE access_denials;
+
+ALTER TABLE api_keys
+    DROP COLUMN roles;
@@ -0,0 +1,19 @@
+-- Роли back-office, выданные ключу. Разрешения ролей задаются в конфигурации RBAC.
+ALTER TABLE api_keys
+    ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{}';
+
+-- Журнал отказов в доступе
+CREATE TABLE access_denials
+(
+    id          BIGSERIAL PRIMARY KEY,
+    subject     VARCHAR(255) NOT NULL,
+    auth_method VARCHAR(10)  NOT NULL,
+    roles       TEXT[]       NOT NULL DEFAULT '{}',
+    permission  VARCHAR(50)  NOT NULL,
+    http_method VARCHAR(10)  NOT NULL,
+    path        TEXT         NOT NULL,
+    request_id  VARCHAR(255) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_access_denials_subject_occurred_at ON access_denials (subject, occurred_at DESC);
@@ -0,0 +1,32 @@
+-- Слоты складываются обратно в одну строку на период и валюту
+CREATE TEMPORARY TABLE ggr_hourly_merged ON COMMIT DROP AS
+SELECT period_start, currency, SUM(bets) AS bets, SUM(wins) AS wins, SUM(reversals) AS reversals,
+       SUM(bet_count) AS bet_count, SUM(win_count) AS win_count
+FROM ggr_hourly
+GROUP BY period_start, currency;
+DELETE FROM ggr_hourly;
+ALTER TABLE ggr_hourly
+    DROP CONSTRAINT ggr_hourly_pkey;
+ALTER TABLE ggr_hourly
+    DROP COLUMN slot;
+ALTER TABLE ggr_hourly
+    ADD PRIMARY KEY (period_start, currency);
+INSERT INTO ggr_hourly (period_start, currency, bets, wins, reversals, bet_count, win_count)
+SELECT period_start, currency, bets, wins, reversals, bet_count, win_count
+FROM ggr_hourly_merged;
+
+CREATE TEMPORARY TABLE ggr_daily_merged ON COMMIT DROP AS
+SELECT period_start, currency, SUM(bets) AS bets, SUM(wins) AS wins, SUM(reversals) AS reversals,
+       SUM(bet_count) AS bet_count, SUM(win_count) AS win_count
+FROM ggr_daily
+GROUP BY period_start, currency;
+DELETE FROM ggr_daily;
+ALTER TABLE ggr_daily
+    DROP CONSTRAINT ggr_daily_pkey;
+ALTER TABLE ggr_daily
+    DROP COLUMN slot;
+ALTER TABLE ggr_daily
+    ADD PRIMARY KEY (period_start, currency);
+INSERT INTO ggr_daily (period_start, currency, bets, wins, reversals, bet_count, win_count)
+SELECT period_start, currency, bets, wins, reversals, bet_count, win_count
+FROM ggr_daily_merged;
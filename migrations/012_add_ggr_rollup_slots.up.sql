@@ -0,0 +1,16 @@
+-- Агрегат периода и валюты делится на слоты: транзакция БД обновляет слот своего
+-- соединения, поэтому параллельные сохранения не ждут блокировку одной строки.
+-- Отчеты суммируют слоты. Существующие строки попадают в слот 0.
+ALTER TABLE ggr_hourly
+    ADD COLUMN slot SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE ggr_hourly
+    DROP CONSTRAINT ggr_hourly_pkey;
+ALTER TABLE ggr_hourly
+    ADD PRIMARY KEY (period_start, currency, slot);
+
+ALTER TABLE ggr_daily
+    ADD COLUMN slot SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE ggr_daily
+    DROP CONSTRAINT ggr_daily_pkey;
+ALTER TABLE ggr_daily
+    ADD PRIMARY KEY (period_start, currency, slot);
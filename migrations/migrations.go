@@ -0,0 +1,10 @@
+// Package migrations содержит SQL-миграции схемы, встроенные в бинарники.
+// Файл NNN_name.up.sql применяет миграцию с версией NNN, NNN_name.down.sql — откатывает ее.
+package migrations
+
+import "embed"
+
+// FS — файлы миграций для internal/migrate.
+//
+//go:embed *.sql
+var FS embed.FS